@@ -0,0 +1,69 @@
+package main
+
+import (
+	"db_driver"
+	"errors"
+	"fmt"
+	"os"
+	"types"
+)
+
+const claimUsage = `usage: kanbanapi claim-project <project id> <login>
+  makes the user the owner of a project that has no owner, like projects
+  created before project members existed
+`
+
+// runClaimProject is the claim-project command, it works on the database picked by STORAGE_DRIVER
+// and returns the exit code.
+func runClaimProject(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, claimUsage)
+		return 2
+	}
+	projectId, login := args[0], args[1]
+	sqlDb, dialect := openDatabase()
+	if sqlDb == nil {
+		fmt.Fprintln(os.Stderr, "memory storage has no projects to claim")
+		return 1
+	}
+	defer sqlDb.Close()
+	var db db_driver.Storage = db_driver.NewMySQLStorage(sqlDb)
+	if dialect == db_driver.DialectSQLite {
+		db = db_driver.NewSQLiteStorage(sqlDb)
+	}
+
+	_, err := db.GetProject(projectId)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't read project %s: %s\n", projectId, err)
+		return 1
+	}
+	owners, err := db.CountProjectOwners(projectId)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't count owners of project %s: %s\n", projectId, err)
+		return 1
+	}
+	if owners > 0 {
+		fmt.Fprintf(os.Stderr, "project %s already has an owner, they can add members\n", projectId)
+		return 1
+	}
+	user, err := db.GetUserByLogin(login)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't read user %s: %s\n", login, err)
+		return 1
+	}
+
+	_, err = db.GetProjectMember(projectId, user.Id)
+	var nfe db_driver.NotFoundError
+	switch {
+	case err == nil:
+		err = db.UpdateProjectMember(projectId, user.Id, types.RoleOwner, user.Id)
+	case errors.As(err, &nfe):
+		err = db.AddProjectMember(projectId, user.Id, types.RoleOwner, user.Id)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't make %s the owner of project %s: %s\n", login, projectId, err)
+		return 1
+	}
+	fmt.Printf("%s owns project %s\n", login, projectId)
+	return 0
+}
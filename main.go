@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"db_driver"
 	"fmt"
 	"handlers"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,7 +19,7 @@ type HttpServer struct {
 }
 
 func (srv *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handler := cors(srv.handler)
+	handler := cors(handlers.WithRequestId(srv.handler))
 	handler(w, r)
 }
 
@@ -27,6 +29,19 @@ func newHandler(handlerFunc http.HandlerFunc) *HttpServer {
 	return srv
 }
 
+func newAuthHandler(db db_driver.Storage, resource string, handlerFunc http.HandlerFunc) *HttpServer {
+	return newHandler(handlers.Authenticated(db, handlers.RequireScope(resource, handlerFunc)))
+}
+
+func newSessionHandler(db db_driver.Storage, handlerFunc http.HandlerFunc) *HttpServer {
+	return newHandler(handlers.Authenticated(db, handlers.SessionOnly(handlerFunc)))
+}
+
+func deprecated(successor string, srv *HttpServer) *HttpServer {
+	srv.handler = handlers.Deprecated(successor, srv.handler)
+	return srv
+}
+
 func serve(port string, wg *sync.WaitGroup) {
 	fmt.Printf("Server is running on %s\n", port)
 	err := http.ListenAndServe(fmt.Sprintf(":%s", port), nil)
@@ -35,53 +50,214 @@ func serve(port string, wg *sync.WaitGroup) {
 	}
 	wg.Done()
 }
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Printf("Error when loading .env file: %s\n", err)
+
+// rankRebalanceInterval is how often crowded card and column ranks are spread out.
+const rankRebalanceInterval = time.Minute
+
+// rebalanceRanks spreads out crowded ranks in the background for as long as the server runs.
+func rebalanceRanks(db db_driver.Storage) {
+	for range time.Tick(rankRebalanceInterval) {
+		count, err := db.RebalanceRanks()
+		if err != nil {
+			log.Printf("Failed rebalancing ranks: %s\n", err)
+		}
+		if count > 0 {
+			log.Printf("Rebalanced ranks of %d columns and projects\n", count)
+		}
 	}
-	connectionString := os.Getenv("MYSQL_CONNECTION_STRING")
-	if connectionString == "" {
-		panic(fmt.Errorf("provide connection string to a database via MYSQL_CONNECTION_STRING enviroment variable"))
+}
+
+// openDatabase connects to the sql database picked by STORAGE_DRIVER, mysql is used when it is not set.
+// The memory driver has no database and gets nil.
+func openDatabase() (*sql.DB, string) {
+	driver := os.Getenv("STORAGE_DRIVER")
+	switch driver {
+	case "", "mysql":
+		connectionString := os.Getenv("MYSQL_CONNECTION_STRING")
+		if connectionString == "" {
+			panic(fmt.Errorf("provide connection string to a database via MYSQL_CONNECTION_STRING enviroment variable"))
+		}
+		return db_driver.GetDb(connectionString), db_driver.DialectMySQL
+	case "sqlite":
+		connectionString := os.Getenv("SQLITE_CONNECTION_STRING")
+		if connectionString == "" {
+			panic(fmt.Errorf("provide path to a database file via SQLITE_CONNECTION_STRING enviroment variable"))
+		}
+		return db_driver.GetSQLiteDb(connectionString), db_driver.DialectSQLite
+	case "memory":
+		return nil, ""
 	}
-	port := os.Getenv("PORT")
-	if port == "" {
-		panic(fmt.Errorf("provide port via PORT enviroment variable"))
+	panic(fmt.Errorf("unknown storage driver %q in STORAGE_DRIVER enviroment variable, use mysql, sqlite or memory", driver))
+}
+
+// openStorage applies pending migrations before serving, AUTO_MIGRATE=false leaves them to the migrate command.
+func openStorage() db_driver.Storage {
+	sqlDb, dialect := openDatabase()
+	if sqlDb == nil {
+		log.Printf("Using in-memory storage, data is lost on restart\n")
+		return db_driver.NewMemoryStorage()
+	}
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		_, err := db_driver.Migrate(sqlDb, dialect, false)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if dialect == db_driver.DialectSQLite {
+		return db_driver.NewSQLiteStorage(sqlDb)
 	}
+	return db_driver.NewMySQLStorage(sqlDb)
+}
+
+// handleLegacy registers the routes that predate /api/v1, each answers with a Deprecation header
+// and a link to the resource replacing it.
+func handleLegacy(db db_driver.Storage) {
+	registerHandler := deprecated("/api/v1/users", newHandler(handlers.GetUserRegistrar(db)))
+	loginHandler := deprecated("/api/v1/sessions", newHandler(handlers.GetUserLoginHandler(db)))
+	logoutHandler := deprecated("/api/v1/sessions/current", newSessionHandler(db, handlers.GetUserLogoutHandler(db)))
+	currentUserHandler := deprecated("/api/v1/users/me", newSessionHandler(db, handlers.GetCurrentUserHandler(db)))
+
+	http.Handle("/users/register", registerHandler)
+	http.Handle("/users/login", loginHandler)
+	http.Handle("/users/logout", logoutHandler)
+	http.Handle("/users/me", currentUserHandler)
+
+	tokensHandler := deprecated("/api/v1/tokens", newSessionHandler(db, handlers.GetTokensHandler(db)))
 
-	db := db_driver.GetDb(connectionString)
+	http.Handle("/tokens", tokensHandler)
 
-	updateDataHandler := newHandler(handlers.GetProjectDataUpdater(db))
-	kanbanHandler := newHandler(handlers.GetProjectRequestHandler(db))
+	updateDataHandler := deprecated("/api/v1/projects", newAuthHandler(db, "projects", handlers.GetProjectDataUpdater(db)))
+	kanbanHandler := deprecated("/api/v1/projects", newAuthHandler(db, "projects", handlers.GetProjectRequestHandler(db)))
 
 	http.Handle("/kanban", kanbanHandler)
 	http.Handle("/data", updateDataHandler)
 
-	cardCreateHandler := newHandler(handlers.GetCardCreator(db))
-	cardUpdateHandler := newHandler(handlers.GetCardUpdater(db))
-	cardDeleteHandler := newHandler(handlers.GetCardDeleter(db))
+	// Actions carry their own scopes, so these handlers check them per action type.
+	actionHandler := deprecated("/api/v1/projects", newHandler(handlers.Authenticated(db, handlers.GetActionHandler(db))))
+
+	batchHandler := deprecated("/api/v1/projects", newHandler(handlers.Authenticated(db, handlers.GetBatchHandler(db))))
+
+	http.Handle("/projects/{projectId}/actions", actionHandler)
+	http.Handle("/projects/{projectId}/batch", batchHandler)
+
+	eventStreamHandler := deprecated("/api/v1/projects", newAuthHandler(db, "projects", handlers.GetEventStreamHandler(db)))
+
+	http.Handle("/projects/{projectId}/events", eventStreamHandler)
+
+	webSocketHandler := deprecated("/api/v1/projects", newAuthHandler(db, "projects", handlers.GetWebSocketHandler(db)))
+
+	http.Handle("/projects/{projectId}/ws", webSocketHandler)
+
+	membersHandler := deprecated("/api/v1/projects", newAuthHandler(db, "members", handlers.GetMembersHandler(db)))
+
+	http.Handle("/members", membersHandler)
+
+	cardCreateHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardCreator(db)))
+	cardUpdateHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardUpdater(db)))
+	cardDeleteHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardDeleter(db)))
 
 	http.Handle("/cards/create", cardCreateHandler)
 	http.Handle("/cards/update", cardUpdateHandler)
 	http.Handle("/cards/delete", cardDeleteHandler)
 
-	addTagToCardHandler := newHandler(handlers.GetCardTagAdder(db))
-	removeTagFromCardHandler := newHandler(handlers.GetCardTagRemover(db))
-	postTagHandler := newHandler(handlers.GetTagCreator(db))
-	deleteTagHandler := newHandler(handlers.GetTagDeleter(db))
+	cardHistoryHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardHistoryReader(db)))
+	cardRevertHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardReverter(db)))
+
+	http.Handle("/cards/{cardId}/history", cardHistoryHandler)
+	http.Handle("/cards/{cardId}/revert", cardRevertHandler)
+
+	addTagToCardHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardTagAdder(db)))
+	removeTagFromCardHandler := deprecated("/api/v1/projects", newAuthHandler(db, "cards", handlers.GetCardTagRemover(db)))
+	postTagHandler := deprecated("/api/v1/projects", newAuthHandler(db, "tags", handlers.GetTagCreator(db)))
+	deleteTagHandler := deprecated("/api/v1/projects", newAuthHandler(db, "tags", handlers.GetTagDeleter(db)))
 
 	http.Handle("/tags/create", postTagHandler)
 	http.Handle("/tags/delete", deleteTagHandler)
 	http.Handle("/tags/link", addTagToCardHandler)
 	http.Handle("/tags/unlink", removeTagFromCardHandler)
 
-	columnDataUpdateHandler := newHandler(handlers.GetColumnDataUpdater(db))
-	columnDeleteHandler := newHandler(handlers.GetColumnDeleter(db))
-	columnCreateHandler := newHandler(handlers.GetColumnCreator(db))
+	columnDataUpdateHandler := deprecated("/api/v1/projects", newAuthHandler(db, "columns", handlers.GetColumnDataUpdater(db)))
+	columnDeleteHandler := deprecated("/api/v1/projects", newAuthHandler(db, "columns", handlers.GetColumnDeleter(db)))
+	columnCreateHandler := deprecated("/api/v1/projects", newAuthHandler(db, "columns", handlers.GetColumnCreator(db)))
 
 	http.Handle("/columns/create", columnCreateHandler)
 	http.Handle("/columns/update", columnDataUpdateHandler)
 	http.Handle("/columns/delete", columnDeleteHandler)
+}
+
+// handleV1 registers the versioned api, resources are addressed by path and the method says what to do with them.
+func handleV1(db db_driver.Storage) {
+	http.Handle("/api/v1/users", newHandler(handlers.GetUserRegistrar(db)))
+	http.Handle("/api/v1/users/me", newSessionHandler(db, handlers.GetCurrentUserHandler(db)))
+	http.Handle("/api/v1/users/me/cards", newAuthHandler(db, "cards", handlers.GetAssignedCardsHandler(db)))
+	http.Handle("/api/v1/sessions", newHandler(handlers.GetUserLoginHandler(db)))
+	http.Handle("/api/v1/sessions/current", newSessionHandler(db, handlers.GetCurrentSessionHandler(db)))
+
+	http.Handle("/api/v1/tokens", newSessionHandler(db, handlers.GetTokensHandler(db)))
+	http.Handle("/api/v1/tokens/{tokenId}", newSessionHandler(db, handlers.GetTokenHandler(db)))
+
+	http.Handle("/api/v1/projects", newAuthHandler(db, "projects", handlers.GetProjectsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}", newAuthHandler(db, "projects", handlers.GetProjectHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/events", newAuthHandler(db, "projects", handlers.GetEventStreamHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/ws", newAuthHandler(db, "projects", handlers.GetWebSocketHandler(db)))
+
+	// Actions carry their own scopes, so these handlers check them per action type.
+	http.Handle("/api/v1/projects/{projectId}/actions", newHandler(handlers.Authenticated(db, handlers.GetActionHandler(db))))
+	http.Handle("/api/v1/projects/{projectId}/batch", newHandler(handlers.Authenticated(db, handlers.GetBatchHandler(db))))
+
+	http.Handle("/api/v1/projects/{projectId}/members", newAuthHandler(db, "members", handlers.GetProjectMembersHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/members/{userId}", newAuthHandler(db, "members", handlers.GetProjectMemberHandler(db)))
+
+	http.Handle("/api/v1/projects/{projectId}/columns", newAuthHandler(db, "columns", handlers.GetColumnsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/columns/{columnId}", newAuthHandler(db, "columns", handlers.GetColumnHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/columns/{columnId}/move", newAuthHandler(db, "columns", handlers.GetColumnMoveHandler(db)))
+
+	http.Handle("/api/v1/projects/{projectId}/cards", newAuthHandler(db, "cards", handlers.GetCardsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}", newAuthHandler(db, "cards", handlers.GetCardHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/history", newAuthHandler(db, "cards", handlers.GetCardHistoryReader(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/revert", newAuthHandler(db, "cards", handlers.GetCardReverter(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/move", newAuthHandler(db, "cards", handlers.GetCardMoveHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/tags/{tagId}", newAuthHandler(db, "cards", handlers.GetCardTagHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/assignees/{userId}", newAuthHandler(db, "cards", handlers.GetCardAssigneeHandler(db)))
+
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/comments", newAuthHandler(db, "comments", handlers.GetCommentsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/comments/{commentId}", newAuthHandler(db, "comments", handlers.GetCommentHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/comments/{commentId}/edits", newAuthHandler(db, "comments", handlers.GetCommentEditsHandler(db)))
+
+	http.Handle("/api/v1/projects/{projectId}/cards/{cardId}/checklists", newAuthHandler(db, "cards", handlers.GetChecklistsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklists/{checklistId}", newAuthHandler(db, "cards", handlers.GetChecklistHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklists/{checklistId}/move", newAuthHandler(db, "cards", handlers.GetChecklistMoveHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklists/{checklistId}/items", newAuthHandler(db, "cards", handlers.GetChecklistItemsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklist-items/{itemId}", newAuthHandler(db, "cards", handlers.GetChecklistItemHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklist-items/{itemId}/move", newAuthHandler(db, "cards", handlers.GetChecklistItemMoveHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/checklist-items/{itemId}/toggle", newAuthHandler(db, "cards", handlers.GetChecklistItemToggleHandler(db)))
+
+	http.Handle("/api/v1/projects/{projectId}/tags", newAuthHandler(db, "tags", handlers.GetTagsHandler(db)))
+	http.Handle("/api/v1/projects/{projectId}/tags/{tagId}", newAuthHandler(db, "tags", handlers.GetTagHandler(db)))
+}
+
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Printf("Error when loading .env file: %s\n", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "claim-project" {
+		os.Exit(runClaimProject(os.Args[2:]))
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		panic(fmt.Errorf("provide port via PORT enviroment variable"))
+	}
+
+	db := openStorage()
+
+	handleV1(db)
+	handleLegacy(db)
+
+	go rebalanceRanks(db)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -94,7 +270,8 @@ func cors(handler func(http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-Id")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id")
 
 		if r.Method == "OPTIONS" {
 			return
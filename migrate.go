@@ -0,0 +1,80 @@
+package main
+
+import (
+	"db_driver"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const migrateUsage = `usage: kanbanapi migrate [--dry-run] [up|status]
+  up      apply pending migrations, the default
+  status  list migrations and when they were applied
+`
+
+// runMigrate is the migrate command, it works on the database picked by STORAGE_DRIVER and returns the exit code.
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	dryRun := flags.Bool("dry-run", false, "print pending migrations without applying them")
+	if flags.Parse(args) != nil || flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	command := flags.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+	if command != "up" && command != "status" {
+		flags.Usage()
+		return 2
+	}
+	sqlDb, dialect := openDatabase()
+	if sqlDb == nil {
+		fmt.Fprintln(os.Stderr, "memory storage has no schema to migrate")
+		return 1
+	}
+	defer sqlDb.Close()
+
+	if command == "status" {
+		statuses, err := db_driver.GetMigrationStatus(sqlDb, dialect)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "can't read migration status: %s\n", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != 0 {
+				state = "applied " + time.Unix(int64(status.AppliedAt), 0).UTC().Format(time.RFC3339)
+			}
+			if !status.Known {
+				state += ", not in this binary"
+			}
+			fmt.Printf("%04d  %-32s %s\n", status.Version, status.Name, state)
+		}
+		return 0
+	}
+
+	migrations, err := db_driver.Migrate(sqlDb, dialect, *dryRun)
+	for _, migration := range migrations {
+		if !*dryRun {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+			continue
+		}
+		fmt.Printf("-- pending %04d_%s\n", migration.Version, migration.Name)
+		for _, statement := range migration.Statements {
+			fmt.Println(strings.TrimSpace(statement))
+			fmt.Println()
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(migrations) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return 0
+}
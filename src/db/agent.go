@@ -1,22 +1,62 @@
 package db_driver
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
 
 func CreateAgentDB(db *sql.DB) *Agent {
-	agent := Agent{db, nil}
+	agent := Agent{db, nil, nil}
 	return &agent
 }
 func CreateAgentTX(tx *sql.Tx) *Agent {
-	agent := Agent{nil, tx}
+	agent := Agent{nil, tx, nil}
 	return &agent
 }
 
 type Agent struct {
 	db *sql.DB
 	tx *sql.Tx
+	// procedures stands in for stored procedures on databases without them, nil calls the database ones.
+	procedures *procedureSet
+}
+
+// procedureSet emulates stored procedures, reads are single queries taking the procedure arguments in order
+// and writes run in Go on the calling agent.
+type procedureSet struct {
+	reads  map[string]string
+	writes map[string]procedure
+}
+
+type procedure struct {
+	params int
+	run    func(agent *Agent, args []any) (sql.Result, error)
+}
+
+var callPattern = regexp.MustCompile(`^\s*CALL\s+(\w+)\s*\(`)
+
+// withTx returns an agent for the transaction that calls procedures the same way as a.
+func (a *Agent) withTx(tx *sql.Tx) *Agent {
+	return &Agent{nil, tx, a.procedures}
+}
+
+func procedureName(query string) string {
+	match := callPattern.FindStringSubmatch(query)
+	if match == nil {
+		return ""
+	}
+	return match[1]
 }
 
 func (a *Agent) Prepare(query string) (*sql.Stmt, error) {
+	if name := procedureName(query); a.procedures != nil && name != "" {
+		read, found := a.procedures.reads[name]
+		if !found {
+			return nil, fmt.Errorf("procedure %s can't be prepared, call it through Exec", name)
+		}
+		query = read
+	}
 	if a.db != nil {
 		return a.db.Prepare(query)
 	} else {
@@ -24,10 +64,36 @@ func (a *Agent) Prepare(query string) (*sql.Stmt, error) {
 	}
 }
 
+func (a *Agent) QueryRow(query string, args ...any) *sql.Row {
+	if a.db != nil {
+		return a.db.QueryRow(query, args...)
+	} else {
+		return a.tx.QueryRow(query, args...)
+	}
+}
+
+func (a *Agent) Query(query string, args ...any) (*sql.Rows, error) {
+	if a.db != nil {
+		return a.db.Query(query, args...)
+	} else {
+		return a.tx.Query(query, args...)
+	}
+}
+
 func (a *Agent) Exec(query string, args ...any) (sql.Result, error) {
+	if name := procedureName(query); a.procedures != nil && name != "" {
+		write, found := a.procedures.writes[name]
+		if !found {
+			return nil, fmt.Errorf("procedure %s is not available", name)
+		}
+		if len(args) != write.params {
+			return nil, fmt.Errorf("procedure %s takes %d arguments, got %d", name, write.params, len(args))
+		}
+		return write.run(a, args)
+	}
 	if a.db != nil {
-		return a.db.Exec(query, args)
+		return a.db.Exec(query, args...)
 	} else {
-		return a.tx.Exec(query, args)
+		return a.tx.Exec(query, args...)
 	}
 }
@@ -0,0 +1,117 @@
+package db_driver
+
+import (
+	"database/sql"
+	"fmt"
+	"types"
+)
+
+// AssignCard makes the user an assignee of the card, the user has to be a member of the project of the card.
+func AssignCard(agent *Agent, cardId string, userId string, author string) error {
+	res, err := agent.Exec("CALL assign_card(?, ?, ?);", cardId, userId, author)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return itemNotFound(cardId)
+	}
+	return nil
+}
+
+func UnassignCard(agent *Agent, cardId string, userId string) error {
+	res, err := agent.Exec("DELETE FROM CardAssignees WHERE card_id = ? AND user_id = ?;", cardId, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
+
+// readAssigneeIds maps card ids to the ids of their assignees, in the order they were assigned.
+func readAssigneeIds(columns []string, values [][]sql.RawBytes) map[string][]string {
+	assigneeIds := make(map[string][]string)
+	for _, row := range values {
+		var cardId, userId string
+		for j, col := range row {
+			switch columns[j] {
+			case "card_id":
+				cardId = string(col)
+			case "user_id":
+				userId = string(col)
+			}
+		}
+		assigneeIds[cardId] = append(assigneeIds[cardId], userId)
+	}
+	return assigneeIds
+}
+
+func GetCardAssigneeIds(agent *Agent, cardId string) ([]string, error) {
+	columns, values, err := readMultiRow(agent, cardId, `CALL read_card_assignees_by_card_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readAssigneeIds(columns, values)[cardId], nil
+}
+
+// GetCardAssigneeIdsByProject maps the cards of the project to their assignee ids, like GetCardTagIdsByProject does for tags.
+func GetCardAssigneeIdsByProject(agent *Agent, projectId string) (map[string][]string, error) {
+	columns, values, err := readMultiRow(agent, projectId, `CALL read_card_assignees_by_project_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readAssigneeIds(columns, values), nil
+}
+
+// GetAssignedCards reads the cards assigned to the user across all projects, grouped by project in board order.
+func GetAssignedCards(agent *Agent, userId string) ([]types.AssignedCardJson, error) {
+	columns, values, err := readMultiRowArgs(agent, fmt.Sprintf("cards of user %s", userId),
+		`CALL read_cards_by_assignee_id(?);`, userId)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := readCards(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]types.AssignedCardJson, len(cards))
+	tagIds := make(map[string]map[string][]string)
+	assigneeIds := make(map[string]map[string][]string)
+	progress := make(map[string]map[string]types.ChecklistProgressJson)
+	for idx, card := range cards {
+		var projectId string
+		for j, col := range values[idx] {
+			if columns[j] == "project_id" {
+				projectId = string(col)
+			}
+		}
+		if _, found := tagIds[projectId]; !found {
+			tagIds[projectId], err = GetCardTagIdsByProject(agent, projectId)
+			if err != nil {
+				return nil, err
+			}
+			assigneeIds[projectId], err = GetCardAssigneeIdsByProject(agent, projectId)
+			if err != nil {
+				return nil, err
+			}
+			progress[projectId], err = GetChecklistProgressByProject(agent, projectId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		output[idx] = types.AssignedCardJson{ProjectId: projectId, CardJson: *card.Json()}
+		output[idx].TagIds = append(output[idx].TagIds, tagIds[projectId][card.Id]...)
+		output[idx].AssigneeIds = append(output[idx].AssigneeIds, assigneeIds[projectId][card.Id]...)
+		output[idx].ChecklistProgress = progress[projectId][card.Id]
+	}
+	return output, nil
+}
@@ -1,101 +1,93 @@
 package db_driver
 
 import (
-	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"types"
 	"utils"
-
-	"github.com/KustelR/jsondiff"
 )
 
-func UpdateCard(db *sql.DB, card *types.CardJson) (*types.CardJson, error) {
-	tx, err := db.BeginTx(context.Background(), nil)
-	agent := CreateAgentTX(tx)
+// UpdateCardTX writes the card and its update record, agent has to wrap a transaction.
+// A card.Version other than 0 makes the write fail with VersionConflict unless the card is still at that version.
+func UpdateCardTX(agent *Agent, card *types.CardJson, author string) (*types.CardJson, error) {
+	oldCard, err := GetCard(agent, card.Id)
 	if err != nil {
 		return nil, err
 	}
-	stmt, err := agent.Prepare("CALL update_card(?, ?, ?, ?, ?, ?);")
+	newCard := *card
+	// An empty rank keeps the card where it is.
+	rank := ""
+	if oldCard.ColumnId != newCard.ColumnId {
+		rank, newCard.Order, err = cardRanks.place(agent, newCard.ColumnId, newCard.Id, 0)
+	} else if newCard.Order > 0 && newCard.Order != oldCard.Order {
+		rank, newCard.Order, err = cardRanks.place(agent, newCard.ColumnId, newCard.Id, newCard.Order)
+	} else {
+		newCard.Order = oldCard.Order
+	}
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	defer stmt.Close()
-
-	oldCard, err := GetCard(agent, card.Id)
+	err = CreateCardUpdateRecord(agent, oldCard.Json(), recordedCard(oldCard, &newCard), author)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	oldJson, err := json.Marshal(oldCard.Json())
+	res, err := agent.Exec("CALL update_card(?, ?, ?, ?, ?, ?, ?, ?, ?);", newCard.Id, newCard.ColumnId, newCard.Name, newCard.Description,
+		author, rank, newCard.StartAt, newCard.DueAt, card.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "card", newCard.Id, card.Version)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := GetCard(agent, newCard.Id)
+	if err != nil {
+		return nil, err
+	}
+	newCard.Version = updated.Version
+	return &newCard, nil
+}
 
+// MoveCardTX puts the card at the position of the column, only the row of the card is written.
+// A version other than 0 is the one the card is expected at, like in UpdateCardTX.
+func MoveCardTX(agent *Agent, cardId string, columnId string, position int, version int, author string) (*types.CardJson, error) {
+	oldCard, err := GetCard(agent, cardId)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	newJson, err := json.Marshal(*card)
+	_, err = GetColumn(agent, columnId)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	diff1, diff2 := jsondiff.Diff(oldJson, newJson)
-	stmtUR, err := agent.Prepare("CALL create_card_update_record(?, ?, ?);")
+	rank, _, err := cardRanks.place(agent, columnId, cardId, position)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	_, err = stmtUR.Exec(card.Id, string(diff1), string(diff2))
+	res, err := agent.Exec("CALL move_card(?, ?, ?, ?, ?);", cardId, columnId, rank, author, version)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-	newCard := *card
-	if oldCard.ColumnId != newCard.ColumnId {
-		stmtPop, err := agent.Prepare("CALL pop_card_reorder(?, ?);")
-		if err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		defer stmt.Close()
-		_, err = stmtPop.Exec(oldCard.ColumnId, oldCard.Order)
-		if err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		dbColNames, data, err := readOneRow(agent, card.ColumnId, "SELECT max(draw_order) FROM Cards WHERE column_id = ?;")
-		if err != nil {
-			return nil, err
-		}
-		maxDrawOrder, err := GetMaxDrawOrder(dbColNames, data)
-		if err != nil {
-			return nil, err
-		}
-		newCard.Order = maxDrawOrder + 1
+	err = checkVersioned(res, "card", cardId, version)
+	if err != nil {
+		return nil, err
 	}
-	_, err = stmt.Exec(newCard.Id, newCard.ColumnId, newCard.Name, newCard.Description, "placeholder", newCard.Order)
+	newCard, err := GetCard(agent, cardId)
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
-
-	err = tx.Commit()
+	err = CreateCardUpdateRecord(agent, oldCard.Json(), recordedCard(oldCard, newCard.Json()), author)
 	if err != nil {
 		return nil, err
 	}
-	return &newCard, nil
+	return newCard.Json(), nil
 }
 
 func CreateCardTags(agent *Agent, cardId string, tagId string) error {
 	stmt, err := agent.Prepare(`
-	INSERT CardsTags 
-		(card_id, tag_id) 
-	VALUES 
-	(
-		?, # card_id 
-		? # tag_id
-	);`)
+	INSERT INTO CardsTags
+		(card_id, tag_id)
+	VALUES
+		(?, ?);`)
 	if err != nil {
 		return err
 	}
@@ -118,56 +110,34 @@ func RemoveCardTags(agent *Agent, cardId string, tagId string) error {
 	return nil
 }
 
-func DeleteCard(db *sql.DB, id string) error {
-	tx, err := db.BeginTx(context.Background(), nil)
-	if err != nil {
-		return err
-	}
-	agent := CreateAgentTX(tx)
+// DeleteCardTX removes the card, the ranks of the cards left don't change. agent has to wrap a transaction.
+func DeleteCardTX(agent *Agent, id string) error {
 	stmt, err := agent.Prepare("DELETE FROM Cards WHERE id = ?;")
 	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	card, err := GetCard(agent, id)
-	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	stmtPC, err := agent.Prepare("CALL pop_card_reorder(?, ?);")
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	_, err = stmtPC.Exec(card.ColumnId, card.Order)
+	defer stmt.Close()
+	_, err = GetCard(agent, id)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 	_, err = stmt.Exec(id)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	err = tx.Commit()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CreateCards(agent *Agent, columnId string, cards *[]types.CardJson) ([]types.CardJson, error) {
-	stmt, err := agent.Prepare(`
-	CALL create_card(?, ?, ?, ?, ?, ?);`)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-	stmtRT, err := agent.Prepare(`select * from CardsTags 
-	where 
-	card_id = ? AND # card id
-	tag_id = ? # tag id`)
+func CreateCards(agent *Agent, columnId string, cards *[]types.CardJson, author string) ([]types.CardJson, error) {
+	return createCardsAt(agent, columnId, cards, 0, author)
+}
+
+// createCardsAt creates the cards one after the other from position on, 0 appends them to the column.
+func createCardsAt(agent *Agent, columnId string, cards *[]types.CardJson, position int, author string) ([]types.CardJson, error) {
+	stmtRT, err := agent.Prepare(`select * from CardsTags
+	where
+	card_id = ? AND
+	tag_id = ?`)
 	if err != nil {
 		return nil, err
 	}
@@ -180,18 +150,18 @@ out:
 		changedCard := card
 		changedCard.Id = id
 
-		cols, data, err := readOneRow(agent, card.ColumnId, "SELECT max(draw_order) FROM Cards WHERE column_id= ?;")
-		if err != nil {
-			cardErr = err
-			break out
+		at := position
+		if at > 0 {
+			at += idx
 		}
-		drawOrder, err := GetMaxDrawOrder(cols, data)
+		rank, order, err := cardRanks.place(agent, columnId, "", at)
 		if err != nil {
 			cardErr = err
 			break out
 		}
-		changedCard.Order = drawOrder + 1
-		_, err = stmt.Exec(columnId, changedCard.Id, changedCard.Name, changedCard.Description, changedCard.Order, "placeholder")
+		changedCard.Order = order
+		_, err = agent.Exec("CALL create_card(?, ?, ?, ?, ?, ?, ?, ?);", columnId, changedCard.Id, changedCard.Name, changedCard.Description, rank, author,
+			changedCard.StartAt, changedCard.DueAt)
 		if err != nil {
 			cardErr = err
 			break out
@@ -220,3 +190,14 @@ out:
 	}
 	return newCards, nil
 }
+
+// CreateCardAt creates the card at the given position of its column without moving the other cards,
+// positions start at 1 and 0 appends the card to the column.
+func CreateCardAt(agent *Agent, card *types.CardJson, position int, author string) (*types.CardJson, error) {
+	cards := []types.CardJson{*card}
+	newCards, err := createCardsAt(agent, card.ColumnId, &cards, position, author)
+	if err != nil {
+		return nil, err
+	}
+	return &newCards[0], nil
+}
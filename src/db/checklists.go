@@ -0,0 +1,355 @@
+package db_driver
+
+import (
+	"database/sql"
+	"strconv"
+	"types"
+	"utils"
+)
+
+func readChecklist(columns []string, values []sql.RawBytes) (*types.Checklist, error) {
+	var checklist types.Checklist
+	var err error
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			checklist.Id = string(col)
+		case "card_id":
+			checklist.CardId = string(col)
+		case "name":
+			checklist.Name = string(col)
+		case "draw_order":
+			checklist.Order, err = strconv.Atoi(string(col))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	meta, err := readMeta(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	checklist.CreatedAt = meta.Created_at
+	checklist.UpdatedAt = meta.Updated_at
+	checklist.CreatedBy = meta.Created_by
+	checklist.UpdatedBy = meta.Updated_by
+	checklist.Version = meta.Version
+	return &checklist, nil
+}
+
+func readChecklistItem(columns []string, values []sql.RawBytes) (*types.ChecklistItem, error) {
+	var item types.ChecklistItem
+	var err error
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			item.Id = string(col)
+		case "checklist_id":
+			item.ChecklistId = string(col)
+		case "text":
+			item.Text = string(col)
+		case "done":
+			item.Done = string(col) == "1"
+		case "draw_order":
+			item.Order, err = strconv.Atoi(string(col))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	meta, err := readMeta(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	item.CreatedAt = meta.Created_at
+	item.UpdatedAt = meta.Updated_at
+	item.CreatedBy = meta.Created_by
+	item.UpdatedBy = meta.Updated_by
+	item.Version = meta.Version
+	return &item, nil
+}
+
+func readChecklistItems(columns []string, values [][]sql.RawBytes) ([]types.ChecklistItem, error) {
+	var items []types.ChecklistItem
+	for _, row := range values {
+		item, err := readChecklistItem(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, nil
+}
+
+func GetChecklist(agent *Agent, id string) (*types.Checklist, error) {
+	columns, values, err := readOneRow(agent, id, `CALL read_checklist_by_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readChecklist(columns, values)
+}
+
+// GetChecklists reads the checklists of the card in order, each with its items in order.
+func GetChecklists(agent *Agent, cardId string) ([]types.ChecklistJson, error) {
+	columns, values, err := readMultiRow(agent, cardId, `CALL read_checklists_by_card_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]types.ChecklistJson, len(values))
+	for idx, row := range values {
+		checklist, err := readChecklist(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		output[idx] = *checklist.Json()
+	}
+	columns, values, err = readMultiRow(agent, cardId, `CALL read_checklist_items_by_card_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	items, err := readChecklistItems(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		for idx := range output {
+			if output[idx].Id == item.ChecklistId {
+				output[idx].Items = append(output[idx].Items, *item.Json())
+			}
+		}
+	}
+	return output, nil
+}
+
+func GetChecklistItem(agent *Agent, id string) (*types.ChecklistItem, error) {
+	columns, values, err := readOneRow(agent, id, `CALL read_checklist_item_by_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readChecklistItem(columns, values)
+}
+
+func GetChecklistItems(agent *Agent, checklistId string) ([]types.ChecklistItem, error) {
+	columns, values, err := readMultiRow(agent, checklistId, `CALL read_checklist_items_by_checklist_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readChecklistItems(columns, values)
+}
+
+// readChecklistProgress maps card ids to the done and total counts of their checklist items.
+func readChecklistProgress(columns []string, values [][]sql.RawBytes) (map[string]types.ChecklistProgressJson, error) {
+	progress := make(map[string]types.ChecklistProgressJson)
+	for _, row := range values {
+		var cardId string
+		var counts types.ChecklistProgressJson
+		var err error
+		for j, col := range row {
+			switch columns[j] {
+			case "card_id":
+				cardId = string(col)
+			case "done":
+				counts.Done, err = strconv.Atoi(string(col))
+			case "total":
+				counts.Total, err = strconv.Atoi(string(col))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		progress[cardId] = counts
+	}
+	return progress, nil
+}
+
+// GetChecklistProgress counts the done and all items in the checklists of the card.
+func GetChecklistProgress(agent *Agent, cardId string) (*types.ChecklistProgressJson, error) {
+	columns, values, err := readMultiRow(agent, cardId, `CALL read_checklist_progress_by_card_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := readChecklistProgress(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	counts := progress[cardId]
+	return &counts, nil
+}
+
+// GetChecklistProgressByProject maps the cards of the project having checklist items to their progress,
+// like GetCardTagIdsByProject does for tags.
+func GetChecklistProgressByProject(agent *Agent, projectId string) (map[string]types.ChecklistProgressJson, error) {
+	columns, values, err := readMultiRow(agent, projectId, `CALL read_checklist_progress_by_project_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readChecklistProgress(columns, values)
+}
+
+// CreateChecklistTX adds the checklist to the card at position, 0 appends it. agent has to wrap a transaction.
+func CreateChecklistTX(agent *Agent, cardId string, name string, position int, author string) (*types.Checklist, error) {
+	_, err := GetCard(agent, cardId)
+	if err != nil {
+		return nil, err
+	}
+	rank, _, err := checklistRanks.place(agent, cardId, "", position)
+	if err != nil {
+		return nil, err
+	}
+	id := utils.GetUUID()
+	_, err = agent.Exec(`CALL create_checklist(?, ?, ?, ?, ?);`, cardId, id, name, rank, author)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklist(agent, id)
+}
+
+// RenameChecklist writes the name of the checklist, a version other than 0 is the one the checklist is expected at.
+func RenameChecklist(agent *Agent, id string, name string, version int, author string) (*types.Checklist, error) {
+	if version != 0 {
+		_, err := GetChecklist(agent, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := agent.Exec(`CALL update_checklist(?, ?, ?, ?);`, id, name, author, version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "checklist", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklist(agent, id)
+}
+
+// MoveChecklistTX puts the checklist at the position among the checklists of its card,
+// only the row of the checklist is written. A version other than 0 is the one the checklist is expected at,
+// agent has to wrap a transaction.
+func MoveChecklistTX(agent *Agent, id string, position int, version int, author string) (*types.Checklist, error) {
+	checklist, err := GetChecklist(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	rank, _, err := checklistRanks.place(agent, checklist.CardId, id, position)
+	if err != nil {
+		return nil, err
+	}
+	res, err := agent.Exec(`CALL move_checklist(?, ?, ?, ?);`, id, rank, author, version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "checklist", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklist(agent, id)
+}
+
+// DeleteChecklist removes the checklist with its items, the ranks of the checklists left don't change.
+func DeleteChecklist(agent *Agent, id string) error {
+	res, err := agent.Exec("DELETE FROM Checklists WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
+
+// CreateChecklistItemTX adds an undone item to the checklist at position, 0 appends it.
+// agent has to wrap a transaction.
+func CreateChecklistItemTX(agent *Agent, checklistId string, text string, position int, author string) (*types.ChecklistItem, error) {
+	_, err := GetChecklist(agent, checklistId)
+	if err != nil {
+		return nil, err
+	}
+	rank, _, err := checklistItemRanks.place(agent, checklistId, "", position)
+	if err != nil {
+		return nil, err
+	}
+	id := utils.GetUUID()
+	_, err = agent.Exec(`CALL create_checklist_item(?, ?, ?, ?, ?);`, checklistId, id, text, rank, author)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklistItem(agent, id)
+}
+
+// UpdateChecklistItem writes the text and the done flag of the item, a version other than 0 is the one
+// the item is expected at.
+func UpdateChecklistItem(agent *Agent, id string, text string, done bool, version int, author string) (*types.ChecklistItem, error) {
+	if version != 0 {
+		_, err := GetChecklistItem(agent, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := agent.Exec(`CALL update_checklist_item(?, ?, ?, ?, ?);`, id, text, done, author, version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "checklist item", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklistItem(agent, id)
+}
+
+// ToggleChecklistItem flips the done flag of the item in a single write, so concurrent toggles don't get lost.
+func ToggleChecklistItem(agent *Agent, id string, author string) (*types.ChecklistItem, error) {
+	res, err := agent.Exec(`CALL toggle_checklist_item(?, ?);`, id, author)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, itemNotFound(id)
+	}
+	return GetChecklistItem(agent, id)
+}
+
+// MoveChecklistItemTX puts the item at the position of its checklist, only the row of the item is written.
+// A version other than 0 is the one the item is expected at, agent has to wrap a transaction.
+func MoveChecklistItemTX(agent *Agent, id string, position int, version int, author string) (*types.ChecklistItem, error) {
+	item, err := GetChecklistItem(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	rank, _, err := checklistItemRanks.place(agent, item.ChecklistId, id, position)
+	if err != nil {
+		return nil, err
+	}
+	res, err := agent.Exec(`CALL move_checklist_item(?, ?, ?, ?);`, id, rank, author, version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "checklist item", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return GetChecklistItem(agent, id)
+}
+
+func DeleteChecklistItem(agent *Agent, id string) error {
+	res, err := agent.Exec("DELETE FROM ChecklistItems WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
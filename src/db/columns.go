@@ -1,76 +1,86 @@
 package db_driver
 
 import (
-	"context"
-	"database/sql"
 	"types"
 	"utils"
 )
 
-func UpdateColumnData(db *sql.DB, column *types.Column) error {
-	tx, err := db.BeginTx(context.Background(), nil)
-	agent := CreateAgentTX(tx)
+// UpdateColumnDataTX renames the column and moves it to column.Order, 0 keeps it in place.
+// A column.Version other than 0 is the one the column is expected at. agent has to wrap a transaction.
+func UpdateColumnDataTX(agent *Agent, column *types.Column, author string) error {
+	oldColumn, err := GetColumn(agent, column.Id)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-	stmt, err := agent.Prepare("CALL update_column_data(?, ?, ?, ?)")
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(column.Id, column.Name, "placeholder", column.Order)
-	if err != nil {
-		tx.Rollback()
-		return err
+	// An empty rank keeps the column where it is.
+	rank := ""
+	if column.Order > 0 && column.Order != oldColumn.Order {
+		rank, _, err = columnRanks.place(agent, oldColumn.ProjectId, column.Id, column.Order)
+		if err != nil {
+			return err
+		}
 	}
-
-	err = tx.Commit()
+	res, err := agent.Exec("CALL update_column_data(?, ?, ?, ?, ?)", column.Id, column.Name, author, rank, column.Version)
 	if err != nil {
 		return err
 	}
-	return nil
+	return checkVersioned(res, "column", column.Id, column.Version)
 }
 
-func DeleteColumn(db *sql.DB, id string) error {
-	tx, err := db.BeginTx(context.Background(), nil)
-	agent := CreateAgentTX(tx)
+// MoveColumnTX puts the column at the position of its project and returns the columns in their new order,
+// a version other than 0 is the one the column is expected at. agent has to wrap a transaction.
+func MoveColumnTX(agent *Agent, id string, position int, version int, author string) ([]types.ColumnJson, error) {
+	column, err := GetColumn(agent, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
-	stmt, err := agent.Prepare("DELETE FROM ProjectColumns WHERE id = ?;")
+	rank, _, err := columnRanks.place(agent, column.ProjectId, id, position)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, err
 	}
-	oldCol, err := GetColumn(agent, id)
+	res, err := agent.Exec("CALL move_column(?, ?, ?, ?);", id, rank, author, version)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return nil, err
 	}
-	stmtP, err := agent.Prepare("CALL pop_column_reorder(?, ?)")
+	err = checkVersioned(res, "column", id, version)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	defer stmtP.Close()
-	stmtP.Exec(oldCol.ProjectId, oldCol.Order)
-	stmt.Exec(id)
-
-	err = tx.Commit()
+	columns, err := ReadColumns(agent, column.ProjectId)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return nil
+	ordered := make([]types.ColumnJson, len(columns))
+	for idx, column := range columns {
+		ordered[idx] = *column.Json()
+	}
+	return ordered, nil
 }
 
-func CreateColumns(agent *Agent, projectId string, columns []types.ColumnJson) ([]types.ColumnJson, error) {
-	stmt, err := agent.Prepare(`CALL create_column(?, ?, ?, ?, ?)`)
+// DeleteColumnTX removes the column, the ranks of the columns left don't change. agent has to wrap a transaction.
+func DeleteColumnTX(agent *Agent, id string) error {
+	stmt, err := agent.Prepare("DELETE FROM ProjectColumns WHERE id = ?;")
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer stmt.Close()
+	_, err = GetColumn(agent, id)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func CreateColumns(agent *Agent, projectId string, columns []types.ColumnJson, author string) ([]types.ColumnJson, error) {
+	return createColumnsAt(agent, projectId, columns, 0, author)
+}
+
+// createColumnsAt creates the columns one after the other from position on, 0 appends them to the project.
+func createColumnsAt(agent *Agent, projectId string, columns []types.ColumnJson, position int, author string) ([]types.ColumnJson, error) {
 	var colErr error
 
 	newCols := make([]types.ColumnJson, len(columns))
@@ -81,23 +91,22 @@ out:
 		changedCol := col
 		changedCol.Id = id
 
-		dbColNames, data, err := readOneRow(agent, projectId, "SELECT max(draw_order) FROM ProjectColumns WHERE project_id= ?;")
-		if err != nil {
-			colErr = err
-			break out
+		at := position
+		if at > 0 {
+			at += idx
 		}
-		drawOrder, err := GetMaxDrawOrder(dbColNames, data)
+		rank, order, err := columnRanks.place(agent, projectId, "", at)
 		if err != nil {
 			colErr = err
 			break out
 		}
-		changedCol.Order = drawOrder + 1
-		_, err = stmt.Exec(projectId, id, changedCol.Name, changedCol.Order, "placeholder")
+		changedCol.Order = order
+		_, err = agent.Exec(`CALL create_column(?, ?, ?, ?, ?)`, projectId, id, changedCol.Name, rank, author)
 		if err != nil {
 			colErr = err
 			break out
 		}
-		cards, err := CreateCards(agent, id, &col.Cards)
+		cards, err := CreateCards(agent, id, &col.Cards, author)
 		if err != nil {
 			colErr = err
 			break out
@@ -111,3 +120,13 @@ out:
 	}
 	return newCols, nil
 }
+
+// CreateColumnAt creates the column at the given position of the project without moving the other columns,
+// positions start at 1 and 0 appends the column to the project.
+func CreateColumnAt(agent *Agent, projectId string, column *types.ColumnJson, position int, author string) (*types.ColumnJson, error) {
+	newColumns, err := createColumnsAt(agent, projectId, []types.ColumnJson{*column}, position, author)
+	if err != nil {
+		return nil, err
+	}
+	return &newColumns[0], nil
+}
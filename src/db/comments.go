@@ -0,0 +1,135 @@
+package db_driver
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"types"
+	"utils"
+)
+
+func readComment(columns []string, values []sql.RawBytes) (*types.Comment, error) {
+	var comment types.Comment
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			comment.Id = string(col)
+		case "card_id":
+			comment.CardId = string(col)
+		case "parent_id":
+			comment.ParentId = string(col)
+		case "body":
+			comment.Body = string(col)
+		}
+	}
+	meta, err := readMeta(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	comment.CreatedAt = meta.Created_at
+	comment.UpdatedAt = meta.Updated_at
+	comment.CreatedBy = meta.Created_by
+	comment.UpdatedBy = meta.Updated_by
+	comment.Version = meta.Version
+	return &comment, nil
+}
+
+func GetComment(agent *Agent, id string) (*types.Comment, error) {
+	columns, values, err := readOneRow(agent, id, `CALL read_comment_by_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readComment(columns, values)
+}
+
+// GetComments reads up to limit comments of the card posted after the one at afterAt and afterId, oldest first.
+func GetComments(agent *Agent, cardId string, afterAt int, afterId string, limit int) ([]types.Comment, error) {
+	columns, values, err := readMultiRowArgs(agent, fmt.Sprintf("comments of card %s", cardId),
+		`CALL read_comments_by_card_id(?, ?, ?, ?);`, cardId, afterAt, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	var comments []types.Comment
+	for _, row := range values {
+		comment, err := readComment(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *comment)
+	}
+	return comments, nil
+}
+
+// CreateComment posts the comment on the card, parentId names the comment it replies to and is empty otherwise.
+func CreateComment(agent *Agent, cardId string, parentId string, body string, author string) (*types.Comment, error) {
+	id := utils.GetUUID()
+	_, err := agent.Exec(`CALL create_comment(?, ?, ?, ?, ?);`, cardId, id, parentId, body, author)
+	if err != nil {
+		return nil, err
+	}
+	return GetComment(agent, id)
+}
+
+// UpdateCommentTX replaces the body of the comment, the previous body is kept as an edit. A version other
+// than 0 is the one the comment is expected at. agent has to wrap a transaction.
+func UpdateCommentTX(agent *Agent, id string, body string, version int, author string) (*types.Comment, error) {
+	_, err := GetComment(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := agent.Exec(`CALL update_comment(?, ?, ?, ?);`, id, body, author, version)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersioned(res, "comment", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return GetComment(agent, id)
+}
+
+func DeleteComment(agent *Agent, id string) error {
+	res, err := agent.Exec("DELETE FROM Comments WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
+
+// GetCommentEdits reads the earlier bodies of the comment, oldest first.
+func GetCommentEdits(agent *Agent, commentId string) ([]types.CommentEdit, error) {
+	columns, values, err := readMultiRow(agent, commentId, `CALL read_comment_edits_by_comment_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	var edits []types.CommentEdit
+	for _, row := range values {
+		var edit types.CommentEdit
+		for j, col := range row {
+			switch columns[j] {
+			case "id":
+				edit.Id, err = strconv.Atoi(string(col))
+			case "comment_id":
+				edit.CommentId = string(col)
+			case "body":
+				edit.Body = string(col)
+			case "created_at":
+				edit.CreatedAt, err = strconv.Atoi(string(col))
+			case "created_by":
+				edit.CreatedBy = string(col)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
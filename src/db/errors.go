@@ -0,0 +1,104 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Constraint names the integrity rule a write broke, so handlers can answer with a matching status.
+type Constraint int
+
+const (
+	NoConstraint Constraint = iota
+	// ConstraintDuplicate is a primary or unique key that already holds the value.
+	ConstraintDuplicate
+	// ConstraintReferenced is a row that other rows still point to.
+	ConstraintReferenced
+	// ConstraintMissingReference is a foreign key pointing to a row that doesn't exist.
+	ConstraintMissingReference
+)
+
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlRowIsReferenced = 1451
+	mysqlNoReferencedRow = 1452
+)
+
+// DuplicateError is returned by the memory storage where the SQL backends would break a unique key.
+type DuplicateError struct {
+	message string
+}
+
+func (e DuplicateError) Error() string {
+	return e.message
+}
+
+// VersionConflict is a conditional write that found the item at another version than the expected one,
+// the item was changed by someone else since the writer read it.
+type VersionConflict struct {
+	thing    string
+	id       string
+	expected int
+}
+
+func (e VersionConflict) Error() string {
+	return fmt.Sprintf("%s %s is no longer at version %d", e.thing, e.id, e.expected)
+}
+
+// LastOwnerError is a member write that would leave the project without an owner.
+type LastOwnerError struct {
+	projectId string
+}
+
+func (e LastOwnerError) Error() string {
+	return fmt.Sprintf("project %s must keep at least one owner", e.projectId)
+}
+
+// checkVersioned tells whether a write conditioned on version changed its row, version 0 is an unconditional
+// write and always passes. Writers check that the item exists first, a missing one would look like a conflict.
+func checkVersioned(res sql.Result, thing string, id string, version int) error {
+	if version == 0 {
+		return nil
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return VersionConflict{thing, id, version}
+	}
+	return nil
+}
+
+// ViolatedConstraint tells which constraint err comes from, NoConstraint for any other error.
+func ViolatedConstraint(err error) Constraint {
+	var mysqlErr *mysql.MySQLError
+	var sqliteErr sqlite3.Error
+	var duplicate DuplicateError
+	switch {
+	case errors.As(err, &duplicate):
+		return ConstraintDuplicate
+	case errors.As(err, &mysqlErr):
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return ConstraintDuplicate
+		case mysqlRowIsReferenced:
+			return ConstraintReferenced
+		case mysqlNoReferencedRow:
+			return ConstraintMissingReference
+		}
+	case errors.As(err, &sqliteErr):
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return ConstraintDuplicate
+		case sqlite3.ErrConstraintForeignKey:
+			// SQLite reports both directions the same way, writes of missing parents are the common case here.
+			return ConstraintMissingReference
+		}
+	}
+	return NoConstraint
+}
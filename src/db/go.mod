@@ -4,7 +4,10 @@ go 1.23
 
 toolchain go1.23.7
 
-require github.com/go-sql-driver/mysql v1.9.0
+require (
+	github.com/go-sql-driver/mysql v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.33
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
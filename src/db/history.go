@@ -0,0 +1,240 @@
+package db_driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"types"
+
+	"github.com/KustelR/jsondiff"
+)
+
+// diffCards returns both halves of the diff between two card states,
+// the reverse half holds the old values and the forward half holds the new ones.
+// Both halves are empty when the states are equal.
+func diffCards(oldCard *types.CardJson, newCard *types.CardJson) (string, string, error) {
+	oldJson, err := json.Marshal(oldCard)
+	if err != nil {
+		return "", "", err
+	}
+	newJson, err := json.Marshal(newCard)
+	if err != nil {
+		return "", "", err
+	}
+	if bytes.Equal(oldJson, newJson) {
+		return "", "", nil
+	}
+	reverse, forward := jsondiff.Diff(oldJson, newJson)
+	return string(reverse), string(forward), nil
+}
+
+// recordedCard is the card state kept in update records, only stored fields go into it,
+// so the history is not cluttered with tag ids and metadata.
+func recordedCard(oldCard *types.Card, newCard *types.CardJson) *types.CardJson {
+	card := *oldCard
+	card.ColumnId = newCard.ColumnId
+	card.Name = newCard.Name
+	card.Description = newCard.Description
+	card.StartAt = newCard.StartAt
+	card.DueAt = newCard.DueAt
+	card.Order = newCard.Order
+	return card.Json()
+}
+
+// CreateCardUpdateRecord stores the diff between two card states, nothing is stored when they are equal.
+func CreateCardUpdateRecord(agent *Agent, oldCard *types.CardJson, newCard *types.CardJson, author string) error {
+	reverse, forward, err := diffCards(oldCard, newCard)
+	if err != nil || forward == "" {
+		return err
+	}
+	stmt, err := agent.Prepare(`
+	INSERT INTO CardUpdateRecords
+		(card_id, reverse_diff, forward_diff, created_at, created_by)
+	VALUES
+		(?, ?, ?, ?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(oldCard.Id, reverse, forward, time.Now().Unix(), author)
+	return err
+}
+
+func GetCardUpdateRecords(agent *Agent, cardId string) ([]types.CardUpdateRecord, error) {
+	var records []types.CardUpdateRecord
+	columns, values, err := readMultiRow(agent, cardId, `
+	SELECT id, card_id, reverse_diff, forward_diff, created_at, created_by
+	FROM CardUpdateRecords WHERE card_id = ? ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range values {
+		var record types.CardUpdateRecord
+		for j, col := range row {
+			switch columns[j] {
+			case "id":
+				val, err := strconv.Atoi(string(col))
+				if err != nil {
+					return nil, err
+				}
+				record.Id = val
+			case "card_id":
+				record.CardId = string(col)
+			case "reverse_diff":
+				record.ReverseDiff = string(col)
+			case "forward_diff":
+				record.ForwardDiff = string(col)
+			}
+		}
+		meta, err := readMeta(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		record.CreatedAt = meta.Created_at
+		record.CreatedBy = meta.Created_by
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func decodeDiff(diff string) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if diff == "" {
+		return fields, nil
+	}
+	err := json.Unmarshal([]byte(diff), &fields)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode card update record: %w", err)
+	}
+	return fields, nil
+}
+
+// DecodeCardUpdateRecord turns the stored diffs into a list of changed fields with their old and new values.
+func DecodeCardUpdateRecord(record *types.CardUpdateRecord) (*types.CardUpdateRecordJson, error) {
+	reverse, err := decodeDiff(record.ReverseDiff)
+	if err != nil {
+		return nil, err
+	}
+	forward, err := decodeDiff(record.ForwardDiff)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for field := range reverse {
+		fields = append(fields, field)
+	}
+	for field := range forward {
+		if _, found := reverse[field]; !found {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	changes := make([]types.FieldChangeJson, 0, len(fields))
+	for _, field := range fields {
+		changes = append(changes, types.FieldChangeJson{Field: field, Old: reverse[field], New: forward[field]})
+	}
+	return &types.CardUpdateRecordJson{
+		Id:        record.Id,
+		CardId:    record.CardId,
+		Changes:   changes,
+		CreatedAt: record.CreatedAt,
+		CreatedBy: record.CreatedBy,
+	}, nil
+}
+
+func decodeCardHistory(records []types.CardUpdateRecord) ([]types.CardUpdateRecordJson, error) {
+	history := make([]types.CardUpdateRecordJson, 0, len(records))
+	for _, record := range records {
+		entry, err := DecodeCardUpdateRecord(&record)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *entry)
+	}
+	return history, nil
+}
+
+func GetCardHistory(agent *Agent, cardId string) ([]types.CardUpdateRecordJson, error) {
+	records, err := GetCardUpdateRecords(agent, cardId)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCardHistory(records)
+}
+
+// revertedCard applies the reverse diffs of the card records from recordId onwards to the card, newest first.
+// recordId 0 reverts only the last record.
+func revertedCard(card *types.Card, records []types.CardUpdateRecord, recordId int) (*types.CardJson, error) {
+	from := len(records) - 1
+	if recordId != 0 {
+		from = -1
+		for idx, record := range records {
+			if record.Id == recordId {
+				from = idx
+			}
+		}
+	}
+	if from < 0 {
+		return nil, NotFoundError{fmt.Sprintf("update record %d of card %s", recordId, card.Id), nil}
+	}
+	cardJson, err := json.Marshal(card.Json())
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	err = json.Unmarshal(cardJson, &fields)
+	if err != nil {
+		return nil, err
+	}
+	for idx := len(records) - 1; idx >= from; idx-- {
+		reverse, err := decodeDiff(records[idx].ReverseDiff)
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range reverse {
+			fields[field] = value
+		}
+	}
+	revertedJson, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var reverted types.CardJson
+	err = json.Unmarshal(revertedJson, &reverted)
+	if err != nil {
+		return nil, err
+	}
+	// Neighbours may have moved since, so the card keeps its place unless it goes back to another column.
+	reverted.Order = card.Order
+	// The version is not history, the revert is written over the version it was computed from.
+	reverted.Version = card.Version
+	return &reverted, nil
+}
+
+// RevertCardTX undoes every change from the given update record onwards by applying their reverse diffs
+// newest first, recordId 0 undoes only the last change. The revert is stored as a regular update,
+// so it can be undone as well. agent has to wrap a transaction.
+func RevertCardTX(agent *Agent, cardId string, recordId int, author string) (*types.CardJson, error) {
+	card, err := GetCard(agent, cardId)
+	if err != nil {
+		return nil, err
+	}
+	records, err := GetCardUpdateRecords(agent, cardId)
+	if err != nil {
+		return nil, err
+	}
+	reverted, err := revertedCard(card, records, recordId)
+	if err != nil {
+		return nil, err
+	}
+	if reverted.ColumnId != card.ColumnId {
+		_, err = GetColumn(agent, reverted.ColumnId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return UpdateCardTX(agent, reverted, author)
+}
@@ -0,0 +1,211 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"types"
+)
+
+const memberColumns = `m.project_id, m.user_id, u.login, m.role, m.created_at, m.updated_at, m.created_by, m.updated_by`
+
+func readMember(columns []string, values []sql.RawBytes) (*types.Member, error) {
+	var member types.Member
+	for i, col := range values {
+		switch columns[i] {
+		case "project_id":
+			member.ProjectId = string(col)
+		case "user_id":
+			member.UserId = string(col)
+		case "login":
+			member.Login = string(col)
+		case "role":
+			member.Role = string(col)
+		}
+	}
+	meta, err := readMeta(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	member.CreatedAt = meta.Created_at
+	member.UpdatedAt = meta.Updated_at
+	member.CreatedBy = meta.Created_by
+	member.UpdatedBy = meta.Updated_by
+	return &member, nil
+}
+
+func AddProjectMember(agent *Agent, projectId string, userId string, role string, author string) error {
+	stmt, err := agent.Prepare(`
+	INSERT INTO ProjectMembers
+		(project_id, user_id, role, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().Unix()
+	_, err = stmt.Exec(projectId, userId, role, now, now, author, author)
+	return err
+}
+
+func GetProjectMember(agent *Agent, projectId string, userId string) (*types.Member, error) {
+	columns, values, err := readOneRowArgs(agent, fmt.Sprintf("member %s of project %s", userId, projectId), `
+	SELECT `+memberColumns+`
+	FROM ProjectMembers m JOIN Users u ON u.id = m.user_id
+	WHERE m.project_id = ? AND m.user_id = ?;`, projectId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return readMember(columns, values)
+}
+
+func GetProjectMembers(agent *Agent, projectId string) ([]types.Member, error) {
+	var members []types.Member
+	columns, values, err := readMultiRow(agent, projectId, `
+	SELECT `+memberColumns+`
+	FROM ProjectMembers m JOIN Users u ON u.id = m.user_id
+	WHERE m.project_id = ?
+	ORDER BY m.created_at;`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range values {
+		member, err := readMember(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *member)
+	}
+	return members, nil
+}
+
+// keepsOwnerCondition holds back member writes taking away the last owner, the owners are counted in a derived
+// table because MySQL can't read the table a statement writes from a plain subquery.
+const keepsOwnerCondition = `(role <> 'owner' OR (SELECT owners FROM (
+		SELECT count(*) AS owners FROM ProjectMembers WHERE project_id = ? AND role = 'owner') AS o) > 1)`
+
+// memberWriteMissed tells why a member write changed no row, the member is missing or it is the last owner.
+func memberWriteMissed(agent *Agent, projectId string, userId string, role string) error {
+	member, err := GetProjectMember(agent, projectId, userId)
+	if err != nil {
+		var nfe NotFoundError
+		if errors.As(err, &nfe) {
+			return NoEffect{}
+		}
+		return err
+	}
+	if member.Role == types.RoleOwner && role != types.RoleOwner {
+		return LastOwnerError{projectId}
+	}
+	return nil
+}
+
+// UpdateProjectMemberTX changes the role of the member, it fails with LastOwnerError when that takes away
+// the last owner. The project row is locked first so two owners can't demote each other at once,
+// agent has to wrap a transaction.
+func UpdateProjectMemberTX(agent *Agent, projectId string, userId string, role string, author string) error {
+	_, err := agent.Exec("CALL lock_project(?);", projectId)
+	if err != nil {
+		return err
+	}
+	res, err := agent.Exec(`
+	UPDATE ProjectMembers SET role = ?, updated_at = ?, updated_by = ?
+	WHERE project_id = ? AND user_id = ? AND (? = 'owner' OR `+keepsOwnerCondition+`);`,
+		role, time.Now().Unix(), author, projectId, userId, role, projectId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return memberWriteMissed(agent, projectId, userId, role)
+	}
+	return nil
+}
+
+// RemoveProjectMemberTX removes the member unless it is the last owner, like UpdateProjectMemberTX.
+// agent has to wrap a transaction.
+func RemoveProjectMemberTX(agent *Agent, projectId string, userId string) error {
+	_, err := agent.Exec("CALL lock_project(?);", projectId)
+	if err != nil {
+		return err
+	}
+	res, err := agent.Exec(`
+	DELETE FROM ProjectMembers WHERE project_id = ? AND user_id = ? AND `+keepsOwnerCondition+`;`,
+		projectId, userId, projectId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return memberWriteMissed(agent, projectId, userId, "")
+	}
+	return nil
+}
+
+func CountProjectOwners(agent *Agent, projectId string) (int, error) {
+	var count int
+	err := agent.QueryRow("SELECT count(*) FROM ProjectMembers WHERE project_id = ? AND role = ?;", projectId, types.RoleOwner).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func readProjectId(agent *Agent, id string, query string) (string, error) {
+	columns, values, err := readOneRow(agent, id, query)
+	if err != nil {
+		return "", err
+	}
+	for i, col := range values {
+		if columns[i] == "project_id" {
+			return string(col), nil
+		}
+	}
+	return "", fmt.Errorf("provided query does not contain project_id")
+}
+
+func GetCardProjectId(agent *Agent, cardId string) (string, error) {
+	return readProjectId(agent, cardId, `
+	SELECT pc.project_id FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id WHERE c.id = ?;`)
+}
+
+func GetColumnProjectId(agent *Agent, columnId string) (string, error) {
+	return readProjectId(agent, columnId, `SELECT project_id FROM ProjectColumns WHERE id = ?;`)
+}
+
+func GetTagProjectId(agent *Agent, tagId string) (string, error) {
+	return readProjectId(agent, tagId, `SELECT project_id FROM Tags WHERE id = ?;`)
+}
+
+func GetCommentProjectId(agent *Agent, commentId string) (string, error) {
+	return readProjectId(agent, commentId, `
+	SELECT pc.project_id FROM Comments cm
+	JOIN Cards c ON c.id = cm.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE cm.id = ?;`)
+}
+
+func GetChecklistProjectId(agent *Agent, checklistId string) (string, error) {
+	return readProjectId(agent, checklistId, `
+	SELECT pc.project_id FROM Checklists cl
+	JOIN Cards c ON c.id = cl.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE cl.id = ?;`)
+}
+
+func GetChecklistItemProjectId(agent *Agent, itemId string) (string, error) {
+	return readProjectId(agent, itemId, `
+	SELECT pc.project_id FROM ChecklistItems i
+	JOIN Checklists cl ON cl.id = i.checklist_id
+	JOIN Cards c ON c.id = cl.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE i.id = ?;`)
+}
@@ -0,0 +1,1783 @@
+package db_driver
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"types"
+	"utils"
+)
+
+type memberKey struct {
+	projectId string
+	userId    string
+}
+
+type memorySession struct {
+	userId    string
+	expiresAt int
+}
+
+type memoryToken struct {
+	token types.AccessToken
+	hash  string
+}
+
+type memoryState struct {
+	projects     map[string]types.Kanban
+	columns      map[string]types.Column
+	cards        map[string]types.Card
+	tags         map[string]types.Tag
+	cardTags     map[string][]string
+	assignees    map[string][]string
+	records      []types.CardUpdateRecord
+	lastRecordId int
+	comments     map[string]types.Comment
+	commentEdits []types.CommentEdit
+	lastEditId   int
+	checklists   map[string]types.Checklist
+	items        map[string]types.ChecklistItem
+	members      map[memberKey]types.Member
+	users        map[string]types.User
+	sessions     map[string]memorySession
+	tokens       map[string]memoryToken
+}
+
+// MemoryStorage keeps everything in process memory, it is meant for local development and tests
+// and loses all data on restart. It follows the semantics of the MySQL storage, except it keeps dense
+// draw orders instead of ranks, its lock already keeps renumbering writes from racing.
+type MemoryStorage struct {
+	mu    *sync.Mutex
+	state *memoryState
+	tx    bool
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	state := memoryState{
+		projects:   make(map[string]types.Kanban),
+		columns:    make(map[string]types.Column),
+		cards:      make(map[string]types.Card),
+		tags:       make(map[string]types.Tag),
+		cardTags:   make(map[string][]string),
+		assignees:  make(map[string][]string),
+		comments:   make(map[string]types.Comment),
+		checklists: make(map[string]types.Checklist),
+		items:      make(map[string]types.ChecklistItem),
+		members:    make(map[memberKey]types.Member),
+		users:      make(map[string]types.User),
+		sessions:   make(map[string]memorySession),
+		tokens:     make(map[string]memoryToken),
+	}
+	return &MemoryStorage{&sync.Mutex{}, &state, false}
+}
+
+func (st *memoryState) clone() *memoryState {
+	cardTags := make(map[string][]string, len(st.cardTags))
+	for cardId, tagIds := range st.cardTags {
+		cardTags[cardId] = slices.Clone(tagIds)
+	}
+	assignees := make(map[string][]string, len(st.assignees))
+	for cardId, userIds := range st.assignees {
+		assignees[cardId] = slices.Clone(userIds)
+	}
+	return &memoryState{
+		projects:     maps.Clone(st.projects),
+		columns:      maps.Clone(st.columns),
+		cards:        maps.Clone(st.cards),
+		tags:         maps.Clone(st.tags),
+		cardTags:     cardTags,
+		assignees:    assignees,
+		records:      slices.Clone(st.records),
+		lastRecordId: st.lastRecordId,
+		comments:     maps.Clone(st.comments),
+		commentEdits: slices.Clone(st.commentEdits),
+		lastEditId:   st.lastEditId,
+		checklists:   maps.Clone(st.checklists),
+		items:        maps.Clone(st.items),
+		members:      maps.Clone(st.members),
+		users:        maps.Clone(st.users),
+		sessions:     maps.Clone(st.sessions),
+		tokens:       maps.Clone(st.tokens),
+	}
+}
+
+// lock takes the storage lock and returns its release, a transaction storage already holds it.
+func (s *MemoryStorage) lock() func() {
+	if s.tx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// atomic runs fn under the lock and restores the previous state when it fails.
+func (s *MemoryStorage) atomic(fn func(st *memoryState) error) error {
+	if s.tx {
+		return fn(s.state)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.state.clone()
+	err := fn(s.state)
+	if err != nil {
+		*s.state = *snapshot
+	}
+	return err
+}
+
+func (s *MemoryStorage) Tx(fn func(tx Storage) error) error {
+	return s.atomic(func(st *memoryState) error {
+		return fn(&MemoryStorage{s.mu, st, true})
+	})
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// RebalanceRanks has nothing to do, draw orders in memory are dense.
+func (s *MemoryStorage) RebalanceRanks() (int, error) {
+	return 0, nil
+}
+
+func unixNow() int {
+	return int(time.Now().Unix())
+}
+
+// checkVersion is the condition of the versioned writes, version 0 writes unconditionally.
+func checkVersion(thing string, id string, stored int, version int) error {
+	if version != 0 && version != stored {
+		return VersionConflict{thing, id, version}
+	}
+	return nil
+}
+
+func itemNotFound(id string) NotFoundError {
+	return NotFoundError{fmt.Sprintf("item with id %s", id), nil}
+}
+
+func (st *memoryState) projectColumns(projectId string) []types.Column {
+	var columns []types.Column
+	for _, column := range st.columns {
+		if column.ProjectId == projectId {
+			columns = append(columns, column)
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Order < columns[j].Order })
+	return columns
+}
+
+func (st *memoryState) columnCards(columnId string) []types.Card {
+	var cards []types.Card
+	for _, card := range st.cards {
+		if card.ColumnId == columnId {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].Order < cards[j].Order })
+	return cards
+}
+
+func (st *memoryState) projectTags(projectId string) []types.Tag {
+	var tags []types.Tag
+	for _, tag := range st.tags {
+		if tag.ProjectId == projectId {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].CreatedAt != tags[j].CreatedAt {
+			return tags[i].CreatedAt < tags[j].CreatedAt
+		}
+		return tags[i].Id < tags[j].Id
+	})
+	return tags
+}
+
+// moveOrder clamps the requested draw order to count items and returns the range of neighbours to shift
+// and the direction, so that moving an item keeps the orders contiguous.
+func moveOrder(from int, to int, count int) (int, int, int, int) {
+	to = max(1, min(to, count))
+	if to < from {
+		return to, to, from - 1, 1
+	}
+	return to, from + 1, to, -1
+}
+
+// shiftColumns moves the project columns with a draw order from first to last by delta, except is left in place.
+func (st *memoryState) shiftColumns(projectId string, first int, last int, delta int, except string) {
+	for id, column := range st.columns {
+		if column.ProjectId == projectId && column.Order >= first && column.Order <= last && id != except {
+			column.Order += delta
+			st.columns[id] = column
+		}
+	}
+}
+
+// shiftCards moves the column cards with a draw order from first to last by delta, except is left in place.
+func (st *memoryState) shiftCards(columnId string, first int, last int, delta int, except string) {
+	for id, card := range st.cards {
+		if card.ColumnId == columnId && card.Order >= first && card.Order <= last && id != except {
+			card.Order += delta
+			st.cards[id] = card
+		}
+	}
+}
+
+func (st *memoryState) createProject(id string, project *types.KanbanJson, author string) error {
+	if _, found := st.projects[id]; found {
+		return DuplicateError{fmt.Sprintf("project %s already exists", id)}
+	}
+	created := unixNow()
+	st.projects[id] = types.Kanban{Name: project.Name, Id: id, Created_At: created, Updated_At: created, Created_By: author, Updated_By: author,
+		Version: 1}
+	err := st.addProjectMember(id, author, types.RoleOwner, author)
+	if err != nil {
+		return err
+	}
+	_, err = st.createTags(id, &project.Tags, author)
+	if err != nil {
+		return err
+	}
+	_, err = st.createColumns(id, project.Columns, author)
+	return err
+}
+
+func (st *memoryState) getProject(id string) (*types.KanbanJson, error) {
+	project, found := st.projects[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	output := project.Json()
+	for _, tag := range st.projectTags(id) {
+		output.Tags = append(output.Tags, *tag.Json())
+	}
+	for _, column := range st.projectColumns(id) {
+		outputColumn := column.Json()
+		var outputCards []types.CardJson
+		for _, card := range st.columnCards(column.Id) {
+			outputCard := card.Json()
+			outputCard.TagIds = append(outputCard.TagIds, st.cardTags[card.Id]...)
+			outputCard.AssigneeIds = append(outputCard.AssigneeIds, st.assignees[card.Id]...)
+			outputCard.ChecklistProgress = st.checklistProgress(card.Id)
+			outputCards = append(outputCards, *outputCard)
+		}
+		outputColumn.Cards = outputCards
+		output.Columns = append(output.Columns, *outputColumn)
+	}
+	return output, nil
+}
+
+func (st *memoryState) updateProjectData(id string, name string, version int, author string) error {
+	project, found := st.projects[id]
+	if !found {
+		if version != 0 {
+			return itemNotFound(id)
+		}
+		return nil
+	}
+	err := checkVersion("project", id, project.Version, version)
+	if err != nil {
+		return err
+	}
+	project.Name = name
+	project.Updated_At = unixNow()
+	project.Updated_By = author
+	project.Version++
+	st.projects[id] = project
+	return nil
+}
+
+func (st *memoryState) deleteProject(id string) error {
+	if _, found := st.projects[id]; !found {
+		return NoEffect{}
+	}
+	for _, column := range st.projectColumns(id) {
+		st.deleteColumn(column.Id)
+	}
+	for _, tag := range st.projectTags(id) {
+		st.deleteTag(tag.Id)
+	}
+	for key := range st.members {
+		if key.projectId == id {
+			delete(st.members, key)
+		}
+	}
+	delete(st.projects, id)
+	return nil
+}
+
+func (st *memoryState) getColumn(id string) (*types.Column, error) {
+	column, found := st.columns[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &column, nil
+}
+
+func (st *memoryState) createColumns(projectId string, columns []types.ColumnJson, author string) ([]types.ColumnJson, error) {
+	if _, found := st.projects[projectId]; !found {
+		return nil, itemNotFound(projectId)
+	}
+	newColumns := make([]types.ColumnJson, len(columns))
+	for idx, column := range columns {
+		created := unixNow()
+		stored := types.Column{
+			Id:        utils.GetUUID(),
+			Name:      column.Name,
+			Order:     len(st.projectColumns(projectId)) + 1,
+			ProjectId: projectId,
+			CreatedAt: created,
+			UpdatedAt: created,
+			CreatedBy: author,
+			UpdatedBy: author,
+			Version:   1,
+		}
+		st.columns[stored.Id] = stored
+		changedColumn := column
+		changedColumn.Id = stored.Id
+		changedColumn.Order = stored.Order
+		cards, err := st.createCards(stored.Id, &column.Cards, author)
+		if err != nil {
+			return nil, err
+		}
+		changedColumn.Cards = cards
+		newColumns[idx] = changedColumn
+	}
+	return newColumns, nil
+}
+
+func (st *memoryState) createColumnAt(projectId string, column *types.ColumnJson, position int, author string) (*types.ColumnJson, error) {
+	newColumns, err := st.createColumns(projectId, []types.ColumnJson{*column}, author)
+	if err != nil {
+		return nil, err
+	}
+	newColumn := newColumns[0]
+	if position <= 0 || position >= newColumn.Order {
+		return &newColumn, nil
+	}
+	st.shiftColumns(projectId, position, math.MaxInt, 1, newColumn.Id)
+	stored := st.columns[newColumn.Id]
+	stored.Order = position
+	st.columns[newColumn.Id] = stored
+	newColumn.Order = position
+	return &newColumn, nil
+}
+
+// updateColumnData renames the column and moves it to the given draw order, 0 keeps it in place.
+func (st *memoryState) updateColumnData(column *types.Column, author string) error {
+	stored, found := st.columns[column.Id]
+	if !found {
+		return itemNotFound(column.Id)
+	}
+	err := checkVersion("column", column.Id, stored.Version, column.Version)
+	if err != nil {
+		return err
+	}
+	if column.Order > 0 && column.Order != stored.Order {
+		to, first, last, delta := moveOrder(stored.Order, column.Order, len(st.projectColumns(stored.ProjectId)))
+		st.shiftColumns(stored.ProjectId, first, last, delta, stored.Id)
+		stored.Order = to
+	}
+	stored.Name = column.Name
+	stored.UpdatedAt = unixNow()
+	stored.UpdatedBy = author
+	stored.Version++
+	st.columns[column.Id] = stored
+	return nil
+}
+
+func (st *memoryState) moveColumn(id string, position int, version int, author string) ([]types.ColumnJson, error) {
+	stored, found := st.columns[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("column", id, stored.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	count := len(st.projectColumns(stored.ProjectId))
+	if position <= 0 {
+		position = count
+	}
+	to, first, last, delta := moveOrder(stored.Order, position, count)
+	st.shiftColumns(stored.ProjectId, first, last, delta, stored.Id)
+	stored.Order = to
+	stored.UpdatedAt = unixNow()
+	stored.UpdatedBy = author
+	stored.Version++
+	st.columns[id] = stored
+	columns := st.projectColumns(stored.ProjectId)
+	ordered := make([]types.ColumnJson, len(columns))
+	for idx, column := range columns {
+		ordered[idx] = *column.Json()
+	}
+	return ordered, nil
+}
+
+func (st *memoryState) deleteColumn(id string) error {
+	column, found := st.columns[id]
+	if !found {
+		return itemNotFound(id)
+	}
+	st.shiftColumns(column.ProjectId, column.Order+1, math.MaxInt, -1, "")
+	for _, card := range st.columnCards(id) {
+		st.removeCard(card.Id)
+	}
+	delete(st.columns, id)
+	return nil
+}
+
+func (st *memoryState) getCard(id string) (*types.Card, error) {
+	card, found := st.cards[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &card, nil
+}
+
+func (st *memoryState) getCardProjectId(id string) (string, error) {
+	card, found := st.cards[id]
+	if !found {
+		return "", itemNotFound(id)
+	}
+	return st.columns[card.ColumnId].ProjectId, nil
+}
+
+func (st *memoryState) getDueCards(projectId string, after int, before int) []types.CardJson {
+	var cards []types.Card
+	for _, card := range st.cards {
+		if st.columns[card.ColumnId].ProjectId == projectId && card.DueAt != nil && *card.DueAt >= after && *card.DueAt < before {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if *cards[i].DueAt != *cards[j].DueAt {
+			return *cards[i].DueAt < *cards[j].DueAt
+		}
+		return cards[i].Id < cards[j].Id
+	})
+	output := make([]types.CardJson, len(cards))
+	for idx, card := range cards {
+		output[idx] = *card.Json()
+		output[idx].TagIds = append(output[idx].TagIds, st.cardTags[card.Id]...)
+		output[idx].AssigneeIds = append(output[idx].AssigneeIds, st.assignees[card.Id]...)
+		output[idx].ChecklistProgress = st.checklistProgress(card.Id)
+	}
+	return output
+}
+
+func (st *memoryState) createCards(columnId string, cards *[]types.CardJson, author string) ([]types.CardJson, error) {
+	if _, found := st.columns[columnId]; !found {
+		return nil, itemNotFound(columnId)
+	}
+	newCards := make([]types.CardJson, len(*cards))
+	for idx, card := range *cards {
+		created := unixNow()
+		stored := types.Card{
+			Id:          utils.GetUUID(),
+			ColumnId:    columnId,
+			Name:        card.Name,
+			Order:       len(st.columnCards(columnId)) + 1,
+			Description: card.Description,
+			StartAt:     card.StartAt,
+			DueAt:       card.DueAt,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			CreatedBy:   author,
+			UpdatedBy:   author,
+			Version:     1,
+		}
+		st.cards[stored.Id] = stored
+		for _, tagId := range card.TagIds {
+			if slices.Contains(st.cardTags[stored.Id], tagId) {
+				continue
+			}
+			err := st.createCardTags(stored.Id, tagId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		changedCard := card
+		changedCard.Id = stored.Id
+		changedCard.Order = stored.Order
+		newCards[idx] = changedCard
+	}
+	return newCards, nil
+}
+
+func (st *memoryState) createCardAt(card *types.CardJson, position int, author string) (*types.CardJson, error) {
+	newCards, err := st.createCards(card.ColumnId, &[]types.CardJson{*card}, author)
+	if err != nil {
+		return nil, err
+	}
+	newCard := newCards[0]
+	if position <= 0 || position >= newCard.Order {
+		return &newCard, nil
+	}
+	st.shiftCards(newCard.ColumnId, position, math.MaxInt, 1, newCard.Id)
+	stored := st.cards[newCard.Id]
+	stored.Order = position
+	st.cards[newCard.Id] = stored
+	newCard.Order = position
+	return &newCard, nil
+}
+
+// updateCard writes the card like the update_card procedure, a card moved to another column is appended to it
+// and a card moved inside its column shifts the cards in between.
+func (st *memoryState) updateCard(card *types.CardJson, author string) (*types.CardJson, error) {
+	oldCard, err := st.getCard(card.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersion("card", card.Id, oldCard.Version, card.Version)
+	if err != nil {
+		return nil, err
+	}
+	newCard := *card
+	stored := *oldCard
+	if oldCard.ColumnId != newCard.ColumnId {
+		if _, found := st.columns[newCard.ColumnId]; !found {
+			return nil, itemNotFound(newCard.ColumnId)
+		}
+		st.shiftCards(oldCard.ColumnId, oldCard.Order+1, math.MaxInt, -1, oldCard.Id)
+		newCard.Order = len(st.columnCards(newCard.ColumnId)) + 1
+		stored.Order = newCard.Order
+	} else if newCard.Order > 0 && newCard.Order != oldCard.Order {
+		to, first, last, delta := moveOrder(oldCard.Order, newCard.Order, len(st.columnCards(oldCard.ColumnId)))
+		st.shiftCards(oldCard.ColumnId, first, last, delta, oldCard.Id)
+		newCard.Order = to
+		stored.Order = to
+	} else {
+		newCard.Order = oldCard.Order
+	}
+	err = st.createCardUpdateRecord(oldCard.Json(), recordedCard(oldCard, &newCard), author)
+	if err != nil {
+		return nil, err
+	}
+	stored.ColumnId = newCard.ColumnId
+	stored.Name = newCard.Name
+	stored.Description = newCard.Description
+	stored.StartAt = newCard.StartAt
+	stored.DueAt = newCard.DueAt
+	stored.UpdatedAt = unixNow()
+	stored.UpdatedBy = author
+	stored.Version++
+	st.cards[stored.Id] = stored
+	newCard.Version = stored.Version
+	return &newCard, nil
+}
+
+// moveCard puts the card at the position like the move_card procedure.
+func (st *memoryState) moveCard(cardId string, columnId string, position int, version int, author string) (*types.CardJson, error) {
+	oldCard, err := st.getCard(cardId)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersion("card", cardId, oldCard.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	if _, found := st.columns[columnId]; !found {
+		return nil, itemNotFound(columnId)
+	}
+	st.shiftCards(oldCard.ColumnId, oldCard.Order+1, math.MaxInt, -1, oldCard.Id)
+	count := len(st.columnCards(columnId))
+	if oldCard.ColumnId == columnId {
+		count--
+	}
+	if position <= 0 || position > count {
+		position = count + 1
+	}
+	st.shiftCards(columnId, position, math.MaxInt, 1, oldCard.Id)
+	stored := *oldCard
+	stored.ColumnId = columnId
+	stored.Order = position
+	stored.UpdatedAt = unixNow()
+	stored.UpdatedBy = author
+	stored.Version++
+	err = st.createCardUpdateRecord(oldCard.Json(), recordedCard(oldCard, stored.Json()), author)
+	if err != nil {
+		return nil, err
+	}
+	st.cards[stored.Id] = stored
+	return stored.Json(), nil
+}
+
+// removeCard drops the card with its tag links, assignees, comments, checklists and update records
+// without touching its neighbours.
+func (st *memoryState) removeCard(id string) {
+	delete(st.cards, id)
+	delete(st.cardTags, id)
+	delete(st.assignees, id)
+	st.records = slices.DeleteFunc(st.records, func(record types.CardUpdateRecord) bool {
+		return record.CardId == id
+	})
+	for commentId, comment := range st.comments {
+		if comment.CardId == id {
+			st.removeComment(commentId)
+		}
+	}
+	for checklistId, checklist := range st.checklists {
+		if checklist.CardId == id {
+			st.removeChecklist(checklistId)
+		}
+	}
+}
+
+func (st *memoryState) deleteCard(id string) error {
+	card, err := st.getCard(id)
+	if err != nil {
+		return err
+	}
+	st.shiftCards(card.ColumnId, card.Order+1, math.MaxInt, -1, card.Id)
+	st.removeCard(id)
+	return nil
+}
+
+func (st *memoryState) createCardTags(cardId string, tagId string) error {
+	if _, found := st.cards[cardId]; !found {
+		return itemNotFound(cardId)
+	}
+	if _, found := st.tags[tagId]; !found {
+		return itemNotFound(tagId)
+	}
+	if slices.Contains(st.cardTags[cardId], tagId) {
+		return DuplicateError{fmt.Sprintf("tag %s is already linked to card %s", tagId, cardId)}
+	}
+	st.cardTags[cardId] = append(st.cardTags[cardId], tagId)
+	return nil
+}
+
+func (st *memoryState) removeCardTags(cardId string, tagId string) {
+	st.cardTags[cardId] = slices.DeleteFunc(st.cardTags[cardId], func(id string) bool { return id == tagId })
+}
+
+func (st *memoryState) assignCard(cardId string, userId string) error {
+	projectId, err := st.getCardProjectId(cardId)
+	if err != nil {
+		return err
+	}
+	if _, err = st.getProjectMember(projectId, userId); err != nil {
+		return err
+	}
+	if slices.Contains(st.assignees[cardId], userId) {
+		return DuplicateError{fmt.Sprintf("user %s is already assigned to card %s", userId, cardId)}
+	}
+	st.assignees[cardId] = append(st.assignees[cardId], userId)
+	return nil
+}
+
+func (st *memoryState) unassignCard(cardId string, userId string) error {
+	if !slices.Contains(st.assignees[cardId], userId) {
+		return NoEffect{}
+	}
+	st.assignees[cardId] = slices.DeleteFunc(st.assignees[cardId], func(id string) bool { return id == userId })
+	return nil
+}
+
+func (st *memoryState) getAssignedCards(userId string) []types.AssignedCardJson {
+	var cards []types.Card
+	for cardId, userIds := range st.assignees {
+		if slices.Contains(userIds, userId) {
+			cards = append(cards, st.cards[cardId])
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		left, right := st.columns[cards[i].ColumnId], st.columns[cards[j].ColumnId]
+		if left.ProjectId != right.ProjectId {
+			return left.ProjectId < right.ProjectId
+		}
+		if left.Order != right.Order {
+			return left.Order < right.Order
+		}
+		return cards[i].Order < cards[j].Order
+	})
+	output := make([]types.AssignedCardJson, len(cards))
+	for idx, card := range cards {
+		output[idx] = types.AssignedCardJson{ProjectId: st.columns[card.ColumnId].ProjectId, CardJson: *card.Json()}
+		output[idx].TagIds = append(output[idx].TagIds, st.cardTags[card.Id]...)
+		output[idx].AssigneeIds = append(output[idx].AssigneeIds, st.assignees[card.Id]...)
+		output[idx].ChecklistProgress = st.checklistProgress(card.Id)
+	}
+	return output
+}
+
+func (st *memoryState) getTag(id string) (*types.Tag, error) {
+	tag, found := st.tags[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &tag, nil
+}
+
+func (st *memoryState) getTagProjectId(id string) (string, error) {
+	tag, found := st.tags[id]
+	if !found {
+		return "", itemNotFound(id)
+	}
+	return tag.ProjectId, nil
+}
+
+func (st *memoryState) getTagsByCard(cardId string) []types.Tag {
+	var tags []types.Tag
+	for _, tagId := range st.cardTags[cardId] {
+		tags = append(tags, st.tags[tagId])
+	}
+	return tags
+}
+
+func (st *memoryState) createTags(projectId string, tags *[]types.TagJson, author string) ([]types.TagJson, error) {
+	if _, found := st.projects[projectId]; !found {
+		return nil, itemNotFound(projectId)
+	}
+	createdTags := make([]types.TagJson, len(*tags))
+	for idx, tag := range *tags {
+		created := unixNow()
+		stored := types.Tag{
+			Id:        utils.GetUUID(),
+			ProjectId: projectId,
+			Name:      tag.Name,
+			Color:     tag.Color,
+			CreatedAt: created,
+			UpdatedAt: created,
+			CreatedBy: author,
+			UpdatedBy: author,
+			Version:   1,
+		}
+		st.tags[stored.Id] = stored
+		newTag := tag
+		newTag.Id = stored.Id
+		createdTags[idx] = newTag
+	}
+	return createdTags, nil
+}
+
+func (st *memoryState) updateTag(tag *types.TagJson, author string) error {
+	stored, found := st.tags[tag.Id]
+	if !found {
+		if tag.Version != 0 {
+			return itemNotFound(tag.Id)
+		}
+		return nil
+	}
+	err := checkVersion("tag", tag.Id, stored.Version, tag.Version)
+	if err != nil {
+		return err
+	}
+	stored.Name = tag.Name
+	stored.Color = tag.Color
+	stored.UpdatedAt = unixNow()
+	stored.UpdatedBy = author
+	stored.Version++
+	st.tags[tag.Id] = stored
+	return nil
+}
+
+func (st *memoryState) deleteTag(id string) error {
+	if _, found := st.tags[id]; !found {
+		return NoEffect{}
+	}
+	for cardId := range st.cardTags {
+		st.removeCardTags(cardId, id)
+	}
+	delete(st.tags, id)
+	return nil
+}
+
+func (st *memoryState) createCardUpdateRecord(oldCard *types.CardJson, newCard *types.CardJson, author string) error {
+	reverse, forward, err := diffCards(oldCard, newCard)
+	if err != nil || forward == "" {
+		return err
+	}
+	st.lastRecordId++
+	st.records = append(st.records, types.CardUpdateRecord{
+		Id:          st.lastRecordId,
+		CardId:      oldCard.Id,
+		ReverseDiff: reverse,
+		ForwardDiff: forward,
+		CreatedAt:   unixNow(),
+		CreatedBy:   author,
+	})
+	return nil
+}
+
+func (st *memoryState) getCardUpdateRecords(cardId string) []types.CardUpdateRecord {
+	var records []types.CardUpdateRecord
+	for _, record := range st.records {
+		if record.CardId == cardId {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (st *memoryState) revertCard(cardId string, recordId int, author string) (*types.CardJson, error) {
+	card, err := st.getCard(cardId)
+	if err != nil {
+		return nil, err
+	}
+	reverted, err := revertedCard(card, st.getCardUpdateRecords(cardId), recordId)
+	if err != nil {
+		return nil, err
+	}
+	return st.updateCard(reverted, author)
+}
+
+func (st *memoryState) getComment(id string) (*types.Comment, error) {
+	comment, found := st.comments[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &comment, nil
+}
+
+func (st *memoryState) getComments(cardId string, afterAt int, afterId string, limit int) []types.Comment {
+	var comments []types.Comment
+	for _, comment := range st.comments {
+		if comment.CardId != cardId {
+			continue
+		}
+		if comment.CreatedAt > afterAt || (comment.CreatedAt == afterAt && comment.Id > afterId) {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].CreatedAt != comments[j].CreatedAt {
+			return comments[i].CreatedAt < comments[j].CreatedAt
+		}
+		return comments[i].Id < comments[j].Id
+	})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments
+}
+
+func (st *memoryState) createComment(cardId string, parentId string, body string, author string) (*types.Comment, error) {
+	if _, found := st.cards[cardId]; !found {
+		return nil, itemNotFound(cardId)
+	}
+	if _, found := st.comments[parentId]; parentId != "" && !found {
+		return nil, itemNotFound(parentId)
+	}
+	created := unixNow()
+	comment := types.Comment{
+		Id:        utils.GetUUID(),
+		CardId:    cardId,
+		ParentId:  parentId,
+		Body:      body,
+		CreatedAt: created,
+		UpdatedAt: created,
+		CreatedBy: author,
+		UpdatedBy: author,
+		Version:   1,
+	}
+	st.comments[comment.Id] = comment
+	return &comment, nil
+}
+
+func (st *memoryState) updateComment(id string, body string, version int, author string) (*types.Comment, error) {
+	comment, found := st.comments[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("comment", id, comment.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	st.lastEditId++
+	updated := unixNow()
+	st.commentEdits = append(st.commentEdits, types.CommentEdit{
+		Id:        st.lastEditId,
+		CommentId: id,
+		Body:      comment.Body,
+		CreatedAt: updated,
+		CreatedBy: author,
+	})
+	comment.Body = body
+	comment.UpdatedAt = updated
+	comment.UpdatedBy = author
+	comment.Version++
+	st.comments[id] = comment
+	return &comment, nil
+}
+
+// removeComment drops the comment with its replies and edits.
+func (st *memoryState) removeComment(id string) {
+	delete(st.comments, id)
+	st.commentEdits = slices.DeleteFunc(st.commentEdits, func(edit types.CommentEdit) bool {
+		return edit.CommentId == id
+	})
+	for replyId, reply := range st.comments {
+		if reply.ParentId == id {
+			st.removeComment(replyId)
+		}
+	}
+}
+
+func (st *memoryState) deleteComment(id string) error {
+	if _, found := st.comments[id]; !found {
+		return NoEffect{}
+	}
+	st.removeComment(id)
+	return nil
+}
+
+func (st *memoryState) getCommentEdits(commentId string) []types.CommentEdit {
+	var edits []types.CommentEdit
+	for _, edit := range st.commentEdits {
+		if edit.CommentId == commentId {
+			edits = append(edits, edit)
+		}
+	}
+	return edits
+}
+
+func (st *memoryState) cardChecklists(cardId string) []types.Checklist {
+	var checklists []types.Checklist
+	for _, checklist := range st.checklists {
+		if checklist.CardId == cardId {
+			checklists = append(checklists, checklist)
+		}
+	}
+	sort.Slice(checklists, func(i, j int) bool { return checklists[i].Order < checklists[j].Order })
+	return checklists
+}
+
+func (st *memoryState) checklistItems(checklistId string) []types.ChecklistItem {
+	var items []types.ChecklistItem
+	for _, item := range st.items {
+		if item.ChecklistId == checklistId {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Order < items[j].Order })
+	return items
+}
+
+// shiftChecklists moves the card checklists with a draw order from first on by delta, except is left in place.
+func (st *memoryState) shiftChecklists(cardId string, first int, delta int, except string) {
+	for id, checklist := range st.checklists {
+		if checklist.CardId == cardId && checklist.Order >= first && id != except {
+			checklist.Order += delta
+			st.checklists[id] = checklist
+		}
+	}
+}
+
+// shiftChecklistItems moves the checklist items with a draw order from first on by delta, except is left in place.
+func (st *memoryState) shiftChecklistItems(checklistId string, first int, delta int, except string) {
+	for id, item := range st.items {
+		if item.ChecklistId == checklistId && item.Order >= first && id != except {
+			item.Order += delta
+			st.items[id] = item
+		}
+	}
+}
+
+// insertOrder clamps position to the count siblings, 0 or a position past the last one appends.
+func insertOrder(position int, count int) int {
+	if position <= 0 || position > count {
+		return count + 1
+	}
+	return position
+}
+
+func (st *memoryState) checklistProgress(cardId string) types.ChecklistProgressJson {
+	var progress types.ChecklistProgressJson
+	for _, item := range st.items {
+		if st.checklists[item.ChecklistId].CardId != cardId {
+			continue
+		}
+		progress.Total++
+		if item.Done {
+			progress.Done++
+		}
+	}
+	return progress
+}
+
+func (st *memoryState) getChecklist(id string) (*types.Checklist, error) {
+	checklist, found := st.checklists[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &checklist, nil
+}
+
+func (st *memoryState) getChecklists(cardId string) []types.ChecklistJson {
+	checklists := st.cardChecklists(cardId)
+	output := make([]types.ChecklistJson, len(checklists))
+	for idx, checklist := range checklists {
+		output[idx] = *checklist.Json()
+		for _, item := range st.checklistItems(checklist.Id) {
+			output[idx].Items = append(output[idx].Items, *item.Json())
+		}
+	}
+	return output
+}
+
+func (st *memoryState) createChecklist(cardId string, name string, position int, author string) (*types.Checklist, error) {
+	if _, found := st.cards[cardId]; !found {
+		return nil, itemNotFound(cardId)
+	}
+	position = insertOrder(position, len(st.cardChecklists(cardId)))
+	st.shiftChecklists(cardId, position, 1, "")
+	created := unixNow()
+	checklist := types.Checklist{
+		Id:        utils.GetUUID(),
+		CardId:    cardId,
+		Name:      name,
+		Order:     position,
+		CreatedAt: created,
+		UpdatedAt: created,
+		CreatedBy: author,
+		UpdatedBy: author,
+		Version:   1,
+	}
+	st.checklists[checklist.Id] = checklist
+	return &checklist, nil
+}
+
+func (st *memoryState) renameChecklist(id string, name string, version int, author string) (*types.Checklist, error) {
+	checklist, found := st.checklists[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("checklist", id, checklist.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	checklist.Name = name
+	checklist.UpdatedAt = unixNow()
+	checklist.UpdatedBy = author
+	checklist.Version++
+	st.checklists[id] = checklist
+	return &checklist, nil
+}
+
+func (st *memoryState) moveChecklist(id string, position int, version int, author string) (*types.Checklist, error) {
+	checklist, found := st.checklists[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("checklist", id, checklist.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	st.shiftChecklists(checklist.CardId, checklist.Order+1, -1, id)
+	position = insertOrder(position, len(st.cardChecklists(checklist.CardId))-1)
+	st.shiftChecklists(checklist.CardId, position, 1, id)
+	checklist.Order = position
+	checklist.UpdatedAt = unixNow()
+	checklist.UpdatedBy = author
+	checklist.Version++
+	st.checklists[id] = checklist
+	return &checklist, nil
+}
+
+// removeChecklist drops the checklist with its items without touching its neighbours.
+func (st *memoryState) removeChecklist(id string) {
+	delete(st.checklists, id)
+	for itemId, item := range st.items {
+		if item.ChecklistId == id {
+			delete(st.items, itemId)
+		}
+	}
+}
+
+func (st *memoryState) deleteChecklist(id string) error {
+	checklist, found := st.checklists[id]
+	if !found {
+		return NoEffect{}
+	}
+	st.shiftChecklists(checklist.CardId, checklist.Order+1, -1, id)
+	st.removeChecklist(id)
+	return nil
+}
+
+func (st *memoryState) getChecklistItem(id string) (*types.ChecklistItem, error) {
+	item, found := st.items[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &item, nil
+}
+
+func (st *memoryState) createChecklistItem(checklistId string, text string, position int, author string) (*types.ChecklistItem, error) {
+	if _, found := st.checklists[checklistId]; !found {
+		return nil, itemNotFound(checklistId)
+	}
+	position = insertOrder(position, len(st.checklistItems(checklistId)))
+	st.shiftChecklistItems(checklistId, position, 1, "")
+	created := unixNow()
+	item := types.ChecklistItem{
+		Id:          utils.GetUUID(),
+		ChecklistId: checklistId,
+		Text:        text,
+		Order:       position,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		CreatedBy:   author,
+		UpdatedBy:   author,
+		Version:     1,
+	}
+	st.items[item.Id] = item
+	return &item, nil
+}
+
+func (st *memoryState) updateChecklistItem(id string, text string, done bool, version int, author string) (*types.ChecklistItem, error) {
+	item, found := st.items[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("checklist item", id, item.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	item.Text = text
+	item.Done = done
+	item.UpdatedAt = unixNow()
+	item.UpdatedBy = author
+	item.Version++
+	st.items[id] = item
+	return &item, nil
+}
+
+func (st *memoryState) moveChecklistItem(id string, position int, version int, author string) (*types.ChecklistItem, error) {
+	item, found := st.items[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	err := checkVersion("checklist item", id, item.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	st.shiftChecklistItems(item.ChecklistId, item.Order+1, -1, id)
+	position = insertOrder(position, len(st.checklistItems(item.ChecklistId))-1)
+	st.shiftChecklistItems(item.ChecklistId, position, 1, id)
+	item.Order = position
+	item.UpdatedAt = unixNow()
+	item.UpdatedBy = author
+	item.Version++
+	st.items[id] = item
+	return &item, nil
+}
+
+func (st *memoryState) deleteChecklistItem(id string) error {
+	item, found := st.items[id]
+	if !found {
+		return NoEffect{}
+	}
+	st.shiftChecklistItems(item.ChecklistId, item.Order+1, -1, id)
+	delete(st.items, id)
+	return nil
+}
+
+func (st *memoryState) addProjectMember(projectId string, userId string, role string, author string) error {
+	key := memberKey{projectId, userId}
+	if _, found := st.members[key]; found {
+		return DuplicateError{fmt.Sprintf("user %s is already a member of project %s", userId, projectId)}
+	}
+	created := unixNow()
+	st.members[key] = types.Member{
+		ProjectId: projectId,
+		UserId:    userId,
+		Role:      role,
+		CreatedAt: created,
+		UpdatedAt: created,
+		CreatedBy: author,
+		UpdatedBy: author,
+	}
+	return nil
+}
+
+func (st *memoryState) getProjectMember(projectId string, userId string) (*types.Member, error) {
+	member, found := st.members[memberKey{projectId, userId}]
+	if !found {
+		return nil, NotFoundError{fmt.Sprintf("member %s of project %s", userId, projectId), nil}
+	}
+	member.Login = st.users[userId].Login
+	return &member, nil
+}
+
+func (st *memoryState) getProjectMembers(projectId string) []types.Member {
+	var members []types.Member
+	for key, member := range st.members {
+		if key.projectId == projectId {
+			member.Login = st.users[key.userId].Login
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].CreatedAt != members[j].CreatedAt {
+			return members[i].CreatedAt < members[j].CreatedAt
+		}
+		return members[i].Login < members[j].Login
+	})
+	return members
+}
+
+func (st *memoryState) updateProjectMember(projectId string, userId string, role string, author string) error {
+	key := memberKey{projectId, userId}
+	member, found := st.members[key]
+	if !found {
+		return NoEffect{}
+	}
+	if member.Role == types.RoleOwner && role != types.RoleOwner && st.countProjectOwners(projectId) <= 1 {
+		return LastOwnerError{projectId}
+	}
+	member.Role = role
+	member.UpdatedAt = unixNow()
+	member.UpdatedBy = author
+	st.members[key] = member
+	return nil
+}
+
+func (st *memoryState) removeProjectMember(projectId string, userId string) error {
+	key := memberKey{projectId, userId}
+	member, found := st.members[key]
+	if !found {
+		return NoEffect{}
+	}
+	if member.Role == types.RoleOwner && st.countProjectOwners(projectId) <= 1 {
+		return LastOwnerError{projectId}
+	}
+	delete(st.members, key)
+	for cardId := range st.assignees {
+		if st.columns[st.cards[cardId].ColumnId].ProjectId == projectId {
+			st.unassignCard(cardId, userId)
+		}
+	}
+	return nil
+}
+
+func (st *memoryState) countProjectOwners(projectId string) int {
+	count := 0
+	for key, member := range st.members {
+		if key.projectId == projectId && member.Role == types.RoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+func (st *memoryState) createUser(login string, passwordHash string) (*types.User, error) {
+	for _, user := range st.users {
+		if user.Login == login {
+			return nil, DuplicateError{fmt.Sprintf("login %s is already taken", login)}
+		}
+	}
+	created := unixNow()
+	user := types.User{Id: utils.GetUUID(), Login: login, PasswordHash: passwordHash, CreatedAt: created, UpdatedAt: created}
+	st.users[user.Id] = user
+	return &user, nil
+}
+
+func (st *memoryState) getUserById(id string) (*types.User, error) {
+	user, found := st.users[id]
+	if !found {
+		return nil, itemNotFound(id)
+	}
+	return &user, nil
+}
+
+func (st *memoryState) getUserByLogin(login string) (*types.User, error) {
+	for _, user := range st.users {
+		if user.Login == login {
+			return &user, nil
+		}
+	}
+	return nil, itemNotFound(login)
+}
+
+func (st *memoryState) getSessionUser(tokenHash string) (*types.User, error) {
+	session, found := st.sessions[tokenHash]
+	if !found || session.expiresAt <= unixNow() {
+		return nil, itemNotFound(tokenHash)
+	}
+	return st.getUserById(session.userId)
+}
+
+func (st *memoryState) getAccessTokens(userId string) []types.AccessToken {
+	var tokens []types.AccessToken
+	for _, stored := range st.tokens {
+		if stored.token.UserId == userId {
+			tokens = append(tokens, stored.token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].CreatedAt != tokens[j].CreatedAt {
+			return tokens[i].CreatedAt < tokens[j].CreatedAt
+		}
+		return tokens[i].Id < tokens[j].Id
+	})
+	return tokens
+}
+
+func (st *memoryState) getAccessTokenUser(tokenHash string) (*types.AccessToken, *types.User, error) {
+	for _, stored := range st.tokens {
+		if stored.hash == tokenHash && stored.token.RevokedAt == 0 {
+			user, err := st.getUserById(stored.token.UserId)
+			if err != nil {
+				return nil, nil, err
+			}
+			token := stored.token
+			return &token, user, nil
+		}
+	}
+	return nil, nil, itemNotFound(tokenHash)
+}
+
+func (st *memoryState) revokeAccessToken(userId string, id string) error {
+	stored, found := st.tokens[id]
+	if !found || stored.token.UserId != userId || stored.token.RevokedAt != 0 {
+		return NoEffect{}
+	}
+	stored.token.RevokedAt = unixNow()
+	st.tokens[id] = stored
+	return nil
+}
+
+func (s *MemoryStorage) CreateProject(id string, project *types.KanbanJson, author string) error {
+	return s.atomic(func(st *memoryState) error {
+		return st.createProject(id, project, author)
+	})
+}
+
+func (s *MemoryStorage) GetProject(id string) (*types.KanbanJson, error) {
+	defer s.lock()()
+	return s.state.getProject(id)
+}
+
+func (s *MemoryStorage) UpdateProjectData(id string, name string, version int, author string) error {
+	defer s.lock()()
+	return s.state.updateProjectData(id, name, version, author)
+}
+
+func (s *MemoryStorage) DeleteProject(id string) error {
+	defer s.lock()()
+	return s.state.deleteProject(id)
+}
+
+func (s *MemoryStorage) GetColumn(id string) (*types.Column, error) {
+	defer s.lock()()
+	return s.state.getColumn(id)
+}
+
+func (s *MemoryStorage) GetColumnProjectId(id string) (string, error) {
+	defer s.lock()()
+	column, err := s.state.getColumn(id)
+	if err != nil {
+		return "", err
+	}
+	return column.ProjectId, nil
+}
+
+func (s *MemoryStorage) CreateColumns(projectId string, columns []types.ColumnJson, author string) ([]types.ColumnJson, error) {
+	var newColumns []types.ColumnJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newColumns, err = st.createColumns(projectId, columns, author)
+		return err
+	})
+	return newColumns, err
+}
+
+func (s *MemoryStorage) CreateColumnAt(projectId string, column *types.ColumnJson, position int, author string) (*types.ColumnJson, error) {
+	var newColumn *types.ColumnJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newColumn, err = st.createColumnAt(projectId, column, position, author)
+		return err
+	})
+	return newColumn, err
+}
+
+func (s *MemoryStorage) UpdateColumnData(column *types.Column, author string) error {
+	defer s.lock()()
+	return s.state.updateColumnData(column, author)
+}
+
+func (s *MemoryStorage) MoveColumn(id string, position int, version int, author string) ([]types.ColumnJson, error) {
+	defer s.lock()()
+	return s.state.moveColumn(id, position, version, author)
+}
+
+func (s *MemoryStorage) DeleteColumn(id string) error {
+	defer s.lock()()
+	return s.state.deleteColumn(id)
+}
+
+func (s *MemoryStorage) GetCard(id string) (*types.Card, error) {
+	defer s.lock()()
+	return s.state.getCard(id)
+}
+
+func (s *MemoryStorage) GetCardProjectId(id string) (string, error) {
+	defer s.lock()()
+	return s.state.getCardProjectId(id)
+}
+
+func (s *MemoryStorage) GetDueCards(projectId string, after int, before int) ([]types.CardJson, error) {
+	defer s.lock()()
+	return s.state.getDueCards(projectId, after, before), nil
+}
+
+func (s *MemoryStorage) CreateCards(columnId string, cards *[]types.CardJson, author string) ([]types.CardJson, error) {
+	var newCards []types.CardJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newCards, err = st.createCards(columnId, cards, author)
+		return err
+	})
+	return newCards, err
+}
+
+func (s *MemoryStorage) CreateCardAt(card *types.CardJson, position int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newCard, err = st.createCardAt(card, position, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *MemoryStorage) UpdateCard(card *types.CardJson, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newCard, err = st.updateCard(card, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *MemoryStorage) MoveCard(cardId string, columnId string, position int, version int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newCard, err = st.moveCard(cardId, columnId, position, version, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *MemoryStorage) DeleteCard(id string) error {
+	defer s.lock()()
+	return s.state.deleteCard(id)
+}
+
+func (s *MemoryStorage) CreateCardTags(cardId string, tagId string) error {
+	defer s.lock()()
+	return s.state.createCardTags(cardId, tagId)
+}
+
+func (s *MemoryStorage) RemoveCardTags(cardId string, tagId string) error {
+	defer s.lock()()
+	s.state.removeCardTags(cardId, tagId)
+	return nil
+}
+
+func (s *MemoryStorage) AssignCard(cardId string, userId string, author string) error {
+	defer s.lock()()
+	return s.state.assignCard(cardId, userId)
+}
+
+func (s *MemoryStorage) UnassignCard(cardId string, userId string) error {
+	defer s.lock()()
+	return s.state.unassignCard(cardId, userId)
+}
+
+func (s *MemoryStorage) GetCardAssigneeIds(cardId string) ([]string, error) {
+	defer s.lock()()
+	return slices.Clone(s.state.assignees[cardId]), nil
+}
+
+func (s *MemoryStorage) GetAssignedCards(userId string) ([]types.AssignedCardJson, error) {
+	defer s.lock()()
+	return s.state.getAssignedCards(userId), nil
+}
+
+func (s *MemoryStorage) GetTag(id string) (*types.Tag, error) {
+	defer s.lock()()
+	return s.state.getTag(id)
+}
+
+func (s *MemoryStorage) GetTagProjectId(id string) (string, error) {
+	defer s.lock()()
+	return s.state.getTagProjectId(id)
+}
+
+func (s *MemoryStorage) GetTagsByCard(cardId string) ([]types.Tag, error) {
+	defer s.lock()()
+	return s.state.getTagsByCard(cardId), nil
+}
+
+func (s *MemoryStorage) CreateTags(projectId string, tags *[]types.TagJson, author string) ([]types.TagJson, error) {
+	defer s.lock()()
+	return s.state.createTags(projectId, tags, author)
+}
+
+func (s *MemoryStorage) UpdateTag(tag *types.TagJson, author string) error {
+	defer s.lock()()
+	return s.state.updateTag(tag, author)
+}
+
+func (s *MemoryStorage) DeleteTag(id string) error {
+	defer s.lock()()
+	return s.state.deleteTag(id)
+}
+
+func (s *MemoryStorage) GetCardHistory(cardId string) ([]types.CardUpdateRecordJson, error) {
+	defer s.lock()()
+	return decodeCardHistory(s.state.getCardUpdateRecords(cardId))
+}
+
+func (s *MemoryStorage) RevertCard(cardId string, recordId int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.atomic(func(st *memoryState) error {
+		var err error
+		newCard, err = st.revertCard(cardId, recordId, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *MemoryStorage) GetComment(id string) (*types.Comment, error) {
+	defer s.lock()()
+	return s.state.getComment(id)
+}
+
+func (s *MemoryStorage) GetCommentProjectId(id string) (string, error) {
+	defer s.lock()()
+	comment, err := s.state.getComment(id)
+	if err != nil {
+		return "", err
+	}
+	return s.state.getCardProjectId(comment.CardId)
+}
+
+func (s *MemoryStorage) GetComments(cardId string, afterAt int, afterId string, limit int) ([]types.Comment, error) {
+	defer s.lock()()
+	return s.state.getComments(cardId, afterAt, afterId, limit), nil
+}
+
+func (s *MemoryStorage) CreateComment(cardId string, parentId string, body string, author string) (*types.Comment, error) {
+	defer s.lock()()
+	return s.state.createComment(cardId, parentId, body, author)
+}
+
+func (s *MemoryStorage) UpdateComment(id string, body string, version int, author string) (*types.Comment, error) {
+	defer s.lock()()
+	return s.state.updateComment(id, body, version, author)
+}
+
+func (s *MemoryStorage) DeleteComment(id string) error {
+	defer s.lock()()
+	return s.state.deleteComment(id)
+}
+
+func (s *MemoryStorage) GetCommentEdits(commentId string) ([]types.CommentEdit, error) {
+	defer s.lock()()
+	return s.state.getCommentEdits(commentId), nil
+}
+
+func (s *MemoryStorage) GetChecklist(id string) (*types.Checklist, error) {
+	defer s.lock()()
+	return s.state.getChecklist(id)
+}
+
+func (s *MemoryStorage) GetChecklistProjectId(id string) (string, error) {
+	defer s.lock()()
+	checklist, err := s.state.getChecklist(id)
+	if err != nil {
+		return "", err
+	}
+	return s.state.getCardProjectId(checklist.CardId)
+}
+
+func (s *MemoryStorage) GetChecklists(cardId string) ([]types.ChecklistJson, error) {
+	defer s.lock()()
+	return s.state.getChecklists(cardId), nil
+}
+
+func (s *MemoryStorage) GetChecklistProgress(cardId string) (*types.ChecklistProgressJson, error) {
+	defer s.lock()()
+	progress := s.state.checklistProgress(cardId)
+	return &progress, nil
+}
+
+func (s *MemoryStorage) CreateChecklist(cardId string, name string, position int, author string) (*types.Checklist, error) {
+	defer s.lock()()
+	return s.state.createChecklist(cardId, name, position, author)
+}
+
+func (s *MemoryStorage) RenameChecklist(id string, name string, version int, author string) (*types.Checklist, error) {
+	defer s.lock()()
+	return s.state.renameChecklist(id, name, version, author)
+}
+
+func (s *MemoryStorage) MoveChecklist(id string, position int, version int, author string) (*types.Checklist, error) {
+	defer s.lock()()
+	return s.state.moveChecklist(id, position, version, author)
+}
+
+func (s *MemoryStorage) DeleteChecklist(id string) error {
+	defer s.lock()()
+	return s.state.deleteChecklist(id)
+}
+
+func (s *MemoryStorage) GetChecklistItem(id string) (*types.ChecklistItem, error) {
+	defer s.lock()()
+	return s.state.getChecklistItem(id)
+}
+
+func (s *MemoryStorage) GetChecklistItemProjectId(id string) (string, error) {
+	defer s.lock()()
+	item, err := s.state.getChecklistItem(id)
+	if err != nil {
+		return "", err
+	}
+	checklist, err := s.state.getChecklist(item.ChecklistId)
+	if err != nil {
+		return "", err
+	}
+	return s.state.getCardProjectId(checklist.CardId)
+}
+
+func (s *MemoryStorage) GetChecklistItems(checklistId string) ([]types.ChecklistItem, error) {
+	defer s.lock()()
+	return s.state.checklistItems(checklistId), nil
+}
+
+func (s *MemoryStorage) CreateChecklistItem(checklistId string, text string, position int, author string) (*types.ChecklistItem, error) {
+	defer s.lock()()
+	return s.state.createChecklistItem(checklistId, text, position, author)
+}
+
+func (s *MemoryStorage) UpdateChecklistItem(id string, text string, done bool, version int, author string) (*types.ChecklistItem, error) {
+	defer s.lock()()
+	return s.state.updateChecklistItem(id, text, done, version, author)
+}
+
+func (s *MemoryStorage) ToggleChecklistItem(id string, author string) (*types.ChecklistItem, error) {
+	defer s.lock()()
+	item, err := s.state.getChecklistItem(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.state.updateChecklistItem(id, item.Text, !item.Done, 0, author)
+}
+
+func (s *MemoryStorage) MoveChecklistItem(id string, position int, version int, author string) (*types.ChecklistItem, error) {
+	defer s.lock()()
+	return s.state.moveChecklistItem(id, position, version, author)
+}
+
+func (s *MemoryStorage) DeleteChecklistItem(id string) error {
+	defer s.lock()()
+	return s.state.deleteChecklistItem(id)
+}
+
+func (s *MemoryStorage) AddProjectMember(projectId string, userId string, role string, author string) error {
+	defer s.lock()()
+	return s.state.addProjectMember(projectId, userId, role, author)
+}
+
+func (s *MemoryStorage) GetProjectMember(projectId string, userId string) (*types.Member, error) {
+	defer s.lock()()
+	return s.state.getProjectMember(projectId, userId)
+}
+
+func (s *MemoryStorage) GetProjectMembers(projectId string) ([]types.Member, error) {
+	defer s.lock()()
+	return s.state.getProjectMembers(projectId), nil
+}
+
+func (s *MemoryStorage) UpdateProjectMember(projectId string, userId string, role string, author string) error {
+	defer s.lock()()
+	return s.state.updateProjectMember(projectId, userId, role, author)
+}
+
+func (s *MemoryStorage) RemoveProjectMember(projectId string, userId string) error {
+	defer s.lock()()
+	return s.state.removeProjectMember(projectId, userId)
+}
+
+func (s *MemoryStorage) CountProjectOwners(projectId string) (int, error) {
+	defer s.lock()()
+	return s.state.countProjectOwners(projectId), nil
+}
+
+func (s *MemoryStorage) CreateUser(login string, passwordHash string) (*types.User, error) {
+	defer s.lock()()
+	return s.state.createUser(login, passwordHash)
+}
+
+func (s *MemoryStorage) GetUserById(id string) (*types.User, error) {
+	defer s.lock()()
+	return s.state.getUserById(id)
+}
+
+func (s *MemoryStorage) GetUserByLogin(login string) (*types.User, error) {
+	defer s.lock()()
+	return s.state.getUserByLogin(login)
+}
+
+func (s *MemoryStorage) CreateSession(userId string, tokenHash string, expiresAt int) error {
+	defer s.lock()()
+	s.state.sessions[tokenHash] = memorySession{userId, expiresAt}
+	return nil
+}
+
+func (s *MemoryStorage) GetSessionUser(tokenHash string) (*types.User, error) {
+	defer s.lock()()
+	return s.state.getSessionUser(tokenHash)
+}
+
+func (s *MemoryStorage) DeleteSession(tokenHash string) error {
+	defer s.lock()()
+	if _, found := s.state.sessions[tokenHash]; !found {
+		return NoEffect{}
+	}
+	delete(s.state.sessions, tokenHash)
+	return nil
+}
+
+func (s *MemoryStorage) CreateAccessToken(userId string, name string, tokenHash string, scopes []string) (*types.AccessToken, error) {
+	defer s.lock()()
+	token := types.AccessToken{Id: utils.GetUUID(), UserId: userId, Name: name, Scopes: scopes, CreatedAt: unixNow()}
+	s.state.tokens[token.Id] = memoryToken{token, tokenHash}
+	return &token, nil
+}
+
+func (s *MemoryStorage) GetAccessTokens(userId string) ([]types.AccessToken, error) {
+	defer s.lock()()
+	return s.state.getAccessTokens(userId), nil
+}
+
+func (s *MemoryStorage) GetAccessTokenUser(tokenHash string) (*types.AccessToken, *types.User, error) {
+	defer s.lock()()
+	return s.state.getAccessTokenUser(tokenHash)
+}
+
+func (s *MemoryStorage) TouchAccessToken(id string) error {
+	defer s.lock()()
+	if stored, found := s.state.tokens[id]; found {
+		stored.token.LastUsedAt = unixNow()
+		s.state.tokens[id] = stored
+	}
+	return nil
+}
+
+func (s *MemoryStorage) RevokeAccessToken(userId string, id string) error {
+	defer s.lock()()
+	return s.state.revokeAccessToken(userId, id)
+}
@@ -0,0 +1,246 @@
+package db_driver
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is one embedded schema version, files are named <version>_<name>.sql.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// MigrationStatus tells whether a version was applied, AppliedAt is 0 for pending versions
+// and Known is false for versions recorded in the database but missing from this binary.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt int
+	Known     bool
+}
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS SchemaMigrations (
+	version INT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at BIGINT NOT NULL
+);`
+
+// migrationLockName is taken with GET_LOCK so instances starting together don't apply the same version twice.
+const migrationLockName = "kanban_schema_migrations"
+
+// LoadMigrations returns the embedded migrations of the dialect ordered by version.
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s dialect: %w", dialect, err)
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || path.Ext(fileName) != ".sql" {
+			continue
+		}
+		versionPart, name, found := strings.Cut(strings.TrimSuffix(fileName, ".sql"), "_")
+		version, err := strconv.Atoi(versionPart)
+		if !found || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.sql", fileName)
+		}
+		data, err := migrationFiles.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{version, name, splitStatements(string(data))})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migration version %d is used twice", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// splitStatements cuts a script into statements the way the mysql client does,
+// a DELIMITER line switches the terminator so procedure bodies can hold semicolons.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	delimiter := ";"
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		if fields := strings.Fields(trimmed); len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+			delimiter = fields[1]
+			continue
+		}
+		if strings.HasSuffix(trimmed, delimiter) {
+			current.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t"), delimiter))
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+func lockMigrations(ctx context.Context, conn *sql.Conn, dialect string) (func(), error) {
+	if dialect != DialectMySQL {
+		return func() {}, nil
+	}
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60);", migrationLockName).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		return nil, fmt.Errorf("timed out waiting for another instance to finish migrations")
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?);", migrationLockName)
+	}, nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]MigrationStatus, error) {
+	_, err := conn.ExecContext(ctx, createMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM SchemaMigrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]MigrationStatus)
+	for rows.Next() {
+		var status MigrationStatus
+		err = rows.Scan(&status.Version, &status.Name, &status.AppliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[status.Version] = status
+	}
+	return applied, rows.Err()
+}
+
+// GetMigrationStatus lists the embedded versions together with the ones recorded in the database.
+func GetMigrationStatus(db *sql.DB, dialect string) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{migration.Version, migration.Name, applied[migration.Version].AppliedAt, true}
+		delete(applied, migration.Version)
+		statuses = append(statuses, status)
+	}
+	for _, status := range applied {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Migrate applies the pending migrations in order and returns them, with dryRun nothing is executed.
+// MySQL commits DDL implicitly, so a failing migration there can leave its earlier statements applied.
+func Migrate(db *sql.DB, dialect string, dryRun bool) ([]Migration, error) {
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	unlock, err := lockMigrations(ctx, conn, dialect)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if _, found := applied[migration.Version]; !found {
+			pending = append(pending, migration)
+		}
+	}
+	if dryRun {
+		return pending, nil
+	}
+	for idx, migration := range pending {
+		err = applyMigration(ctx, conn, &migration)
+		if err != nil {
+			return pending[:idx], fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+	}
+	return pending, nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, migration *Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, statement := range migration.Statements {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO SchemaMigrations (version, name, applied_at) VALUES (?, ?, ?);",
+		migration.Version, migration.Name, time.Now().Unix())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
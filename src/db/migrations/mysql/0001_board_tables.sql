@@ -0,0 +1,57 @@
+-- IF NOT EXISTS lets databases created before migrations were versioned adopt this version.
+
+CREATE TABLE IF NOT EXISTS Projects (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ProjectColumns (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	project_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	draw_order INT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	INDEX project_columns_project_id (project_id, draw_order),
+	FOREIGN KEY (project_id) REFERENCES Projects (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Cards (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	column_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL,
+	draw_order INT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	INDEX cards_column_id (column_id, draw_order),
+	FOREIGN KEY (column_id) REFERENCES ProjectColumns (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Tags (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	project_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	color VARCHAR(32) NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES Projects (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS CardsTags (
+	card_id VARCHAR(36) NOT NULL,
+	tag_id VARCHAR(36) NOT NULL,
+	PRIMARY KEY (card_id, tag_id),
+	FOREIGN KEY (card_id) REFERENCES Cards (id) ON DELETE CASCADE,
+	FOREIGN KEY (tag_id) REFERENCES Tags (id) ON DELETE CASCADE
+);
@@ -0,0 +1,174 @@
+-- Procedures are dropped first so databases that already had them get the versions the code expects.
+
+DROP PROCEDURE IF EXISTS read_project;
+DROP PROCEDURE IF EXISTS read_column_by_id;
+DROP PROCEDURE IF EXISTS read_columns_by_project_id;
+DROP PROCEDURE IF EXISTS read_card_by_id;
+DROP PROCEDURE IF EXISTS read_cards_by_column_id;
+DROP PROCEDURE IF EXISTS read_tags_by_card_id;
+DROP PROCEDURE IF EXISTS read_tags_by_project_id;
+DROP PROCEDURE IF EXISTS create_project;
+DROP PROCEDURE IF EXISTS update_project_data;
+DROP PROCEDURE IF EXISTS create_column;
+DROP PROCEDURE IF EXISTS update_column_data;
+DROP PROCEDURE IF EXISTS pop_column_reorder;
+DROP PROCEDURE IF EXISTS create_card;
+DROP PROCEDURE IF EXISTS update_card;
+DROP PROCEDURE IF EXISTS pop_card_reorder;
+DROP PROCEDURE IF EXISTS create_tag;
+DROP PROCEDURE IF EXISTS create_card_update_record;
+
+DELIMITER //
+
+CREATE PROCEDURE read_project(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, name, created_at, updated_at, created_by, updated_by FROM Projects WHERE id = p_id;
+END //
+
+CREATE PROCEDURE read_column_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, project_id, name, draw_order, created_at, updated_at, created_by, updated_by
+	FROM ProjectColumns WHERE id = p_id;
+END //
+
+CREATE PROCEDURE read_columns_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT id, project_id, name, draw_order, created_at, updated_at, created_by, updated_by
+	FROM ProjectColumns WHERE project_id = p_project_id ORDER BY draw_order;
+END //
+
+CREATE PROCEDURE read_card_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, column_id, name, description, draw_order, created_at, updated_at, created_by, updated_by
+	FROM Cards WHERE id = p_id;
+END //
+
+CREATE PROCEDURE read_cards_by_column_id(IN p_column_id VARCHAR(36))
+BEGIN
+	SELECT id, column_id, name, description, draw_order, created_at, updated_at, created_by, updated_by
+	FROM Cards WHERE column_id = p_column_id ORDER BY draw_order;
+END //
+
+CREATE PROCEDURE read_tags_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by
+	FROM CardsTags ct JOIN Tags t ON t.id = ct.tag_id
+	WHERE ct.card_id = p_card_id ORDER BY t.created_at, t.id;
+END //
+
+CREATE PROCEDURE read_tags_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by
+	FROM Tags t WHERE t.project_id = p_project_id ORDER BY t.created_at, t.id;
+END //
+
+CREATE PROCEDURE create_project(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Projects
+		(id, name, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_name, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+CREATE PROCEDURE update_project_data(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE Projects SET name = p_name, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE create_column(
+	IN p_project_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_order INT, IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO ProjectColumns
+		(id, project_id, name, draw_order, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_project_id, p_name, p_order, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_column_data moves the column to p_order within its project and shifts the columns in between,
+-- orders below 1 keep the column in place.
+CREATE PROCEDURE update_column_data(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36), IN p_order INT)
+BEGIN
+	DECLARE v_project_id VARCHAR(36);
+	DECLARE v_order INT;
+	DECLARE v_count INT;
+	DECLARE v_to INT;
+	SELECT project_id, draw_order INTO v_project_id, v_order FROM ProjectColumns WHERE id = p_id;
+	IF p_order > 0 AND v_project_id IS NOT NULL THEN
+		SELECT count(*) INTO v_count FROM ProjectColumns WHERE project_id = v_project_id;
+		SET v_to = GREATEST(1, LEAST(p_order, v_count));
+		IF v_to < v_order THEN
+			UPDATE ProjectColumns SET draw_order = draw_order + 1
+			WHERE project_id = v_project_id AND draw_order BETWEEN v_to AND v_order - 1;
+		ELSEIF v_to > v_order THEN
+			UPDATE ProjectColumns SET draw_order = draw_order - 1
+			WHERE project_id = v_project_id AND draw_order BETWEEN v_order + 1 AND v_to;
+		END IF;
+		UPDATE ProjectColumns SET draw_order = v_to WHERE id = p_id;
+	END IF;
+	UPDATE ProjectColumns SET name = p_name, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE pop_column_reorder(IN p_project_id VARCHAR(36), IN p_order INT)
+BEGIN
+	UPDATE ProjectColumns SET draw_order = draw_order - 1 WHERE project_id = p_project_id AND draw_order > p_order;
+END //
+
+CREATE PROCEDURE create_card(
+	IN p_column_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_order INT, IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Cards
+		(id, column_id, name, description, draw_order, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_column_id, p_name, p_description, p_order, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_card moves a card staying in its column to p_order and shifts the cards in between,
+-- a card coming from another column takes p_order as it is.
+CREATE PROCEDURE update_card(
+	IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_author VARCHAR(36), IN p_order INT)
+BEGIN
+	DECLARE v_column_id VARCHAR(36);
+	DECLARE v_order INT;
+	DECLARE v_count INT;
+	DECLARE v_to INT;
+	SELECT column_id, draw_order INTO v_column_id, v_order FROM Cards WHERE id = p_id;
+	IF v_column_id = p_column_id THEN
+		IF p_order > 0 THEN
+			SELECT count(*) INTO v_count FROM Cards WHERE column_id = p_column_id;
+			SET v_to = GREATEST(1, LEAST(p_order, v_count));
+			IF v_to < v_order THEN
+				UPDATE Cards SET draw_order = draw_order + 1
+				WHERE column_id = p_column_id AND draw_order BETWEEN v_to AND v_order - 1;
+			ELSEIF v_to > v_order THEN
+				UPDATE Cards SET draw_order = draw_order - 1
+				WHERE column_id = p_column_id AND draw_order BETWEEN v_order + 1 AND v_to;
+			END IF;
+			UPDATE Cards SET draw_order = v_to WHERE id = p_id;
+		END IF;
+	ELSE
+		UPDATE Cards SET draw_order = p_order WHERE id = p_id;
+	END IF;
+	UPDATE Cards
+	SET column_id = p_column_id, name = p_name, description = p_description,
+		updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+CREATE PROCEDURE pop_card_reorder(IN p_column_id VARCHAR(36), IN p_order INT)
+BEGIN
+	UPDATE Cards SET draw_order = draw_order - 1 WHERE column_id = p_column_id AND draw_order > p_order;
+END //
+
+CREATE PROCEDURE create_tag(
+	IN p_project_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_color VARCHAR(32),
+	IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Tags
+		(id, project_id, name, color, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_project_id, p_name, p_color, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+DELIMITER ;
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS Users (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	login VARCHAR(255) NOT NULL UNIQUE,
+	password_hash VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS Sessions (
+	token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+	user_id VARCHAR(36) NOT NULL,
+	created_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES Users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS AccessTokens (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	user_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	scopes VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	last_used_at BIGINT NOT NULL DEFAULT 0,
+	revoked_at BIGINT NOT NULL DEFAULT 0,
+	FOREIGN KEY (user_id) REFERENCES Users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ProjectMembers (
+	project_id VARCHAR(36) NOT NULL,
+	user_id VARCHAR(36) NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	PRIMARY KEY (project_id, user_id),
+	FOREIGN KEY (project_id) REFERENCES Projects (id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES Users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS CardUpdateRecords (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	card_id VARCHAR(36) NOT NULL,
+	reverse_diff TEXT NOT NULL,
+	forward_diff TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	INDEX card_update_records_card_id (card_id),
+	FOREIGN KEY (card_id) REFERENCES Cards (id) ON DELETE CASCADE
+);
@@ -0,0 +1,25 @@
+-- Set based reads so a whole board loads in a fixed number of queries.
+
+DROP PROCEDURE IF EXISTS read_cards_by_project_id;
+DROP PROCEDURE IF EXISTS read_card_tags_by_project_id;
+
+DELIMITER //
+
+CREATE PROCEDURE read_cards_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description, c.draw_order, c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id ORDER BY c.column_id, c.draw_order;
+END //
+
+CREATE PROCEDURE read_card_tags_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT ct.card_id, ct.tag_id
+	FROM CardsTags ct
+	JOIN Cards c ON c.id = ct.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	JOIN Tags t ON t.id = ct.tag_id
+	WHERE pc.project_id = p_project_id ORDER BY t.created_at, t.id;
+END //
+
+DELIMITER ;
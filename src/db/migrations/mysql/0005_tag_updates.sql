@@ -0,0 +1,19 @@
+-- Tags can be read one by one and renamed or recolored in place.
+
+DROP PROCEDURE IF EXISTS read_tag_by_id;
+DROP PROCEDURE IF EXISTS update_tag;
+
+DELIMITER //
+
+CREATE PROCEDURE read_tag_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by
+	FROM Tags t WHERE t.id = p_id;
+END //
+
+CREATE PROCEDURE update_tag(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_color VARCHAR(32), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE Tags SET name = p_name, color = p_color, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,29 @@
+-- Cards move to an exact position within or across columns.
+
+DROP PROCEDURE IF EXISTS move_card;
+
+DELIMITER //
+
+CREATE PROCEDURE move_card(IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_position INT, IN p_author VARCHAR(36))
+BEGIN
+	DECLARE v_project_id VARCHAR(36);
+	DECLARE v_from_column_id VARCHAR(36);
+	DECLARE v_from_order INT;
+	DECLARE v_count INT;
+	DECLARE v_position INT;
+
+	SELECT project_id INTO v_project_id FROM ProjectColumns WHERE id = p_column_id;
+	-- Moves within a project queue up on its row, so their shifts never interleave.
+	SELECT id INTO v_project_id FROM Projects WHERE id = v_project_id FOR UPDATE;
+
+	SELECT column_id, draw_order INTO v_from_column_id, v_from_order FROM Cards WHERE id = p_id FOR UPDATE;
+	UPDATE Cards SET draw_order = draw_order - 1 WHERE column_id = v_from_column_id AND draw_order > v_from_order;
+
+	SELECT count(*) INTO v_count FROM Cards WHERE column_id = p_column_id AND id <> p_id;
+	SET v_position = IF(p_position <= 0 OR p_position > v_count, v_count + 1, p_position);
+	UPDATE Cards SET draw_order = draw_order + 1 WHERE column_id = p_column_id AND id <> p_id AND draw_order >= v_position;
+	UPDATE Cards SET column_id = p_column_id, draw_order = v_position, updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+DELIMITER ;
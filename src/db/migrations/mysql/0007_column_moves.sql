@@ -0,0 +1,31 @@
+-- Columns move to an exact position within their project.
+
+DROP PROCEDURE IF EXISTS move_column;
+
+DELIMITER //
+
+CREATE PROCEDURE move_column(IN p_id VARCHAR(36), IN p_position INT, IN p_author VARCHAR(36))
+BEGIN
+	DECLARE v_project_id VARCHAR(36);
+	DECLARE v_order INT;
+	DECLARE v_count INT;
+	DECLARE v_to INT;
+
+	SELECT project_id INTO v_project_id FROM ProjectColumns WHERE id = p_id;
+	-- Moves within a project queue up on its row, so their shifts never interleave.
+	SELECT id INTO v_project_id FROM Projects WHERE id = v_project_id FOR UPDATE;
+
+	SELECT draw_order INTO v_order FROM ProjectColumns WHERE id = p_id;
+	SELECT count(*) INTO v_count FROM ProjectColumns WHERE project_id = v_project_id;
+	SET v_to = IF(p_position <= 0 OR p_position > v_count, v_count, p_position);
+	IF v_to < v_order THEN
+		UPDATE ProjectColumns SET draw_order = draw_order + 1
+		WHERE project_id = v_project_id AND draw_order BETWEEN v_to AND v_order - 1;
+	ELSEIF v_to > v_order THEN
+		UPDATE ProjectColumns SET draw_order = draw_order - 1
+		WHERE project_id = v_project_id AND draw_order BETWEEN v_order + 1 AND v_to;
+	END IF;
+	UPDATE ProjectColumns SET draw_order = v_to, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+DELIMITER ;
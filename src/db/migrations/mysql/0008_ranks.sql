@@ -0,0 +1,144 @@
+-- Cards and columns are ordered by rank strings, placing one writes a single row instead of renumbering
+-- its siblings. Draw orders become zero padded ranks, which sort the same way, and positions are counted
+-- from the ranks when reading.
+
+ALTER TABLE ProjectColumns ADD COLUMN draw_rank VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '';
+UPDATE ProjectColumns SET draw_rank = LPAD(draw_order, 10, '0');
+ALTER TABLE ProjectColumns
+	DROP INDEX project_columns_project_id,
+	DROP COLUMN draw_order,
+	ADD INDEX project_columns_project_id (project_id, draw_rank);
+
+ALTER TABLE Cards ADD COLUMN draw_rank VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '';
+UPDATE Cards SET draw_rank = LPAD(draw_order, 10, '0');
+ALTER TABLE Cards
+	DROP INDEX cards_column_id,
+	DROP COLUMN draw_order,
+	ADD INDEX cards_column_id (column_id, draw_rank);
+
+DROP PROCEDURE IF EXISTS read_column_by_id;
+DROP PROCEDURE IF EXISTS read_columns_by_project_id;
+DROP PROCEDURE IF EXISTS read_card_by_id;
+DROP PROCEDURE IF EXISTS read_cards_by_column_id;
+DROP PROCEDURE IF EXISTS read_cards_by_project_id;
+DROP PROCEDURE IF EXISTS lock_project;
+DROP PROCEDURE IF EXISTS lock_column;
+DROP PROCEDURE IF EXISTS create_column;
+DROP PROCEDURE IF EXISTS update_column_data;
+DROP PROCEDURE IF EXISTS move_column;
+DROP PROCEDURE IF EXISTS pop_column_reorder;
+DROP PROCEDURE IF EXISTS create_card;
+DROP PROCEDURE IF EXISTS update_card;
+DROP PROCEDURE IF EXISTS move_card;
+DROP PROCEDURE IF EXISTS pop_card_reorder;
+
+DELIMITER //
+
+-- Single rows count the siblings ranked before them, equal ranks are ordered by id.
+CREATE PROCEDURE read_column_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT pc.id, pc.project_id, pc.name,
+		(SELECT count(*) FROM ProjectColumns o
+		WHERE o.project_id = pc.project_id AND (o.draw_rank, o.id) <= (pc.draw_rank, pc.id)) AS draw_order,
+		pc.created_at, pc.updated_at, pc.created_by, pc.updated_by
+	FROM ProjectColumns pc WHERE pc.id = p_id;
+END //
+
+CREATE PROCEDURE read_columns_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT id, project_id, name, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by
+	FROM ProjectColumns WHERE project_id = p_project_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_card_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c WHERE c.id = p_id;
+END //
+
+CREATE PROCEDURE read_cards_by_column_id(IN p_column_id VARCHAR(36))
+BEGIN
+	SELECT id, column_id, name, description, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by
+	FROM Cards WHERE column_id = p_column_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_cards_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		ROW_NUMBER() OVER (PARTITION BY c.column_id ORDER BY c.draw_rank, c.id) AS draw_order,
+		c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id ORDER BY c.column_id, c.draw_rank, c.id;
+END //
+
+-- Placements lock the parent row first, so two of them never pick the same rank.
+CREATE PROCEDURE lock_project(IN p_id VARCHAR(36))
+BEGIN
+	DECLARE v_id VARCHAR(36);
+	SELECT id INTO v_id FROM Projects WHERE id = p_id FOR UPDATE;
+END //
+
+CREATE PROCEDURE lock_column(IN p_id VARCHAR(36))
+BEGIN
+	DECLARE v_id VARCHAR(36);
+	SELECT id INTO v_id FROM ProjectColumns WHERE id = p_id FOR UPDATE;
+END //
+
+CREATE PROCEDURE create_column(
+	IN p_project_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_rank VARCHAR(64),
+	IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO ProjectColumns
+		(id, project_id, name, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_project_id, p_name, p_rank, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_column_data takes the rank of the new place, an empty rank keeps the column in place.
+CREATE PROCEDURE update_column_data(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36), IN p_rank VARCHAR(64))
+BEGIN
+	UPDATE ProjectColumns
+	SET name = p_name, draw_rank = COALESCE(NULLIF(p_rank, ''), draw_rank),
+		updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+CREATE PROCEDURE move_column(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE ProjectColumns SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE create_card(
+	IN p_column_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Cards
+		(id, column_id, name, description, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_column_id, p_name, p_description, p_rank, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_card takes the rank of the new place, an empty rank keeps the card in place.
+CREATE PROCEDURE update_card(
+	IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_author VARCHAR(36), IN p_rank VARCHAR(64))
+BEGIN
+	UPDATE Cards
+	SET column_id = p_column_id, name = p_name, description = p_description,
+		draw_rank = COALESCE(NULLIF(p_rank, ''), draw_rank), updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+CREATE PROCEDURE move_card(IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE Cards
+	SET column_id = p_column_id, draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,77 @@
+-- Cards get optional start and due dates, the index serves the overdue and due soon queries.
+
+ALTER TABLE Cards
+	ADD COLUMN start_at BIGINT NULL,
+	ADD COLUMN due_at BIGINT NULL,
+	ADD INDEX cards_due_at (due_at);
+
+DROP PROCEDURE IF EXISTS read_card_by_id;
+DROP PROCEDURE IF EXISTS read_cards_by_column_id;
+DROP PROCEDURE IF EXISTS read_cards_by_project_id;
+DROP PROCEDURE IF EXISTS read_due_cards_by_project_id;
+DROP PROCEDURE IF EXISTS create_card;
+DROP PROCEDURE IF EXISTS update_card;
+
+DELIMITER //
+
+CREATE PROCEDURE read_card_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c WHERE c.id = p_id;
+END //
+
+CREATE PROCEDURE read_cards_by_column_id(IN p_column_id VARCHAR(36))
+BEGIN
+	SELECT id, column_id, name, description, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		start_at, due_at, created_at, updated_at, created_by, updated_by
+	FROM Cards WHERE column_id = p_column_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_cards_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		ROW_NUMBER() OVER (PARTITION BY c.column_id ORDER BY c.draw_rank, c.id) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id ORDER BY c.column_id, c.draw_rank, c.id;
+END //
+
+-- read_due_cards_by_project_id returns the cards due from p_after up to p_before, soonest first.
+CREATE PROCEDURE read_due_cards_by_project_id(IN p_project_id VARCHAR(36), IN p_after BIGINT, IN p_before BIGINT)
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id AND c.due_at >= p_after AND c.due_at < p_before
+	ORDER BY c.due_at, c.id;
+END //
+
+CREATE PROCEDURE create_card(
+	IN p_column_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_rank VARCHAR(64), IN p_author VARCHAR(36), IN p_start_at BIGINT, IN p_due_at BIGINT)
+BEGIN
+	INSERT INTO Cards
+		(id, column_id, name, description, draw_rank, start_at, due_at, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_column_id, p_name, p_description, p_rank, p_start_at, p_due_at,
+		UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_card takes the rank of the new place, an empty rank keeps the card in place.
+CREATE PROCEDURE update_card(
+	IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_author VARCHAR(36), IN p_rank VARCHAR(64), IN p_start_at BIGINT, IN p_due_at BIGINT)
+BEGIN
+	UPDATE Cards
+	SET column_id = p_column_id, name = p_name, description = p_description,
+		draw_rank = COALESCE(NULLIF(p_rank, ''), draw_rank), start_at = p_start_at, due_at = p_due_at,
+		updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,58 @@
+-- Cards get assignees. An assignee has to be a member of the project of the card, so the membership
+-- foreign key drops the assignments of members leaving the project.
+
+CREATE TABLE IF NOT EXISTS CardAssignees (
+	card_id VARCHAR(36) NOT NULL,
+	project_id VARCHAR(36) NOT NULL,
+	user_id VARCHAR(36) NOT NULL,
+	created_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	PRIMARY KEY (card_id, user_id),
+	INDEX card_assignees_user_id (user_id),
+	INDEX card_assignees_project_id (project_id, user_id),
+	FOREIGN KEY (card_id) REFERENCES Cards (id) ON DELETE CASCADE,
+	FOREIGN KEY (project_id, user_id) REFERENCES ProjectMembers (project_id, user_id) ON DELETE CASCADE
+);
+
+DROP PROCEDURE IF EXISTS assign_card;
+DROP PROCEDURE IF EXISTS read_card_assignees_by_card_id;
+DROP PROCEDURE IF EXISTS read_card_assignees_by_project_id;
+DROP PROCEDURE IF EXISTS read_cards_by_assignee_id;
+
+DELIMITER //
+
+-- assign_card takes the project from the column of the card, nothing is inserted for a missing card.
+CREATE PROCEDURE assign_card(IN p_card_id VARCHAR(36), IN p_user_id VARCHAR(36), IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO CardAssignees
+		(card_id, project_id, user_id, created_at, created_by)
+	SELECT c.id, pc.project_id, p_user_id, UNIX_TIMESTAMP(), p_author
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE c.id = p_card_id;
+END //
+
+CREATE PROCEDURE read_card_assignees_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT card_id, user_id FROM CardAssignees WHERE card_id = p_card_id ORDER BY created_at, user_id;
+END //
+
+CREATE PROCEDURE read_card_assignees_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT card_id, user_id FROM CardAssignees WHERE project_id = p_project_id ORDER BY created_at, user_id;
+END //
+
+-- read_cards_by_assignee_id returns the cards assigned to the user in every project, in board order.
+CREATE PROCEDURE read_cards_by_assignee_id(IN p_user_id VARCHAR(36))
+BEGIN
+	SELECT ca.project_id, c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by
+	FROM CardAssignees ca
+	JOIN Cards c ON c.id = ca.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE ca.user_id = p_user_id
+	ORDER BY ca.project_id, pc.draw_rank, pc.id, c.draw_rank, c.id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,78 @@
+-- Cards get comments, replies point at a top level comment of the same card. Every edit keeps the body
+-- the comment had before it in CommentEdits.
+
+CREATE TABLE IF NOT EXISTS Comments (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	card_id VARCHAR(36) NOT NULL,
+	parent_id VARCHAR(36) NULL,
+	body TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	INDEX comments_card_id (card_id, created_at, id),
+	FOREIGN KEY (card_id) REFERENCES Cards (id) ON DELETE CASCADE,
+	FOREIGN KEY (parent_id) REFERENCES Comments (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS CommentEdits (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	comment_id VARCHAR(36) NOT NULL,
+	body TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	INDEX comment_edits_comment_id (comment_id),
+	FOREIGN KEY (comment_id) REFERENCES Comments (id) ON DELETE CASCADE
+);
+
+DROP PROCEDURE IF EXISTS read_comment_by_id;
+DROP PROCEDURE IF EXISTS read_comments_by_card_id;
+DROP PROCEDURE IF EXISTS read_comment_edits_by_comment_id;
+DROP PROCEDURE IF EXISTS create_comment;
+DROP PROCEDURE IF EXISTS update_comment;
+
+DELIMITER //
+
+CREATE PROCEDURE read_comment_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by
+	FROM Comments WHERE id = p_id;
+END //
+
+-- read_comments_by_card_id returns up to p_limit comments posted after the one at p_after_at and p_after_id,
+-- oldest first.
+CREATE PROCEDURE read_comments_by_card_id(
+	IN p_card_id VARCHAR(36), IN p_after_at BIGINT, IN p_after_id VARCHAR(36), IN p_limit INT)
+BEGIN
+	SELECT id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by
+	FROM Comments
+	WHERE card_id = p_card_id AND (created_at, id) > (p_after_at, p_after_id)
+	ORDER BY created_at, id
+	LIMIT p_limit;
+END //
+
+CREATE PROCEDURE read_comment_edits_by_comment_id(IN p_comment_id VARCHAR(36))
+BEGIN
+	SELECT id, comment_id, body, created_at, created_by
+	FROM CommentEdits WHERE comment_id = p_comment_id ORDER BY id;
+END //
+
+CREATE PROCEDURE create_comment(
+	IN p_card_id VARCHAR(36), IN p_id VARCHAR(36), IN p_parent_id VARCHAR(36), IN p_body TEXT, IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Comments
+		(id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_card_id, NULLIF(p_parent_id, ''), p_body, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+-- update_comment keeps the current body in CommentEdits before replacing it.
+CREATE PROCEDURE update_comment(IN p_id VARCHAR(36), IN p_body TEXT, IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO CommentEdits
+		(comment_id, body, created_at, created_by)
+	SELECT id, body, UNIX_TIMESTAMP(), p_author FROM Comments WHERE id = p_id;
+	UPDATE Comments SET body = p_body, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,166 @@
+-- Cards get checklists, named lists of items that are either done or not. Checklists are ranked within
+-- their card and items within their checklist, like cards within a column.
+
+CREATE TABLE IF NOT EXISTS Checklists (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	card_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	draw_rank VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	INDEX checklists_card_id (card_id, draw_rank),
+	FOREIGN KEY (card_id) REFERENCES Cards (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ChecklistItems (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	checklist_id VARCHAR(36) NOT NULL,
+	text TEXT NOT NULL,
+	done BOOLEAN NOT NULL DEFAULT FALSE,
+	draw_rank VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	created_by VARCHAR(36) NOT NULL,
+	updated_by VARCHAR(36) NOT NULL,
+	INDEX checklist_items_checklist_id (checklist_id, draw_rank),
+	FOREIGN KEY (checklist_id) REFERENCES Checklists (id) ON DELETE CASCADE
+);
+
+DROP PROCEDURE IF EXISTS read_checklist_by_id;
+DROP PROCEDURE IF EXISTS read_checklists_by_card_id;
+DROP PROCEDURE IF EXISTS read_checklist_item_by_id;
+DROP PROCEDURE IF EXISTS read_checklist_items_by_checklist_id;
+DROP PROCEDURE IF EXISTS read_checklist_items_by_card_id;
+DROP PROCEDURE IF EXISTS read_checklist_progress_by_card_id;
+DROP PROCEDURE IF EXISTS read_checklist_progress_by_project_id;
+DROP PROCEDURE IF EXISTS lock_card;
+DROP PROCEDURE IF EXISTS lock_checklist;
+DROP PROCEDURE IF EXISTS create_checklist;
+DROP PROCEDURE IF EXISTS update_checklist;
+DROP PROCEDURE IF EXISTS move_checklist;
+DROP PROCEDURE IF EXISTS create_checklist_item;
+DROP PROCEDURE IF EXISTS update_checklist_item;
+DROP PROCEDURE IF EXISTS toggle_checklist_item;
+DROP PROCEDURE IF EXISTS move_checklist_item;
+
+DELIMITER //
+
+CREATE PROCEDURE read_checklist_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT cl.id, cl.card_id, cl.name,
+		(SELECT count(*) FROM Checklists o
+		WHERE o.card_id = cl.card_id AND (o.draw_rank, o.id) <= (cl.draw_rank, cl.id)) AS draw_order,
+		cl.created_at, cl.updated_at, cl.created_by, cl.updated_by
+	FROM Checklists cl WHERE cl.id = p_id;
+END //
+
+CREATE PROCEDURE read_checklists_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT id, card_id, name, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by
+	FROM Checklists WHERE card_id = p_card_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_checklist_item_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT i.id, i.checklist_id, i.text, i.done,
+		(SELECT count(*) FROM ChecklistItems o
+		WHERE o.checklist_id = i.checklist_id AND (o.draw_rank, o.id) <= (i.draw_rank, i.id)) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by
+	FROM ChecklistItems i WHERE i.id = p_id;
+END //
+
+CREATE PROCEDURE read_checklist_items_by_checklist_id(IN p_checklist_id VARCHAR(36))
+BEGIN
+	SELECT id, checklist_id, text, done, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by
+	FROM ChecklistItems WHERE checklist_id = p_checklist_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_checklist_items_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT i.id, i.checklist_id, i.text, i.done,
+		ROW_NUMBER() OVER (PARTITION BY i.checklist_id ORDER BY i.draw_rank, i.id) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by
+	FROM ChecklistItems i JOIN Checklists cl ON cl.id = i.checklist_id
+	WHERE cl.card_id = p_card_id ORDER BY i.checklist_id, i.draw_rank, i.id;
+END //
+
+-- The progress reads count the done items and all items of every card having any.
+CREATE PROCEDURE read_checklist_progress_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT cl.card_id, SUM(i.done) AS done, count(*) AS total
+	FROM ChecklistItems i JOIN Checklists cl ON cl.id = i.checklist_id
+	WHERE cl.card_id = p_card_id GROUP BY cl.card_id;
+END //
+
+CREATE PROCEDURE read_checklist_progress_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT cl.card_id, SUM(i.done) AS done, count(*) AS total
+	FROM ChecklistItems i
+	JOIN Checklists cl ON cl.id = i.checklist_id
+	JOIN Cards c ON c.id = cl.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id GROUP BY cl.card_id;
+END //
+
+CREATE PROCEDURE lock_card(IN p_id VARCHAR(36))
+BEGIN
+	DECLARE v_id VARCHAR(36);
+	SELECT id INTO v_id FROM Cards WHERE id = p_id FOR UPDATE;
+END //
+
+CREATE PROCEDURE lock_checklist(IN p_id VARCHAR(36))
+BEGIN
+	DECLARE v_id VARCHAR(36);
+	SELECT id INTO v_id FROM Checklists WHERE id = p_id FOR UPDATE;
+END //
+
+CREATE PROCEDURE create_checklist(
+	IN p_card_id VARCHAR(36), IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO Checklists
+		(id, card_id, name, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_card_id, p_name, p_rank, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+CREATE PROCEDURE update_checklist(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE Checklists SET name = p_name, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE move_checklist(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE Checklists SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE create_checklist_item(
+	IN p_checklist_id VARCHAR(36), IN p_id VARCHAR(36), IN p_text TEXT, IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	INSERT INTO ChecklistItems
+		(id, checklist_id, text, done, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(p_id, p_checklist_id, p_text, FALSE, p_rank, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), p_author, p_author);
+END //
+
+CREATE PROCEDURE update_checklist_item(IN p_id VARCHAR(36), IN p_text TEXT, IN p_done BOOLEAN, IN p_author VARCHAR(36))
+BEGIN
+	UPDATE ChecklistItems
+	SET text = p_text, done = p_done, updated_at = UNIX_TIMESTAMP(), updated_by = p_author
+	WHERE id = p_id;
+END //
+
+CREATE PROCEDURE toggle_checklist_item(IN p_id VARCHAR(36), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE ChecklistItems SET done = NOT done, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+CREATE PROCEDURE move_checklist_item(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE ChecklistItems SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author WHERE id = p_id;
+END //
+
+DELIMITER ;
@@ -0,0 +1,9 @@
+-- Projects made before project members existed have none, so nobody could reach them. The author of
+-- such a project becomes its owner. Projects written by the old placeholder author have no user to
+-- pick, the claim-project command hands them to one.
+
+INSERT INTO ProjectMembers
+	(project_id, user_id, role, created_at, updated_at, created_by, updated_by)
+SELECT p.id, u.id, 'owner', UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), u.id, u.id
+FROM Projects p JOIN Users u ON u.id = p.created_by
+WHERE NOT EXISTS (SELECT 1 FROM ProjectMembers m WHERE m.project_id = p.id);
@@ -0,0 +1,175 @@
+-- Projects, columns, cards and tags get a version counter that every write to the row bumps. Updates name
+-- the version they expect and change nothing when the row moved on, which makes optimistic concurrency
+-- exact where second precise timestamps can't tell two writes apart.
+
+ALTER TABLE Projects ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE ProjectColumns ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE Cards ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE Tags ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+DROP PROCEDURE IF EXISTS read_project;
+DROP PROCEDURE IF EXISTS read_column_by_id;
+DROP PROCEDURE IF EXISTS read_columns_by_project_id;
+DROP PROCEDURE IF EXISTS read_card_by_id;
+DROP PROCEDURE IF EXISTS read_cards_by_column_id;
+DROP PROCEDURE IF EXISTS read_cards_by_project_id;
+DROP PROCEDURE IF EXISTS read_due_cards_by_project_id;
+DROP PROCEDURE IF EXISTS read_cards_by_assignee_id;
+DROP PROCEDURE IF EXISTS read_tags_by_card_id;
+DROP PROCEDURE IF EXISTS read_tags_by_project_id;
+DROP PROCEDURE IF EXISTS read_tag_by_id;
+DROP PROCEDURE IF EXISTS update_project_data;
+DROP PROCEDURE IF EXISTS update_column_data;
+DROP PROCEDURE IF EXISTS move_column;
+DROP PROCEDURE IF EXISTS update_card;
+DROP PROCEDURE IF EXISTS move_card;
+DROP PROCEDURE IF EXISTS update_tag;
+
+DELIMITER //
+
+CREATE PROCEDURE read_project(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, name, created_at, updated_at, created_by, updated_by, version FROM Projects WHERE id = p_id;
+END //
+
+CREATE PROCEDURE read_column_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT pc.id, pc.project_id, pc.name,
+		(SELECT count(*) FROM ProjectColumns o
+		WHERE o.project_id = pc.project_id AND (o.draw_rank, o.id) <= (pc.draw_rank, pc.id)) AS draw_order,
+		pc.created_at, pc.updated_at, pc.created_by, pc.updated_by, pc.version
+	FROM ProjectColumns pc WHERE pc.id = p_id;
+END //
+
+CREATE PROCEDURE read_columns_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT id, project_id, name, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by, version
+	FROM ProjectColumns WHERE project_id = p_project_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_card_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version
+	FROM Cards c WHERE c.id = p_id;
+END //
+
+CREATE PROCEDURE read_cards_by_column_id(IN p_column_id VARCHAR(36))
+BEGIN
+	SELECT id, column_id, name, description, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		start_at, due_at, created_at, updated_at, created_by, updated_by, version
+	FROM Cards WHERE column_id = p_column_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_cards_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		ROW_NUMBER() OVER (PARTITION BY c.column_id ORDER BY c.draw_rank, c.id) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id ORDER BY c.column_id, c.draw_rank, c.id;
+END //
+
+-- read_due_cards_by_project_id returns the cards due from p_after up to p_before, soonest first.
+CREATE PROCEDURE read_due_cards_by_project_id(IN p_project_id VARCHAR(36), IN p_after BIGINT, IN p_before BIGINT)
+BEGIN
+	SELECT c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE pc.project_id = p_project_id AND c.due_at >= p_after AND c.due_at < p_before
+	ORDER BY c.due_at, c.id;
+END //
+
+-- read_cards_by_assignee_id returns the cards assigned to the user in every project, in board order.
+CREATE PROCEDURE read_cards_by_assignee_id(IN p_user_id VARCHAR(36))
+BEGIN
+	SELECT ca.project_id, c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o
+		WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version
+	FROM CardAssignees ca
+	JOIN Cards c ON c.id = ca.card_id
+	JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE ca.user_id = p_user_id
+	ORDER BY ca.project_id, pc.draw_rank, pc.id, c.draw_rank, c.id;
+END //
+
+CREATE PROCEDURE read_tags_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by, t.version
+	FROM CardsTags ct JOIN Tags t ON t.id = ct.tag_id
+	WHERE ct.card_id = p_card_id ORDER BY t.created_at, t.id;
+END //
+
+CREATE PROCEDURE read_tags_by_project_id(IN p_project_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by, t.version
+	FROM Tags t WHERE t.project_id = p_project_id ORDER BY t.created_at, t.id;
+END //
+
+CREATE PROCEDURE read_tag_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by, t.version
+	FROM Tags t WHERE t.id = p_id;
+END //
+
+-- Versioned writes change nothing when p_version is not 0 and the row is at another version, the caller
+-- reads the affected row count to tell.
+CREATE PROCEDURE update_project_data(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE Projects SET name = p_name, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+-- update_column_data takes the rank of the new place, an empty rank keeps the column in place.
+CREATE PROCEDURE update_column_data(
+	IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36), IN p_rank VARCHAR(64), IN p_version BIGINT)
+BEGIN
+	UPDATE ProjectColumns
+	SET name = p_name, draw_rank = COALESCE(NULLIF(p_rank, ''), draw_rank),
+		updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE move_column(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE ProjectColumns
+	SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+-- update_card takes the rank of the new place, an empty rank keeps the card in place.
+CREATE PROCEDURE update_card(
+	IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_name VARCHAR(255), IN p_description TEXT,
+	IN p_author VARCHAR(36), IN p_rank VARCHAR(64), IN p_start_at BIGINT, IN p_due_at BIGINT, IN p_version BIGINT)
+BEGIN
+	UPDATE Cards
+	SET column_id = p_column_id, name = p_name, description = p_description,
+		draw_rank = COALESCE(NULLIF(p_rank, ''), draw_rank), start_at = p_start_at, due_at = p_due_at,
+		updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE move_card(
+	IN p_id VARCHAR(36), IN p_column_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE Cards
+	SET column_id = p_column_id, draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author,
+		version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE update_tag(
+	IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_color VARCHAR(32), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE Tags SET name = p_name, color = p_color, updated_at = UNIX_TIMESTAMP(), updated_by = p_author,
+		version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+DELIMITER ;
@@ -0,0 +1,42 @@
+-- Comments get a version counter like the board items, edits name the version they expect so two edits
+-- in the same second can't overwrite each other.
+
+ALTER TABLE Comments ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+DROP PROCEDURE IF EXISTS read_comment_by_id;
+DROP PROCEDURE IF EXISTS read_comments_by_card_id;
+DROP PROCEDURE IF EXISTS update_comment;
+
+DELIMITER //
+
+CREATE PROCEDURE read_comment_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by, version
+	FROM Comments WHERE id = p_id;
+END //
+
+-- read_comments_by_card_id returns up to p_limit comments posted after the one at p_after_at and p_after_id,
+-- oldest first.
+CREATE PROCEDURE read_comments_by_card_id(
+	IN p_card_id VARCHAR(36), IN p_after_at BIGINT, IN p_after_id VARCHAR(36), IN p_limit INT)
+BEGIN
+	SELECT id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by, version
+	FROM Comments
+	WHERE card_id = p_card_id AND (created_at, id) > (p_after_at, p_after_id)
+	ORDER BY created_at, id
+	LIMIT p_limit;
+END //
+
+-- update_comment keeps the current body in CommentEdits before replacing it, both writes change nothing
+-- when p_version is not 0 and the comment is at another version.
+CREATE PROCEDURE update_comment(IN p_id VARCHAR(36), IN p_body TEXT, IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	INSERT INTO CommentEdits
+		(comment_id, body, created_at, created_by)
+	SELECT id, body, UNIX_TIMESTAMP(), p_author FROM Comments
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+	UPDATE Comments SET body = p_body, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+DELIMITER ;
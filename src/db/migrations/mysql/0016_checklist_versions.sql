@@ -0,0 +1,96 @@
+-- Checklists and their items get a version counter like the board items. Renames and moves bump the version
+-- of the checklist, edits, toggles and moves the one of the item.
+
+ALTER TABLE Checklists ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE ChecklistItems ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+DROP PROCEDURE IF EXISTS read_checklist_by_id;
+DROP PROCEDURE IF EXISTS read_checklists_by_card_id;
+DROP PROCEDURE IF EXISTS read_checklist_item_by_id;
+DROP PROCEDURE IF EXISTS read_checklist_items_by_checklist_id;
+DROP PROCEDURE IF EXISTS read_checklist_items_by_card_id;
+DROP PROCEDURE IF EXISTS update_checklist;
+DROP PROCEDURE IF EXISTS move_checklist;
+DROP PROCEDURE IF EXISTS update_checklist_item;
+DROP PROCEDURE IF EXISTS toggle_checklist_item;
+DROP PROCEDURE IF EXISTS move_checklist_item;
+
+DELIMITER //
+
+CREATE PROCEDURE read_checklist_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT cl.id, cl.card_id, cl.name,
+		(SELECT count(*) FROM Checklists o
+		WHERE o.card_id = cl.card_id AND (o.draw_rank, o.id) <= (cl.draw_rank, cl.id)) AS draw_order,
+		cl.created_at, cl.updated_at, cl.created_by, cl.updated_by, cl.version
+	FROM Checklists cl WHERE cl.id = p_id;
+END //
+
+CREATE PROCEDURE read_checklists_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT id, card_id, name, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by, version
+	FROM Checklists WHERE card_id = p_card_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_checklist_item_by_id(IN p_id VARCHAR(36))
+BEGIN
+	SELECT i.id, i.checklist_id, i.text, i.done,
+		(SELECT count(*) FROM ChecklistItems o
+		WHERE o.checklist_id = i.checklist_id AND (o.draw_rank, o.id) <= (i.draw_rank, i.id)) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by, i.version
+	FROM ChecklistItems i WHERE i.id = p_id;
+END //
+
+CREATE PROCEDURE read_checklist_items_by_checklist_id(IN p_checklist_id VARCHAR(36))
+BEGIN
+	SELECT id, checklist_id, text, done, ROW_NUMBER() OVER (ORDER BY draw_rank, id) AS draw_order,
+		created_at, updated_at, created_by, updated_by, version
+	FROM ChecklistItems WHERE checklist_id = p_checklist_id ORDER BY draw_rank, id;
+END //
+
+CREATE PROCEDURE read_checklist_items_by_card_id(IN p_card_id VARCHAR(36))
+BEGIN
+	SELECT i.id, i.checklist_id, i.text, i.done,
+		ROW_NUMBER() OVER (PARTITION BY i.checklist_id ORDER BY i.draw_rank, i.id) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by, i.version
+	FROM ChecklistItems i JOIN Checklists cl ON cl.id = i.checklist_id
+	WHERE cl.card_id = p_card_id ORDER BY i.checklist_id, i.draw_rank, i.id;
+END //
+
+-- The versioned writes change nothing when p_version is not 0 and the row is at another version.
+CREATE PROCEDURE update_checklist(IN p_id VARCHAR(36), IN p_name VARCHAR(255), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE Checklists SET name = p_name, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE move_checklist(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE Checklists SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE update_checklist_item(
+	IN p_id VARCHAR(36), IN p_text TEXT, IN p_done BOOLEAN, IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE ChecklistItems
+	SET text = p_text, done = p_done, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+CREATE PROCEDURE toggle_checklist_item(IN p_id VARCHAR(36), IN p_author VARCHAR(36))
+BEGIN
+	UPDATE ChecklistItems
+	SET done = NOT done, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id;
+END //
+
+CREATE PROCEDURE move_checklist_item(IN p_id VARCHAR(36), IN p_rank VARCHAR(64), IN p_author VARCHAR(36), IN p_version BIGINT)
+BEGIN
+	UPDATE ChecklistItems
+	SET draw_rank = p_rank, updated_at = UNIX_TIMESTAMP(), updated_by = p_author, version = version + 1
+	WHERE id = p_id AND (p_version = 0 OR version = p_version);
+END //
+
+DELIMITER ;
@@ -0,0 +1,99 @@
+-- IF NOT EXISTS lets databases created before migrations were versioned adopt this version.
+
+CREATE TABLE IF NOT EXISTS Users (
+	id TEXT PRIMARY KEY,
+	login TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS Sessions (
+	token_hash TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES Users (id) ON DELETE CASCADE,
+	created_at INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS AccessTokens (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES Users (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	last_used_at INTEGER NOT NULL DEFAULT 0,
+	revoked_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS Projects (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ProjectMembers (
+	project_id TEXT NOT NULL REFERENCES Projects (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES Users (id) ON DELETE CASCADE,
+	role TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL,
+	PRIMARY KEY (project_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS ProjectColumns (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL REFERENCES Projects (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	draw_order INTEGER NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS project_columns_project_id ON ProjectColumns (project_id, draw_order);
+
+CREATE TABLE IF NOT EXISTS Cards (
+	id TEXT PRIMARY KEY,
+	column_id TEXT NOT NULL REFERENCES ProjectColumns (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	draw_order INTEGER NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cards_column_id ON Cards (column_id, draw_order);
+
+CREATE TABLE IF NOT EXISTS Tags (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL REFERENCES Projects (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS CardsTags (
+	card_id TEXT NOT NULL REFERENCES Cards (id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES Tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (card_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS CardUpdateRecords (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	card_id TEXT NOT NULL REFERENCES Cards (id) ON DELETE CASCADE,
+	reverse_diff TEXT NOT NULL,
+	forward_diff TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS card_update_records_card_id ON CardUpdateRecords (card_id);
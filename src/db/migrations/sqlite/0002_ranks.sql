@@ -0,0 +1,14 @@
+-- Cards and columns are ordered by rank strings, placing one writes a single row instead of renumbering
+-- its siblings. Draw orders become zero padded ranks, which sort the same way.
+
+ALTER TABLE ProjectColumns ADD COLUMN draw_rank TEXT NOT NULL DEFAULT '';
+UPDATE ProjectColumns SET draw_rank = substr('0000000000' || draw_order, -10);
+DROP INDEX IF EXISTS project_columns_project_id;
+ALTER TABLE ProjectColumns DROP COLUMN draw_order;
+CREATE INDEX project_columns_project_id ON ProjectColumns (project_id, draw_rank);
+
+ALTER TABLE Cards ADD COLUMN draw_rank TEXT NOT NULL DEFAULT '';
+UPDATE Cards SET draw_rank = substr('0000000000' || draw_order, -10);
+DROP INDEX IF EXISTS cards_column_id;
+ALTER TABLE Cards DROP COLUMN draw_order;
+CREATE INDEX cards_column_id ON Cards (column_id, draw_rank);
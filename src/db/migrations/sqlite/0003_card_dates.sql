@@ -0,0 +1,5 @@
+-- Cards get optional start and due dates, the index serves the overdue and due soon queries.
+
+ALTER TABLE Cards ADD COLUMN start_at INTEGER;
+ALTER TABLE Cards ADD COLUMN due_at INTEGER;
+CREATE INDEX cards_due_at ON Cards (due_at);
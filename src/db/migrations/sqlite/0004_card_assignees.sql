@@ -0,0 +1,14 @@
+-- Cards get assignees. An assignee has to be a member of the project of the card, so the membership
+-- foreign key drops the assignments of members leaving the project.
+
+CREATE TABLE CardAssignees (
+	card_id TEXT NOT NULL REFERENCES Cards (id) ON DELETE CASCADE,
+	project_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	PRIMARY KEY (card_id, user_id),
+	FOREIGN KEY (project_id, user_id) REFERENCES ProjectMembers (project_id, user_id) ON DELETE CASCADE
+);
+CREATE INDEX card_assignees_user_id ON CardAssignees (user_id);
+CREATE INDEX card_assignees_project_id ON CardAssignees (project_id, user_id);
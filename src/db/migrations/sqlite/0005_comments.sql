@@ -0,0 +1,24 @@
+-- Cards get comments, replies point at a top level comment of the same card. Every edit keeps the body
+-- the comment had before it in CommentEdits.
+
+CREATE TABLE Comments (
+	id TEXT PRIMARY KEY,
+	card_id TEXT NOT NULL REFERENCES Cards (id) ON DELETE CASCADE,
+	parent_id TEXT REFERENCES Comments (id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+CREATE INDEX comments_card_id ON Comments (card_id, created_at, id);
+CREATE INDEX comments_parent_id ON Comments (parent_id);
+
+CREATE TABLE CommentEdits (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	comment_id TEXT NOT NULL REFERENCES Comments (id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL
+);
+CREATE INDEX comment_edits_comment_id ON CommentEdits (comment_id);
@@ -0,0 +1,27 @@
+-- Cards get checklists, named lists of items that are either done or not. Checklists are ranked within
+-- their card and items within their checklist, like cards within a column.
+
+CREATE TABLE Checklists (
+	id TEXT PRIMARY KEY,
+	card_id TEXT NOT NULL REFERENCES Cards (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	draw_rank TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+CREATE INDEX checklists_card_id ON Checklists (card_id, draw_rank);
+
+CREATE TABLE ChecklistItems (
+	id TEXT PRIMARY KEY,
+	checklist_id TEXT NOT NULL REFERENCES Checklists (id) ON DELETE CASCADE,
+	text TEXT NOT NULL,
+	done INTEGER NOT NULL DEFAULT 0,
+	draw_rank TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	created_by TEXT NOT NULL,
+	updated_by TEXT NOT NULL
+);
+CREATE INDEX checklist_items_checklist_id ON ChecklistItems (checklist_id, draw_rank);
@@ -0,0 +1,8 @@
+-- Projects, columns, cards and tags get a version counter that every write to the row bumps. Updates name
+-- the version they expect and change nothing when the row moved on, which makes optimistic concurrency
+-- exact where second precise timestamps can't tell two writes apart.
+
+ALTER TABLE Projects ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE ProjectColumns ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE Cards ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE Tags ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
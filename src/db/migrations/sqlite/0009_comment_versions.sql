@@ -0,0 +1,4 @@
+-- Comments get a version counter like the board items, edits name the version they expect so two edits
+-- in the same second can't overwrite each other.
+
+ALTER TABLE Comments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
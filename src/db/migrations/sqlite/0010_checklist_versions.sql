@@ -0,0 +1,5 @@
+-- Checklists and their items get a version counter like the board items. Renames and moves bump the version
+-- of the checklist, edits, toggles and moves the one of the item.
+
+ALTER TABLE Checklists ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE ChecklistItems ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
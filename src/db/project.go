@@ -1,97 +1,111 @@
 package db_driver
 
-import (
-	"context"
-	"database/sql"
-	"types"
-)
+import "types"
 
-func CreateProject(db *sql.DB, id string, projectData *types.KanbanJson) error {
-	transaction, err := db.BeginTx(context.Background(), nil)
+// CreateProjectTX stores the project with its tags and columns and makes author its owner,
+// agent has to wrap a transaction.
+func CreateProjectTX(agent *Agent, id string, projectData *types.KanbanJson, author string) error {
+	res, err := agent.Exec(`CALL create_project(?, ?, ?);`, id, projectData.Name, author)
 	if err != nil {
 		return err
 	}
-	agent := CreateAgentTX(transaction)
-	stmt, err := agent.Prepare(`CALL create_project(?, ?, ?);`)
+	rows, err := res.RowsAffected()
 	if err != nil {
-		transaction.Rollback()
 		return err
 	}
-	defer stmt.Close()
-	res, err := stmt.Exec(id, projectData.Name, "placeholder")
+	if rows == 0 {
+		return NoEffect{}
+	}
+	err = AddProjectMember(agent, id, author, types.RoleOwner, author)
 	if err != nil {
-		transaction.Rollback()
 		return err
 	}
-	rows, err := res.RowsAffected()
+	_, err = CreateTags(agent, id, &projectData.Tags, author)
 	if err != nil {
-		transaction.Rollback()
 		return err
 	}
-	if rows == 0 {
-		transaction.Rollback()
-		return NoEffect{}
+	_, err = CreateColumns(agent, id, projectData.Columns, author)
+	if err != nil {
+		return err
 	}
-	_, err = CreateTags(agent, id, &projectData.Tags)
+	return nil
+}
+
+// UpdateProjectData renames the project, a version other than 0 is the one the project is expected at.
+func UpdateProjectData(agent *Agent, id string, name string, version int, author string) error {
+	if version != 0 {
+		_, err := ReadProject(agent, id)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := agent.Exec("CALL update_project_data(?, ?, ?, ?)", id, name, author, version)
 	if err != nil {
-		transaction.Rollback()
 		return err
 	}
-	_, err = CreateColumns(agent, id, projectData.Columns)
+	return checkVersioned(res, "project", id, version)
+}
+
+func DeleteProject(agent *Agent, id string) error {
+	res, err := agent.Exec("DELETE FROM Projects WHERE id = ?", id)
 	if err != nil {
-		transaction.Rollback()
 		return err
 	}
-	err = transaction.Commit()
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return NoEffect{}
+	}
 	return nil
 }
 
-func GetProject(db *sql.DB, id string) (*types.KanbanJson, error) {
-	var output types.KanbanJson
-	project, err := ReadProject(db, id)
+// GetProject loads the whole board with a fixed number of queries and puts it together in memory.
+func GetProject(agent *Agent, id string) (*types.KanbanJson, error) {
+	project, err := ReadProject(agent, id)
 	if err != nil {
 		return nil, err
 	}
-	output = *project.Json()
-	projectTags, err := GetTagsByProject(db, id)
+	output := *project.Json()
+	projectTags, err := GetTagsByProject(agent, id)
 	if err != nil {
 		return nil, err
 	}
 	for _, tag := range projectTags {
-		outputTag := tag.Json()
-		output.Tags = append(output.Tags, *outputTag)
+		output.Tags = append(output.Tags, *tag.Json())
 	}
-	columns, err := ReadColumns(CreateAgentDB(db), id)
+	columns, err := ReadColumns(agent, id)
 	if err != nil {
 		return nil, err
 	}
+	cards, err := GetCardsByProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	tagIds, err := GetCardTagIdsByProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	assigneeIds, err := GetCardAssigneeIdsByProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := GetChecklistProgressByProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	cardsByColumn := make(map[string][]types.CardJson)
+	for _, card := range cards {
+		outputCard := card.Json()
+		outputCard.TagIds = append(outputCard.TagIds, tagIds[card.Id]...)
+		outputCard.AssigneeIds = append(outputCard.AssigneeIds, assigneeIds[card.Id]...)
+		outputCard.ChecklistProgress = progress[card.Id]
+		cardsByColumn[card.ColumnId] = append(cardsByColumn[card.ColumnId], *outputCard)
+	}
 	for _, col := range columns {
 		outputCol := col.Json()
-		var outputCards []types.CardJson
-		cards, err := GetCardsByColumnId(db, col.Id)
-
-		if err != nil {
-			return nil, err
-		}
-		for _, card := range cards {
-			outputCard := card.Json()
-
-			tags, err := GetTagsByCard(db, card.Id)
-			if err != nil {
-				return nil, err
-			}
-			for _, tag := range tags {
-				if tag.Id != "" {
-					outputCard.TagIds = append(outputCard.TagIds, tag.Id)
-				}
-			}
-
-			outputCards = append(outputCards, *outputCard)
-		}
-		outputCol.Cards = outputCards
+		outputCol.Cards = cardsByColumn[col.Id]
 		output.Columns = append(output.Columns, *outputCol)
 	}
 	return &output, nil
@@ -0,0 +1,207 @@
+package db_driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"types"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// preparedQueries counts the statements prepared on countingSQLite connections. Every storage read
+// prepares its statement, so the count is the number of queries.
+var preparedQueries atomic.Int64
+
+var registerCountingDriver sync.Once
+
+type countingDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+}
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) {
+	preparedQueries.Add(1)
+	return c.Conn.Prepare(query)
+}
+
+// countingSQLite opens a migrated SQLite storage like GetSQLiteDb does, with its queries counted.
+func countingSQLite(b *testing.B) *SQLStorage {
+	registerCountingDriver.Do(func() {
+		sql.Register("sqlite3_counting", &countingDriver{})
+	})
+	db, err := sql.Open("sqlite3_counting", filepath.Join(b.TempDir(), "kanban.db")+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		b.Fatalf("can't open sqlite: %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	_, err = Migrate(db, DialectSQLite, false)
+	if err != nil {
+		b.Fatalf("can't migrate sqlite: %s", err)
+	}
+	storage := NewSQLiteStorage(db)
+	b.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+// createBenchmarkBoard stores a board of 6 columns holding 50 cards each and 5 tags, every card
+// is linked to two of the tags.
+func createBenchmarkBoard(b *testing.B, s Storage) string {
+	user, err := s.CreateUser("bench", "hash")
+	if err != nil {
+		b.Fatalf("can't create user: %s", err)
+	}
+	project := types.KanbanJson{Name: "board"}
+	for col := 0; col < 6; col++ {
+		column := types.ColumnJson{Name: fmt.Sprintf("column %d", col)}
+		for card := 0; card < 50; card++ {
+			column.Cards = append(column.Cards, types.CardJson{Name: fmt.Sprintf("card %d", card), Description: "text"})
+		}
+		project.Columns = append(project.Columns, column)
+	}
+	for tag := 0; tag < 5; tag++ {
+		project.Tags = append(project.Tags, types.TagJson{Name: fmt.Sprintf("tag %d", tag), Color: "red"})
+	}
+	id := "project-" + user.Id
+	err = s.CreateProject(id, &project, user.Id)
+	if err != nil {
+		b.Fatalf("can't create project: %s", err)
+	}
+	stored, err := s.GetProject(id)
+	if err != nil {
+		b.Fatalf("can't read project: %s", err)
+	}
+	idx := 0
+	for _, column := range stored.Columns {
+		for _, card := range column.Cards {
+			for _, tag := range []int{idx % 5, (idx + 1) % 5} {
+				err = s.CreateCardTags(card.Id, stored.Tags[tag].Id)
+				if err != nil {
+					b.Fatalf("can't link tag: %s", err)
+				}
+			}
+			idx++
+		}
+	}
+	return id
+}
+
+// getProjectPerRow reads the board the way GetProject did before it batched its queries, with the cards
+// read per column and their tags, assignees and checklist progress per card.
+func getProjectPerRow(agent *Agent, id string) (*types.KanbanJson, error) {
+	project, err := ReadProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	output := *project.Json()
+	projectTags, err := GetTagsByProject(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range projectTags {
+		output.Tags = append(output.Tags, *tag.Json())
+	}
+	columns, err := ReadColumns(agent, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range columns {
+		outputCol := col.Json()
+		cards, err := GetCardsByColumnId(agent, col.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range cards {
+			outputCard := card.Json()
+			tags, err := GetTagsByCard(agent, card.Id)
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range tags {
+				outputCard.TagIds = append(outputCard.TagIds, tag.Id)
+			}
+			assigneeIds, err := GetCardAssigneeIds(agent, card.Id)
+			if err != nil {
+				return nil, err
+			}
+			outputCard.AssigneeIds = append(outputCard.AssigneeIds, assigneeIds...)
+			progress, err := GetChecklistProgress(agent, card.Id)
+			if err != nil {
+				return nil, err
+			}
+			outputCard.ChecklistProgress = *progress
+			outputCol.Cards = append(outputCol.Cards, *outputCard)
+		}
+		output.Columns = append(output.Columns, *outputCol)
+	}
+	return &output, nil
+}
+
+func countBoard(board *types.KanbanJson) (int, int) {
+	cards, links := 0, 0
+	for _, column := range board.Columns {
+		cards += len(column.Cards)
+		for _, card := range column.Cards {
+			links += len(card.TagIds)
+		}
+	}
+	return cards, links
+}
+
+// benchmarkRead reads the board b.N times, queries/op is reported when the storage counts its queries.
+func benchmarkRead(b *testing.B, read func() (*types.KanbanJson, error), counted bool) {
+	board, err := read()
+	if err != nil {
+		b.Fatalf("can't read board: %s", err)
+	}
+	if cards, links := countBoard(board); cards != 300 || links != 600 {
+		b.Fatalf("board has %d cards and %d tag links, want 300 and 600", cards, links)
+	}
+	preparedQueries.Store(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := read()
+		if err != nil {
+			b.Fatalf("can't read board: %s", err)
+		}
+	}
+	b.StopTimer()
+	if counted {
+		b.ReportMetric(float64(preparedQueries.Load())/float64(b.N), "queries/op")
+	}
+}
+
+// BenchmarkGetProject reads a board of 300 cards with GetProject and with the per column and per card
+// reads it replaced.
+func BenchmarkGetProject(b *testing.B) {
+	b.Run("sqlite", func(b *testing.B) {
+		s := countingSQLite(b)
+		id := createBenchmarkBoard(b, s)
+		benchmarkRead(b, func() (*types.KanbanJson, error) { return s.GetProject(id) }, true)
+	})
+	b.Run("sqlite per row", func(b *testing.B) {
+		s := countingSQLite(b)
+		id := createBenchmarkBoard(b, s)
+		benchmarkRead(b, func() (*types.KanbanJson, error) { return getProjectPerRow(s.agent, id) }, true)
+	})
+	b.Run("memory", func(b *testing.B) {
+		s := NewMemoryStorage()
+		id := createBenchmarkBoard(b, s)
+		benchmarkRead(b, func() (*types.KanbanJson, error) { return s.GetProject(id) }, false)
+	})
+}
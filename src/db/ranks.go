@@ -0,0 +1,191 @@
+package db_driver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cards, columns, checklists and their items are ordered by rank strings instead of dense draw orders.
+// Placing an item picks a rank between its new neighbours, so only the row of that item is written. Ranks get
+// longer when items keep landing in the same gap, RebalanceRanks spreads the ranks under such parents out again.
+// Positions are counted from the ranks when reading, equal ranks are ordered by id.
+
+// rankDigits are the digits of a rank in byte order, so ranks compare as plain strings in every database.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const (
+	// rankRebalanceLength is the rank length past which RebalanceRanks spreads the siblings out.
+	rankRebalanceLength = 12
+	// rankMaxLength keeps ranks within the draw_rank column, placements that would go past it
+	// spread the siblings out right away.
+	rankMaxLength = 48
+)
+
+// rankSiblings are items ranked against each other, like cards within a column and columns within a project.
+type rankSiblings struct {
+	table  string
+	parent string
+	// lock is the procedure locking the parent row, so placements under one parent never pick the same rank.
+	lock string
+}
+
+var (
+	cardRanks          = rankSiblings{"Cards", "column_id", "lock_column"}
+	columnRanks        = rankSiblings{"ProjectColumns", "project_id", "lock_project"}
+	checklistRanks     = rankSiblings{"Checklists", "card_id", "lock_card"}
+	checklistItemRanks = rankSiblings{"ChecklistItems", "checklist_id", "lock_checklist"}
+)
+
+// rankBetween returns a rank sorting after prev and before next, an empty prev or next leaves that side open.
+// There is no such rank when prev doesn't sort before next or next is prev followed by zeros.
+func rankBetween(prev string, next string) (string, bool) {
+	if next != "" && prev >= next {
+		return "", false
+	}
+	var rank strings.Builder
+	open := next == ""
+	for idx := 0; ; idx++ {
+		low := 0
+		if idx < len(prev) {
+			low = strings.IndexByte(rankDigits, prev[idx])
+		}
+		high := len(rankDigits)
+		if !open {
+			if idx >= len(next) {
+				return "", false
+			}
+			high = strings.IndexByte(rankDigits, next[idx])
+		}
+		if low < 0 || high < 0 {
+			return "", false
+		}
+		if high-low > 1 {
+			rank.WriteByte(rankDigits[(low+high)/2])
+			return rank.String(), true
+		}
+		rank.WriteByte(rankDigits[low])
+		// Once the rank sorts before next, only prev bounds the digits that follow.
+		open = open || high > low
+	}
+}
+
+// spreadRanks returns count ranks of the same length, evenly spaced with room for many inserts in every gap.
+func spreadRanks(count int) []string {
+	base := len(rankDigits)
+	width, space := 1, base
+	for space < (count+1)*base*base {
+		width++
+		space *= base
+	}
+	step := space / (count + 1)
+	ranks := make([]string, count)
+	for idx := range ranks {
+		digits := make([]byte, width)
+		value := (idx + 1) * step
+		for pos := width - 1; pos >= 0; pos-- {
+			digits[pos] = rankDigits[value%base]
+			value /= base
+		}
+		ranks[idx] = string(digits)
+	}
+	return ranks
+}
+
+func (s rankSiblings) lockParent(agent *Agent, parentId string) error {
+	_, err := agent.Exec(fmt.Sprintf("CALL %s(?);", s.lock), parentId)
+	return err
+}
+
+// ids returns the ids of the siblings under parentId with their ranks, in order and without except.
+func (s rankSiblings) ids(agent *Agent, parentId string, except string) ([]string, []string, error) {
+	rows, err := agent.Query(fmt.Sprintf(`
+	SELECT id, draw_rank FROM %s WHERE %s = ? AND id <> ? ORDER BY draw_rank, id;`, s.table, s.parent), parentId, except)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var ids, ranks []string
+	for rows.Next() {
+		var id, rank string
+		err = rows.Scan(&id, &rank)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		ranks = append(ranks, rank)
+	}
+	return ids, ranks, rows.Err()
+}
+
+// place locks the parent and returns the rank putting an item at position among its siblings, along with
+// the position it ends up at. Positions start at 1, 0 or a position past the last sibling appends the item.
+// except is the item being moved, so it is not counted as its own neighbour. agent has to wrap a transaction.
+func (s rankSiblings) place(agent *Agent, parentId string, except string, position int) (string, int, error) {
+	err := s.lockParent(agent, parentId)
+	if err != nil {
+		return "", 0, err
+	}
+	for spread := false; ; spread = true {
+		_, ranks, err := s.ids(agent, parentId, except)
+		if err != nil {
+			return "", 0, err
+		}
+		if position <= 0 || position > len(ranks) {
+			position = len(ranks) + 1
+		}
+		prev, next := "", ""
+		if position > 1 {
+			prev = ranks[position-2]
+		}
+		if position <= len(ranks) {
+			next = ranks[position-1]
+		}
+		rank, ok := rankBetween(prev, next)
+		if ok && len(rank) <= rankMaxLength {
+			return rank, position, nil
+		}
+		if spread {
+			return "", 0, fmt.Errorf("no rank fits between %q and %q under %s %s", prev, next, s.parent, parentId)
+		}
+		err = s.rebalance(agent, parentId)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+}
+
+// rebalance spreads the ranks of the siblings under parentId out evenly and keeps their order,
+// the parent is expected to be locked.
+func (s rankSiblings) rebalance(agent *Agent, parentId string) error {
+	ids, _, err := s.ids(agent, parentId, "")
+	if err != nil {
+		return err
+	}
+	for idx, rank := range spreadRanks(len(ids)) {
+		_, err = agent.Exec(fmt.Sprintf(`UPDATE %s SET draw_rank = ? WHERE id = ?;`, s.table), rank, ids[idx])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crowded returns the parents having ranks longer than rankRebalanceLength.
+func (s rankSiblings) crowded(agent *Agent) ([]string, error) {
+	rows, err := agent.Query(fmt.Sprintf(`
+	SELECT DISTINCT %s FROM %s WHERE length(draw_rank) > ?;`, s.parent, s.table), rankRebalanceLength)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var parents []string
+	for rows.Next() {
+		var parentId string
+		err = rows.Scan(&parentId)
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, parentId)
+	}
+	return parents, rows.Err()
+}
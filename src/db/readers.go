@@ -8,12 +8,17 @@ import (
 )
 
 func readOneRow(agent *Agent, id string, query string) ([]string, []sql.RawBytes, error) {
+	return readOneRowArgs(agent, fmt.Sprintf("item with id %s", id), query, id)
+}
+
+// readOneRowArgs is readOneRow for queries with several parameters, thing is used to describe a missing row.
+func readOneRowArgs(agent *Agent, thing string, query string, args ...any) ([]string, []sql.RawBytes, error) {
 	stmt, err := agent.Prepare(query)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.Query(id)
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -28,7 +33,7 @@ func readOneRow(agent *Agent, id string, query string) ([]string, []sql.RawBytes
 		scanArgs[i] = &values[i]
 	}
 	if !rows.Next() {
-		return nil, nil, NotFoundError{fmt.Sprintf("item with id %s", id), &query}
+		return nil, nil, NotFoundError{thing, &query}
 	}
 	err = rows.Scan(scanArgs...)
 	if err != nil {
@@ -38,13 +43,18 @@ func readOneRow(agent *Agent, id string, query string) ([]string, []sql.RawBytes
 }
 
 func readMultiRow(agent *Agent, id string, query string) ([]string, [][]sql.RawBytes, error) {
+	return readMultiRowArgs(agent, fmt.Sprintf(" id: %s", id), query, id)
+}
+
+// readMultiRowArgs is readMultiRow for queries with several parameters, thing is used to describe a missing result.
+func readMultiRowArgs(agent *Agent, thing string, query string, args ...any) ([]string, [][]sql.RawBytes, error) {
 	var err error
 	stmt, err := agent.Prepare(query)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.Query(id)
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -71,7 +81,7 @@ func readMultiRow(agent *Agent, id string, query string) ([]string, [][]sql.RawB
 	}
 
 	if len(columns) == 0 {
-		return nil, nil, fmt.Errorf(" id: %s was not found", id)
+		return nil, nil, fmt.Errorf("%s was not found", thing)
 	}
 	return columns, output, nil
 }
@@ -98,6 +108,13 @@ func GetCard(agent *Agent, id string) (*types.Card, error) {
 				return nil, err
 			}
 			card.Order = val
+		case "start_at":
+			card.StartAt, err = readOptionalInt(col)
+		case "due_at":
+			card.DueAt, err = readOptionalInt(col)
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -109,17 +126,67 @@ func GetCard(agent *Agent, id string) (*types.Card, error) {
 	card.UpdatedAt = meta.Updated_at
 	card.CreatedBy = meta.Created_by
 	card.UpdatedBy = meta.Updated_by
+	card.Version = meta.Version
 	return &card, nil
 }
 
-func GetCardsByColumnId(db *sql.DB, id string) ([]types.Card, error) {
-	var outputCards []types.Card
-	columns, values, err := readMultiRow(CreateAgentDB(db), id, `CALL read_cards_by_column_id(?);`)
+func GetCardsByColumnId(agent *Agent, id string) ([]types.Card, error) {
+	columns, values, err := readMultiRow(agent, id, `CALL read_cards_by_column_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readCards(columns, values)
+}
 
+// GetCardsByProject reads the cards of every column in the project at once, ordered by column and draw order.
+func GetCardsByProject(agent *Agent, projectId string) ([]types.Card, error) {
+	columns, values, err := readMultiRow(agent, projectId, `CALL read_cards_by_project_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	return readCards(columns, values)
+}
+
+// GetDueCards reads the cards of the project due from after up to before, soonest first.
+func GetDueCards(agent *Agent, projectId string, after int, before int) ([]types.CardJson, error) {
+	columns, values, err := readMultiRowArgs(agent, fmt.Sprintf("project %s", projectId),
+		`CALL read_due_cards_by_project_id(?, ?, ?);`, projectId, after, before)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := readCards(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	tagIds, err := GetCardTagIdsByProject(agent, projectId)
+	if err != nil {
+		return nil, err
+	}
+	assigneeIds, err := GetCardAssigneeIdsByProject(agent, projectId)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := GetChecklistProgressByProject(agent, projectId)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]types.CardJson, len(cards))
+	for idx, card := range cards {
+		output[idx] = *card.Json()
+		output[idx].TagIds = append(output[idx].TagIds, tagIds[card.Id]...)
+		output[idx].AssigneeIds = append(output[idx].AssigneeIds, assigneeIds[card.Id]...)
+		output[idx].ChecklistProgress = progress[card.Id]
+	}
+	return output, nil
+}
+
+func readCards(columns []string, values [][]sql.RawBytes) ([]types.Card, error) {
+	var outputCards []types.Card
 	for i := range values {
 		row := values[i]
 		rowLength := len(row)
 		var newCard types.Card
+		var err error
 		if row == nil {
 			continue
 		}
@@ -140,6 +207,13 @@ func GetCardsByColumnId(db *sql.DB, id string) ([]types.Card, error) {
 				newCard.Name = string(col)
 			case "description":
 				newCard.Description = string(col)
+			case "start_at":
+				newCard.StartAt, err = readOptionalInt(col)
+			case "due_at":
+				newCard.DueAt, err = readOptionalInt(col)
+			}
+			if err != nil {
+				return nil, err
 			}
 		}
 
@@ -151,13 +225,35 @@ func GetCardsByColumnId(db *sql.DB, id string) ([]types.Card, error) {
 		newCard.UpdatedAt = meta.Updated_at
 		newCard.CreatedBy = meta.Created_by
 		newCard.UpdatedBy = meta.Updated_by
+		newCard.Version = meta.Version
 		outputCards = append(outputCards, newCard)
 	}
-	return outputCards, err
+	return outputCards, nil
 }
 
-func ReadProject(db *sql.DB, id string) (*types.Kanban, error) {
-	agent := CreateAgentDB(db)
+// GetCardTagIdsByProject maps the cards of the project to their tag ids, in the order GetTagsByCard returns them.
+func GetCardTagIdsByProject(agent *Agent, projectId string) (map[string][]string, error) {
+	columns, values, err := readMultiRow(agent, projectId, `CALL read_card_tags_by_project_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	tagIds := make(map[string][]string)
+	for _, row := range values {
+		var cardId, tagId string
+		for j, col := range row {
+			switch columns[j] {
+			case "card_id":
+				cardId = string(col)
+			case "tag_id":
+				tagId = string(col)
+			}
+		}
+		tagIds[cardId] = append(tagIds[cardId], tagId)
+	}
+	return tagIds, nil
+}
+
+func ReadProject(agent *Agent, id string) (*types.Kanban, error) {
 	var project types.Kanban
 	columns, values, err := readOneRow(agent, id, "CALL read_project(?);")
 	if err != nil {
@@ -179,6 +275,7 @@ func ReadProject(db *sql.DB, id string) (*types.Kanban, error) {
 	project.Updated_At = meta.Updated_at
 	project.Created_By = meta.Created_by
 	project.Updated_By = meta.Updated_by
+	project.Version = meta.Version
 	return &project, nil
 }
 
@@ -213,6 +310,7 @@ func GetColumn(agent *Agent, id string) (*types.Column, error) {
 	column.UpdatedAt = meta.Updated_at
 	column.CreatedBy = meta.Created_by
 	column.UpdatedBy = meta.Updated_by
+	column.Version = meta.Version
 	return &column, nil
 }
 func ReadColumns(agent *Agent, projectId string) ([]types.Column, error) {
@@ -250,6 +348,7 @@ func ReadColumns(agent *Agent, projectId string) ([]types.Column, error) {
 		newColumn.UpdatedAt = meta.Updated_at
 		newColumn.CreatedBy = meta.Created_by
 		newColumn.UpdatedBy = meta.Updated_by
+		newColumn.Version = meta.Version
 		outputColumns = append(outputColumns, newColumn)
 	}
 	return outputColumns, err
@@ -268,17 +367,30 @@ func copyAndAppend(sl [][]sql.RawBytes, item []sql.RawBytes) [][]sql.RawBytes {
 	return newSlice
 }
 
+// readOptionalInt reads a nullable integer column, NULL comes out as nil.
+func readOptionalInt(col sql.RawBytes) (*int, error) {
+	if len(col) == 0 {
+		return nil, nil
+	}
+	val, err := strconv.Atoi(string(col))
+	if err != nil {
+		return nil, err
+	}
+	return &val, nil
+}
+
+// Metadata are the audit columns of a row, Version is only read from the tables having a version counter.
 type Metadata struct {
 	Created_at int
 	Updated_at int
 	Created_by string
 	Updated_by string
+	Version    int
 }
 
 func readMeta(columns []string, data []sql.RawBytes) (*Metadata, error) {
 	var result Metadata
 	for idx, col := range columns {
-		fmt.Println(col, string(data[idx]))
 		switch col {
 		case "created_at":
 			data, err := strconv.Atoi(string(data[idx]))
@@ -296,14 +408,20 @@ func readMeta(columns []string, data []sql.RawBytes) (*Metadata, error) {
 			result.Created_by = string(data[idx])
 		case "updated_by":
 			result.Updated_by = string(data[idx])
+		case "version":
+			data, err := strconv.Atoi(string(data[idx]))
+			if err != nil {
+				return nil, err
+			}
+			result.Version = data
 		}
 	}
 	return &result, nil
 }
 
-func GetTagsByCard(db *sql.DB, id string) ([]types.Tag, error) {
+func GetTagsByCard(agent *Agent, id string) ([]types.Tag, error) {
 	var outputTags []types.Tag
-	columns, values, err := readMultiRow(CreateAgentDB(db), id, `CALL read_tags_by_card_id(?);`)
+	columns, values, err := readMultiRow(agent, id, `CALL read_tags_by_card_id(?);`)
 	for i := range values {
 		row := values[i]
 		rowLength := len(row)
@@ -329,14 +447,15 @@ func GetTagsByCard(db *sql.DB, id string) ([]types.Tag, error) {
 		newTag.UpdatedAt = meta.Updated_at
 		newTag.CreatedBy = meta.Created_by
 		newTag.UpdatedBy = meta.Updated_by
+		newTag.Version = meta.Version
 		outputTags = append(outputTags, newTag)
 	}
 	return outputTags, err
 }
 
-func GetTagsByProject(db *sql.DB, id string) ([]types.Tag, error) {
+func GetTagsByProject(agent *Agent, id string) ([]types.Tag, error) {
 	var outputTags []types.Tag
-	columns, values, err := readMultiRow(CreateAgentDB(db), id, `CALL read_tags_by_project_id(?);`)
+	columns, values, err := readMultiRow(agent, id, `CALL read_tags_by_project_id(?);`)
 	if err != nil {
 		return nil, err
 	}
@@ -366,7 +485,38 @@ func GetTagsByProject(db *sql.DB, id string) ([]types.Tag, error) {
 		newTag.UpdatedAt = meta.Updated_at
 		newTag.CreatedBy = meta.Created_by
 		newTag.UpdatedBy = meta.Updated_by
+		newTag.Version = meta.Version
 		outputTags = append(outputTags, newTag)
 	}
 	return outputTags, err
 }
+
+func GetTag(agent *Agent, id string) (*types.Tag, error) {
+	columns, values, err := readOneRow(agent, id, `CALL read_tag_by_id(?);`)
+	if err != nil {
+		return nil, err
+	}
+	var tag types.Tag
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			tag.Id = string(col)
+		case "name":
+			tag.Name = string(col)
+		case "color":
+			tag.Color = string(col)
+		case "project_id":
+			tag.ProjectId = string(col)
+		}
+	}
+	meta, err := readMeta(columns, values)
+	if err != nil {
+		return nil, err
+	}
+	tag.CreatedAt = meta.Created_at
+	tag.UpdatedAt = meta.Updated_at
+	tag.CreatedBy = meta.Created_by
+	tag.UpdatedBy = meta.Updated_by
+	tag.Version = meta.Version
+	return &tag, nil
+}
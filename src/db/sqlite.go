@@ -0,0 +1,332 @@
+package db_driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// GetSQLiteDb opens the database file, it is created when missing and gets its schema from the migrations.
+// connString is a file path, optionally with go-sqlite3 parameters.
+func GetSQLiteDb(connString string) *sql.DB {
+	separator := "?"
+	if strings.Contains(connString, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", connString+separator+"_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		panic(err)
+	}
+	// SQLite allows a single writer, one connection keeps transactions from failing on a locked database.
+	db.SetMaxOpenConns(1)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("Opened SQLite database %s\n", connString)
+	return db
+}
+
+func NewSQLiteStorage(db *sql.DB) *SQLStorage {
+	return &SQLStorage{db, &Agent{db, nil, &sqliteProcedures}}
+}
+
+// Draw orders are counted from the ranks, single rows count the siblings ranked before them.
+const (
+	sqliteProjectColumns = `id, name, created_at, updated_at, created_by, updated_by, version`
+	sqliteColumnColumns  = `pc.id, pc.project_id, pc.name,
+		(SELECT count(*) FROM ProjectColumns o WHERE o.project_id = pc.project_id AND (o.draw_rank, o.id) <= (pc.draw_rank, pc.id)) AS draw_order,
+		pc.created_at, pc.updated_at, pc.created_by, pc.updated_by, pc.version`
+	sqliteProjectColumnColumns = `pc.id, pc.project_id, pc.name, ROW_NUMBER() OVER (ORDER BY pc.draw_rank, pc.id) AS draw_order,
+		pc.created_at, pc.updated_at, pc.created_by, pc.updated_by, pc.version`
+	sqliteCardColumns = `c.id, c.column_id, c.name, c.description,
+		(SELECT count(*) FROM Cards o WHERE o.column_id = c.column_id AND (o.draw_rank, o.id) <= (c.draw_rank, c.id)) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version`
+	sqliteColumnCardColumns = `c.id, c.column_id, c.name, c.description,
+		ROW_NUMBER() OVER (PARTITION BY c.column_id ORDER BY c.draw_rank, c.id) AS draw_order,
+		c.start_at, c.due_at, c.created_at, c.updated_at, c.created_by, c.updated_by, c.version`
+	sqliteCommentColumns   = `id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by, version`
+	sqliteChecklistColumns = `cl.id, cl.card_id, cl.name,
+		(SELECT count(*) FROM Checklists o WHERE o.card_id = cl.card_id AND (o.draw_rank, o.id) <= (cl.draw_rank, cl.id)) AS draw_order,
+		cl.created_at, cl.updated_at, cl.created_by, cl.updated_by, cl.version`
+	sqliteCardChecklistColumns = `cl.id, cl.card_id, cl.name, ROW_NUMBER() OVER (ORDER BY cl.draw_rank, cl.id) AS draw_order,
+		cl.created_at, cl.updated_at, cl.created_by, cl.updated_by, cl.version`
+	sqliteChecklistItemColumns = `i.id, i.checklist_id, i.text, i.done,
+		(SELECT count(*) FROM ChecklistItems o WHERE o.checklist_id = i.checklist_id AND (o.draw_rank, o.id) <= (i.draw_rank, i.id)) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by, i.version`
+	sqliteListChecklistItemColumns = `i.id, i.checklist_id, i.text, i.done,
+		ROW_NUMBER() OVER (PARTITION BY i.checklist_id ORDER BY i.draw_rank, i.id) AS draw_order,
+		i.created_at, i.updated_at, i.created_by, i.updated_by, i.version`
+	sqliteTagColumns = `t.id, t.project_id, t.name, t.color, t.created_at, t.updated_at, t.created_by, t.updated_by, t.version`
+)
+
+// sqliteProcedures follow the stored procedures of the MySQL schema.
+var sqliteProcedures = procedureSet{
+	reads: map[string]string{
+		"read_project":      `SELECT ` + sqliteProjectColumns + ` FROM Projects WHERE id = ?;`,
+		"read_column_by_id": `SELECT ` + sqliteColumnColumns + ` FROM ProjectColumns pc WHERE pc.id = ?;`,
+		"read_columns_by_project_id": `SELECT ` + sqliteProjectColumnColumns + `
+		FROM ProjectColumns pc WHERE pc.project_id = ? ORDER BY pc.draw_rank, pc.id;`,
+		"read_card_by_id":         `SELECT ` + sqliteCardColumns + ` FROM Cards c WHERE c.id = ?;`,
+		"read_cards_by_column_id": `SELECT ` + sqliteColumnCardColumns + ` FROM Cards c WHERE c.column_id = ? ORDER BY c.draw_rank, c.id;`,
+		"read_tags_by_card_id": `SELECT ` + sqliteTagColumns + `
+		FROM CardsTags ct JOIN Tags t ON t.id = ct.tag_id WHERE ct.card_id = ? ORDER BY t.created_at, t.id;`,
+		"read_tags_by_project_id": `SELECT ` + sqliteTagColumns + ` FROM Tags t WHERE t.project_id = ? ORDER BY t.created_at, t.id;`,
+		"read_tag_by_id":          `SELECT ` + sqliteTagColumns + ` FROM Tags t WHERE t.id = ?;`,
+		"read_cards_by_project_id": `SELECT ` + sqliteColumnCardColumns + `
+		FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id WHERE pc.project_id = ? ORDER BY c.column_id, c.draw_rank, c.id;`,
+		"read_due_cards_by_project_id": `SELECT ` + sqliteCardColumns + `
+		FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+		WHERE pc.project_id = ? AND c.due_at >= ? AND c.due_at < ? ORDER BY c.due_at, c.id;`,
+		"read_card_tags_by_project_id": `SELECT ct.card_id, ct.tag_id
+		FROM CardsTags ct
+		JOIN Cards c ON c.id = ct.card_id
+		JOIN ProjectColumns pc ON pc.id = c.column_id
+		JOIN Tags t ON t.id = ct.tag_id
+		WHERE pc.project_id = ? ORDER BY t.created_at, t.id;`,
+		"read_card_assignees_by_card_id": `SELECT card_id, user_id FROM CardAssignees WHERE card_id = ? ORDER BY created_at, user_id;`,
+		"read_card_assignees_by_project_id": `SELECT card_id, user_id
+		FROM CardAssignees WHERE project_id = ? ORDER BY created_at, user_id;`,
+		"read_cards_by_assignee_id": `SELECT ca.project_id, ` + sqliteCardColumns + `
+		FROM CardAssignees ca
+		JOIN Cards c ON c.id = ca.card_id
+		JOIN ProjectColumns pc ON pc.id = c.column_id
+		WHERE ca.user_id = ?
+		ORDER BY ca.project_id, pc.draw_rank, pc.id, c.draw_rank, c.id;`,
+		"read_comment_by_id": `SELECT ` + sqliteCommentColumns + ` FROM Comments WHERE id = ?;`,
+		"read_comments_by_card_id": `SELECT ` + sqliteCommentColumns + `
+		FROM Comments WHERE card_id = ? AND (created_at, id) > (?, ?) ORDER BY created_at, id LIMIT ?;`,
+		"read_comment_edits_by_comment_id": `SELECT id, comment_id, body, created_at, created_by
+		FROM CommentEdits WHERE comment_id = ? ORDER BY id;`,
+		"read_checklist_by_id":       `SELECT ` + sqliteChecklistColumns + ` FROM Checklists cl WHERE cl.id = ?;`,
+		"read_checklists_by_card_id": `SELECT ` + sqliteCardChecklistColumns + ` FROM Checklists cl WHERE cl.card_id = ? ORDER BY cl.draw_rank, cl.id;`,
+		"read_checklist_item_by_id":  `SELECT ` + sqliteChecklistItemColumns + ` FROM ChecklistItems i WHERE i.id = ?;`,
+		"read_checklist_items_by_checklist_id": `SELECT ` + sqliteListChecklistItemColumns + `
+		FROM ChecklistItems i WHERE i.checklist_id = ? ORDER BY i.draw_rank, i.id;`,
+		"read_checklist_items_by_card_id": `SELECT ` + sqliteListChecklistItemColumns + `
+		FROM ChecklistItems i JOIN Checklists cl ON cl.id = i.checklist_id
+		WHERE cl.card_id = ? ORDER BY i.checklist_id, i.draw_rank, i.id;`,
+		"read_checklist_progress_by_card_id": `SELECT cl.card_id, SUM(i.done) AS done, count(*) AS total
+		FROM ChecklistItems i JOIN Checklists cl ON cl.id = i.checklist_id
+		WHERE cl.card_id = ? GROUP BY cl.card_id;`,
+		"read_checklist_progress_by_project_id": `SELECT cl.card_id, SUM(i.done) AS done, count(*) AS total
+		FROM ChecklistItems i
+		JOIN Checklists cl ON cl.id = i.checklist_id
+		JOIN Cards c ON c.id = cl.card_id
+		JOIN ProjectColumns pc ON pc.id = c.column_id
+		WHERE pc.project_id = ? GROUP BY cl.card_id;`,
+	},
+	writes: map[string]procedure{
+		"lock_project":          {1, sqliteLock},
+		"lock_column":           {1, sqliteLock},
+		"lock_card":             {1, sqliteLock},
+		"lock_checklist":        {1, sqliteLock},
+		"create_project":        {3, sqliteCreateProject},
+		"update_project_data":   {4, sqliteUpdateProjectData},
+		"create_column":         {5, sqliteCreateColumn},
+		"update_column_data":    {5, sqliteUpdateColumnData},
+		"move_column":           {4, sqliteMoveColumn},
+		"create_card":           {8, sqliteCreateCard},
+		"update_card":           {9, sqliteUpdateCard},
+		"move_card":             {5, sqliteMoveCard},
+		"create_tag":            {5, sqliteCreateTag},
+		"update_tag":            {5, sqliteUpdateTag},
+		"assign_card":           {3, sqliteAssignCard},
+		"create_comment":        {5, sqliteCreateComment},
+		"update_comment":        {4, sqliteUpdateComment},
+		"create_checklist":      {5, sqliteCreateChecklist},
+		"update_checklist":      {4, sqliteUpdateChecklist},
+		"move_checklist":        {4, sqliteMoveChecklist},
+		"create_checklist_item": {5, sqliteCreateChecklistItem},
+		"update_checklist_item": {5, sqliteUpdateChecklistItem},
+		"toggle_checklist_item": {2, sqliteToggleChecklistItem},
+		"move_checklist_item":   {4, sqliteMoveChecklistItem},
+	},
+}
+
+// lock_project(id), lock_column(id), lock_card(id) and lock_checklist(id), SQLite has a single writer
+// so there is nothing to wait for.
+func sqliteLock(agent *Agent, args []any) (sql.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+// create_project(id, name, author)
+func sqliteCreateProject(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO Projects
+		(id, name, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?);`, args[0], args[1], now, now, args[2], args[2])
+}
+
+// update_project_data(id, name, author, version), like every versioned write it changes nothing when version
+// is not 0 and the row is at another version.
+func sqliteUpdateProjectData(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Projects SET name = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], time.Now().Unix(), args[2], args[0], args[3], args[3])
+}
+
+// create_column(project_id, id, name, rank, author)
+func sqliteCreateColumn(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO ProjectColumns
+		(id, project_id, name, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], now, now, args[4], args[4])
+}
+
+// update_column_data(id, name, author, rank, version), an empty rank keeps the column in place.
+func sqliteUpdateColumnData(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE ProjectColumns SET name = ?, draw_rank = COALESCE(NULLIF(?, ''), draw_rank), updated_at = ?, updated_by = ?,
+		version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], args[3], time.Now().Unix(), args[2], args[0], args[4], args[4])
+}
+
+// move_column(id, rank, author, version)
+func sqliteMoveColumn(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE ProjectColumns SET draw_rank = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], time.Now().Unix(), args[2], args[0], args[3], args[3])
+}
+
+// create_card(column_id, id, name, description, rank, author, start_at, due_at)
+func sqliteCreateCard(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO Cards
+		(id, column_id, name, description, draw_rank, start_at, due_at, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], args[4], args[6], args[7], now, now, args[5], args[5])
+}
+
+// update_card(id, column_id, name, description, author, rank, start_at, due_at, version), an empty rank keeps
+// the card in place.
+func sqliteUpdateCard(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Cards SET column_id = ?, name = ?, description = ?, draw_rank = COALESCE(NULLIF(?, ''), draw_rank),
+		start_at = ?, due_at = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], args[2], args[3], args[5], args[6], args[7], time.Now().Unix(), args[4],
+		args[0], args[8], args[8])
+}
+
+// move_card(id, column_id, rank, author, version)
+func sqliteMoveCard(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Cards SET column_id = ?, draw_rank = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], args[2], time.Now().Unix(), args[3], args[0], args[4], args[4])
+}
+
+// create_tag(project_id, id, name, color, author)
+func sqliteCreateTag(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO Tags
+		(id, project_id, name, color, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], now, now, args[4], args[4])
+}
+
+// update_tag(id, name, color, author, version)
+func sqliteUpdateTag(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Tags SET name = ?, color = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], args[2], time.Now().Unix(), args[3], args[0], args[4], args[4])
+}
+
+// assign_card(card_id, user_id, author), the project is taken from the column of the card.
+func sqliteAssignCard(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	INSERT INTO CardAssignees
+		(card_id, project_id, user_id, created_at, created_by)
+	SELECT c.id, pc.project_id, ?, ?, ?
+	FROM Cards c JOIN ProjectColumns pc ON pc.id = c.column_id
+	WHERE c.id = ?;`, args[1], time.Now().Unix(), args[2], args[0])
+}
+
+// create_comment(card_id, id, parent_id, body, author), an empty parent_id makes a top level comment.
+func sqliteCreateComment(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO Comments
+		(id, card_id, parent_id, body, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], now, now, args[4], args[4])
+}
+
+// update_comment(id, body, author, version), the current body is kept in CommentEdits before it is replaced.
+func sqliteUpdateComment(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	_, err := agent.Exec(`
+	INSERT INTO CommentEdits
+		(comment_id, body, created_at, created_by)
+	SELECT id, body, ?, ? FROM Comments WHERE id = ? AND (? = 0 OR version = ?);`, now, args[2], args[0], args[3], args[3])
+	if err != nil {
+		return nil, err
+	}
+	return agent.Exec(`
+	UPDATE Comments SET body = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], now, args[2], args[0], args[3], args[3])
+}
+
+// create_checklist(card_id, id, name, rank, author)
+func sqliteCreateChecklist(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO Checklists
+		(id, card_id, name, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], now, now, args[4], args[4])
+}
+
+// update_checklist(id, name, author, version)
+func sqliteUpdateChecklist(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Checklists SET name = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], time.Now().Unix(), args[2], args[0], args[3], args[3])
+}
+
+// move_checklist(id, rank, author, version)
+func sqliteMoveChecklist(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE Checklists SET draw_rank = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], time.Now().Unix(), args[2], args[0], args[3], args[3])
+}
+
+// create_checklist_item(checklist_id, id, text, rank, author), new items are not done.
+func sqliteCreateChecklistItem(agent *Agent, args []any) (sql.Result, error) {
+	now := time.Now().Unix()
+	return agent.Exec(`
+	INSERT INTO ChecklistItems
+		(id, checklist_id, text, done, draw_rank, created_at, updated_at, created_by, updated_by)
+	VALUES
+		(?, ?, ?, 0, ?, ?, ?, ?, ?);`, args[1], args[0], args[2], args[3], now, now, args[4], args[4])
+}
+
+// update_checklist_item(id, text, done, author, version)
+func sqliteUpdateChecklistItem(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE ChecklistItems SET text = ?, done = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], args[2], time.Now().Unix(), args[3], args[0], args[4], args[4])
+}
+
+// toggle_checklist_item(id, author)
+func sqliteToggleChecklistItem(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE ChecklistItems SET done = NOT done, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ?;`, time.Now().Unix(), args[1], args[0])
+}
+
+// move_checklist_item(id, rank, author, version)
+func sqliteMoveChecklistItem(agent *Agent, args []any) (sql.Result, error) {
+	return agent.Exec(`
+	UPDATE ChecklistItems SET draw_rank = ?, updated_at = ?, updated_by = ?, version = version + 1
+	WHERE id = ? AND (? = 0 OR version = ?);`, args[1], time.Now().Unix(), args[2], args[0], args[3], args[3])
+}
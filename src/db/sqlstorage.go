@@ -0,0 +1,453 @@
+package db_driver
+
+import (
+	"context"
+	"database/sql"
+	"types"
+)
+
+// SQLStorage is the Storage backed by a database/sql connection. MySQL runs the stored procedures
+// of its schema, databases without them get the procedures emulated by the agent.
+type SQLStorage struct {
+	db    *sql.DB
+	agent *Agent
+}
+
+func NewMySQLStorage(db *sql.DB) *SQLStorage {
+	return &SQLStorage{db, CreateAgentDB(db)}
+}
+
+// inTx runs fn on the current transaction, or on a new one when the storage is not bound to any.
+func (s *SQLStorage) inTx(fn func(agent *Agent) error) error {
+	if s.agent.tx != nil {
+		return fn(s.agent)
+	}
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	err = fn(s.agent.withTx(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStorage) Tx(fn func(tx Storage) error) error {
+	return s.inTx(func(agent *Agent) error {
+		return fn(&SQLStorage{s.db, agent})
+	})
+}
+
+func (s *SQLStorage) Close() error {
+	return s.db.Close()
+}
+
+// RebalanceRanks spreads out every crowded project, column, card and checklist in a transaction of its own,
+// so placements wait on a single parent at a time.
+func (s *SQLStorage) RebalanceRanks() (int, error) {
+	count := 0
+	for _, siblings := range []rankSiblings{columnRanks, cardRanks, checklistRanks, checklistItemRanks} {
+		parents, err := siblings.crowded(s.agent)
+		if err != nil {
+			return count, err
+		}
+		for _, parentId := range parents {
+			err = s.inTx(func(agent *Agent) error {
+				err := siblings.lockParent(agent, parentId)
+				if err != nil {
+					return err
+				}
+				return siblings.rebalance(agent, parentId)
+			})
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *SQLStorage) CreateProject(id string, project *types.KanbanJson, author string) error {
+	return s.inTx(func(agent *Agent) error {
+		return CreateProjectTX(agent, id, project, author)
+	})
+}
+
+func (s *SQLStorage) GetProject(id string) (*types.KanbanJson, error) {
+	return GetProject(s.agent, id)
+}
+
+func (s *SQLStorage) UpdateProjectData(id string, name string, version int, author string) error {
+	return UpdateProjectData(s.agent, id, name, version, author)
+}
+
+func (s *SQLStorage) DeleteProject(id string) error {
+	return DeleteProject(s.agent, id)
+}
+
+func (s *SQLStorage) GetColumn(id string) (*types.Column, error) {
+	return GetColumn(s.agent, id)
+}
+
+func (s *SQLStorage) GetColumnProjectId(id string) (string, error) {
+	return GetColumnProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) CreateColumns(projectId string, columns []types.ColumnJson, author string) ([]types.ColumnJson, error) {
+	var newColumns []types.ColumnJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newColumns, err = CreateColumns(agent, projectId, columns, author)
+		return err
+	})
+	return newColumns, err
+}
+
+func (s *SQLStorage) CreateColumnAt(projectId string, column *types.ColumnJson, position int, author string) (*types.ColumnJson, error) {
+	var newColumn *types.ColumnJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newColumn, err = CreateColumnAt(agent, projectId, column, position, author)
+		return err
+	})
+	return newColumn, err
+}
+
+func (s *SQLStorage) UpdateColumnData(column *types.Column, author string) error {
+	return s.inTx(func(agent *Agent) error {
+		return UpdateColumnDataTX(agent, column, author)
+	})
+}
+
+func (s *SQLStorage) MoveColumn(id string, position int, version int, author string) ([]types.ColumnJson, error) {
+	var columns []types.ColumnJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		columns, err = MoveColumnTX(agent, id, position, version, author)
+		return err
+	})
+	return columns, err
+}
+
+func (s *SQLStorage) DeleteColumn(id string) error {
+	return s.inTx(func(agent *Agent) error {
+		return DeleteColumnTX(agent, id)
+	})
+}
+
+func (s *SQLStorage) GetCard(id string) (*types.Card, error) {
+	return GetCard(s.agent, id)
+}
+
+func (s *SQLStorage) GetCardProjectId(id string) (string, error) {
+	return GetCardProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) GetDueCards(projectId string, after int, before int) ([]types.CardJson, error) {
+	return GetDueCards(s.agent, projectId, after, before)
+}
+
+func (s *SQLStorage) CreateCards(columnId string, cards *[]types.CardJson, author string) ([]types.CardJson, error) {
+	var newCards []types.CardJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newCards, err = CreateCards(agent, columnId, cards, author)
+		return err
+	})
+	return newCards, err
+}
+
+func (s *SQLStorage) CreateCardAt(card *types.CardJson, position int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newCard, err = CreateCardAt(agent, card, position, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *SQLStorage) UpdateCard(card *types.CardJson, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newCard, err = UpdateCardTX(agent, card, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *SQLStorage) MoveCard(cardId string, columnId string, position int, version int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newCard, err = MoveCardTX(agent, cardId, columnId, position, version, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *SQLStorage) DeleteCard(id string) error {
+	return s.inTx(func(agent *Agent) error {
+		return DeleteCardTX(agent, id)
+	})
+}
+
+func (s *SQLStorage) CreateCardTags(cardId string, tagId string) error {
+	return CreateCardTags(s.agent, cardId, tagId)
+}
+
+func (s *SQLStorage) RemoveCardTags(cardId string, tagId string) error {
+	return RemoveCardTags(s.agent, cardId, tagId)
+}
+
+func (s *SQLStorage) AssignCard(cardId string, userId string, author string) error {
+	return AssignCard(s.agent, cardId, userId, author)
+}
+
+func (s *SQLStorage) UnassignCard(cardId string, userId string) error {
+	return UnassignCard(s.agent, cardId, userId)
+}
+
+func (s *SQLStorage) GetCardAssigneeIds(cardId string) ([]string, error) {
+	return GetCardAssigneeIds(s.agent, cardId)
+}
+
+func (s *SQLStorage) GetAssignedCards(userId string) ([]types.AssignedCardJson, error) {
+	return GetAssignedCards(s.agent, userId)
+}
+
+func (s *SQLStorage) GetTag(id string) (*types.Tag, error) {
+	return GetTag(s.agent, id)
+}
+
+func (s *SQLStorage) GetTagProjectId(id string) (string, error) {
+	return GetTagProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) GetTagsByCard(cardId string) ([]types.Tag, error) {
+	return GetTagsByCard(s.agent, cardId)
+}
+
+func (s *SQLStorage) CreateTags(projectId string, tags *[]types.TagJson, author string) ([]types.TagJson, error) {
+	return CreateTags(s.agent, projectId, tags, author)
+}
+
+func (s *SQLStorage) UpdateTag(tag *types.TagJson, author string) error {
+	return UpdateTag(s.agent, tag, author)
+}
+
+func (s *SQLStorage) DeleteTag(id string) error {
+	return DeleteTag(s.agent, id)
+}
+
+func (s *SQLStorage) GetCardHistory(cardId string) ([]types.CardUpdateRecordJson, error) {
+	return GetCardHistory(s.agent, cardId)
+}
+
+func (s *SQLStorage) RevertCard(cardId string, recordId int, author string) (*types.CardJson, error) {
+	var newCard *types.CardJson
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		newCard, err = RevertCardTX(agent, cardId, recordId, author)
+		return err
+	})
+	return newCard, err
+}
+
+func (s *SQLStorage) GetComment(id string) (*types.Comment, error) {
+	return GetComment(s.agent, id)
+}
+
+func (s *SQLStorage) GetCommentProjectId(id string) (string, error) {
+	return GetCommentProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) GetComments(cardId string, afterAt int, afterId string, limit int) ([]types.Comment, error) {
+	return GetComments(s.agent, cardId, afterAt, afterId, limit)
+}
+
+func (s *SQLStorage) CreateComment(cardId string, parentId string, body string, author string) (*types.Comment, error) {
+	return CreateComment(s.agent, cardId, parentId, body, author)
+}
+
+func (s *SQLStorage) UpdateComment(id string, body string, version int, author string) (*types.Comment, error) {
+	var comment *types.Comment
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		comment, err = UpdateCommentTX(agent, id, body, version, author)
+		return err
+	})
+	return comment, err
+}
+
+func (s *SQLStorage) DeleteComment(id string) error {
+	return DeleteComment(s.agent, id)
+}
+
+func (s *SQLStorage) GetCommentEdits(commentId string) ([]types.CommentEdit, error) {
+	return GetCommentEdits(s.agent, commentId)
+}
+
+func (s *SQLStorage) GetChecklist(id string) (*types.Checklist, error) {
+	return GetChecklist(s.agent, id)
+}
+
+func (s *SQLStorage) GetChecklistProjectId(id string) (string, error) {
+	return GetChecklistProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) GetChecklists(cardId string) ([]types.ChecklistJson, error) {
+	return GetChecklists(s.agent, cardId)
+}
+
+func (s *SQLStorage) GetChecklistProgress(cardId string) (*types.ChecklistProgressJson, error) {
+	return GetChecklistProgress(s.agent, cardId)
+}
+
+func (s *SQLStorage) CreateChecklist(cardId string, name string, position int, author string) (*types.Checklist, error) {
+	var checklist *types.Checklist
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		checklist, err = CreateChecklistTX(agent, cardId, name, position, author)
+		return err
+	})
+	return checklist, err
+}
+
+func (s *SQLStorage) RenameChecklist(id string, name string, version int, author string) (*types.Checklist, error) {
+	return RenameChecklist(s.agent, id, name, version, author)
+}
+
+func (s *SQLStorage) MoveChecklist(id string, position int, version int, author string) (*types.Checklist, error) {
+	var checklist *types.Checklist
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		checklist, err = MoveChecklistTX(agent, id, position, version, author)
+		return err
+	})
+	return checklist, err
+}
+
+func (s *SQLStorage) DeleteChecklist(id string) error {
+	return DeleteChecklist(s.agent, id)
+}
+
+func (s *SQLStorage) GetChecklistItem(id string) (*types.ChecklistItem, error) {
+	return GetChecklistItem(s.agent, id)
+}
+
+func (s *SQLStorage) GetChecklistItemProjectId(id string) (string, error) {
+	return GetChecklistItemProjectId(s.agent, id)
+}
+
+func (s *SQLStorage) GetChecklistItems(checklistId string) ([]types.ChecklistItem, error) {
+	return GetChecklistItems(s.agent, checklistId)
+}
+
+func (s *SQLStorage) CreateChecklistItem(checklistId string, text string, position int, author string) (*types.ChecklistItem, error) {
+	var item *types.ChecklistItem
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		item, err = CreateChecklistItemTX(agent, checklistId, text, position, author)
+		return err
+	})
+	return item, err
+}
+
+func (s *SQLStorage) UpdateChecklistItem(id string, text string, done bool, version int, author string) (*types.ChecklistItem, error) {
+	return UpdateChecklistItem(s.agent, id, text, done, version, author)
+}
+
+func (s *SQLStorage) ToggleChecklistItem(id string, author string) (*types.ChecklistItem, error) {
+	return ToggleChecklistItem(s.agent, id, author)
+}
+
+func (s *SQLStorage) MoveChecklistItem(id string, position int, version int, author string) (*types.ChecklistItem, error) {
+	var item *types.ChecklistItem
+	err := s.inTx(func(agent *Agent) error {
+		var err error
+		item, err = MoveChecklistItemTX(agent, id, position, version, author)
+		return err
+	})
+	return item, err
+}
+
+func (s *SQLStorage) DeleteChecklistItem(id string) error {
+	return DeleteChecklistItem(s.agent, id)
+}
+
+func (s *SQLStorage) AddProjectMember(projectId string, userId string, role string, author string) error {
+	return AddProjectMember(s.agent, projectId, userId, role, author)
+}
+
+func (s *SQLStorage) GetProjectMember(projectId string, userId string) (*types.Member, error) {
+	return GetProjectMember(s.agent, projectId, userId)
+}
+
+func (s *SQLStorage) GetProjectMembers(projectId string) ([]types.Member, error) {
+	return GetProjectMembers(s.agent, projectId)
+}
+
+func (s *SQLStorage) UpdateProjectMember(projectId string, userId string, role string, author string) error {
+	return s.inTx(func(agent *Agent) error {
+		return UpdateProjectMemberTX(agent, projectId, userId, role, author)
+	})
+}
+
+func (s *SQLStorage) RemoveProjectMember(projectId string, userId string) error {
+	return s.inTx(func(agent *Agent) error {
+		return RemoveProjectMemberTX(agent, projectId, userId)
+	})
+}
+
+func (s *SQLStorage) CountProjectOwners(projectId string) (int, error) {
+	return CountProjectOwners(s.agent, projectId)
+}
+
+func (s *SQLStorage) CreateUser(login string, passwordHash string) (*types.User, error) {
+	return CreateUser(s.agent, login, passwordHash)
+}
+
+func (s *SQLStorage) GetUserById(id string) (*types.User, error) {
+	return GetUserById(s.agent, id)
+}
+
+func (s *SQLStorage) GetUserByLogin(login string) (*types.User, error) {
+	return GetUserByLogin(s.agent, login)
+}
+
+func (s *SQLStorage) CreateSession(userId string, tokenHash string, expiresAt int) error {
+	return CreateSession(s.agent, userId, tokenHash, expiresAt)
+}
+
+func (s *SQLStorage) GetSessionUser(tokenHash string) (*types.User, error) {
+	return GetSessionUser(s.agent, tokenHash)
+}
+
+func (s *SQLStorage) DeleteSession(tokenHash string) error {
+	return DeleteSession(s.agent, tokenHash)
+}
+
+func (s *SQLStorage) CreateAccessToken(userId string, name string, tokenHash string, scopes []string) (*types.AccessToken, error) {
+	return CreateAccessToken(s.agent, userId, name, tokenHash, scopes)
+}
+
+func (s *SQLStorage) GetAccessTokens(userId string) ([]types.AccessToken, error) {
+	return GetAccessTokens(s.agent, userId)
+}
+
+func (s *SQLStorage) GetAccessTokenUser(tokenHash string) (*types.AccessToken, *types.User, error) {
+	return GetAccessTokenUser(s.agent, tokenHash)
+}
+
+func (s *SQLStorage) TouchAccessToken(id string) error {
+	return TouchAccessToken(s.agent, id)
+}
+
+func (s *SQLStorage) RevokeAccessToken(userId string, id string) error {
+	return RevokeAccessToken(s.agent, userId, id)
+}
@@ -0,0 +1,182 @@
+package db_driver
+
+import "types"
+
+// Storage is everything the api needs from a database. Implementations must be safe for concurrent use,
+// lookups of missing items return NotFoundError and changes that touch nothing return NoEffect.
+// Projects, columns, cards and tags carry a version counter bumped by every write to them. Their updates take
+// the version the caller expects, 0 writes unconditionally, and fail with VersionConflict when the stored
+// item is at another version.
+type Storage interface {
+	ProjectStorage
+	ColumnStorage
+	CardStorage
+	TagStorage
+	HistoryStorage
+	CommentStorage
+	ChecklistStorage
+	MemberStorage
+	UserStorage
+	TokenStorage
+	// Tx runs fn against a storage bound to a single transaction, it is committed when fn returns nil
+	// and rolled back otherwise. Calling Tx on a transaction storage runs fn in that same transaction.
+	Tx(fn func(tx Storage) error) error
+	// RebalanceRanks spreads out the ranks under the projects, columns, cards and checklists whose ranks grew long
+	// from inserts into the same gap, it returns how many were spread out. It is meant to run in the background.
+	RebalanceRanks() (int, error)
+	Close() error
+}
+
+type ProjectStorage interface {
+	// CreateProject stores the project with its tags and columns and makes author its owner.
+	CreateProject(id string, project *types.KanbanJson, author string) error
+	// GetProject returns the whole board with columns, cards and tags.
+	GetProject(id string) (*types.KanbanJson, error)
+	UpdateProjectData(id string, name string, version int, author string) error
+	DeleteProject(id string) error
+}
+
+type ColumnStorage interface {
+	GetColumn(id string) (*types.Column, error)
+	GetColumnProjectId(id string) (string, error)
+	CreateColumns(projectId string, columns []types.ColumnJson, author string) ([]types.ColumnJson, error)
+	// CreateColumnAt creates the column at the given position of the project without moving the other columns,
+	// positions start at 1 and 0 appends the column to the project.
+	CreateColumnAt(projectId string, column *types.ColumnJson, position int, author string) (*types.ColumnJson, error)
+	// UpdateColumnData renames and reorders the column, column.Version is the expected version.
+	UpdateColumnData(column *types.Column, author string) error
+	// MoveColumn puts the column at the given position of its project, it returns the columns of the project
+	// in their new order. Positions start at 1, 0 or a position past the last column moves it to the end.
+	MoveColumn(id string, position int, version int, author string) ([]types.ColumnJson, error)
+	// DeleteColumn removes the column with its cards.
+	DeleteColumn(id string) error
+}
+
+type CardStorage interface {
+	GetCard(id string) (*types.Card, error)
+	GetCardProjectId(id string) (string, error)
+	// GetDueCards returns the cards of the project due from after up to before, soonest first.
+	// Cards without a due date are left out.
+	GetDueCards(projectId string, after int, before int) ([]types.CardJson, error)
+	CreateCards(columnId string, cards *[]types.CardJson, author string) ([]types.CardJson, error)
+	// CreateCardAt creates the card at the given position of its column without moving the other cards,
+	// positions start at 1 and 0 appends the card to the column.
+	CreateCardAt(card *types.CardJson, position int, author string) (*types.CardJson, error)
+	// UpdateCard writes the card along with its update record, a card moved to another column
+	// is appended to it. card.Version is the expected version.
+	UpdateCard(card *types.CardJson, author string) (*types.CardJson, error)
+	// MoveCard puts the card at the given position of the column, within its own column or another one.
+	// Positions start at 1, 0 or a position past the last card appends it.
+	MoveCard(cardId string, columnId string, position int, version int, author string) (*types.CardJson, error)
+	// DeleteCard removes the card with its comments and checklists.
+	DeleteCard(id string) error
+	CreateCardTags(cardId string, tagId string) error
+	RemoveCardTags(cardId string, tagId string) error
+	// AssignCard makes the user an assignee of the card, the user has to be a member of the project of the card.
+	// Assignments go away with the card and when the user leaves the project.
+	AssignCard(cardId string, userId string, author string) error
+	UnassignCard(cardId string, userId string) error
+	// GetCardAssigneeIds returns the assignees of the card in the order they were assigned.
+	GetCardAssigneeIds(cardId string) ([]string, error)
+	// GetAssignedCards returns the cards assigned to the user across all projects, grouped by project in board order.
+	GetAssignedCards(userId string) ([]types.AssignedCardJson, error)
+}
+
+type TagStorage interface {
+	GetTag(id string) (*types.Tag, error)
+	GetTagProjectId(id string) (string, error)
+	GetTagsByCard(cardId string) ([]types.Tag, error)
+	CreateTags(projectId string, tags *[]types.TagJson, author string) ([]types.TagJson, error)
+	// UpdateTag writes the name and color of the tag, tag.Version is the expected version.
+	UpdateTag(tag *types.TagJson, author string) error
+	DeleteTag(id string) error
+}
+
+type HistoryStorage interface {
+	GetCardHistory(cardId string) ([]types.CardUpdateRecordJson, error)
+	// RevertCard undoes every change from the given update record onwards, recordId 0 undoes only the last change.
+	RevertCard(cardId string, recordId int, author string) (*types.CardJson, error)
+}
+
+type CommentStorage interface {
+	GetComment(id string) (*types.Comment, error)
+	GetCommentProjectId(id string) (string, error)
+	// GetComments returns up to limit comments of the card, oldest first. Comments are ordered by creation time
+	// and id, only the ones after afterAt and afterId are returned, afterAt 0 starts from the first comment.
+	GetComments(cardId string, afterAt int, afterId string, limit int) ([]types.Comment, error)
+	// CreateComment posts the comment on the card, parentId names the comment it replies to and is empty otherwise.
+	CreateComment(cardId string, parentId string, body string, author string) (*types.Comment, error)
+	// UpdateComment replaces the body of the comment and keeps the previous body as an edit.
+	// A version other than 0 makes it fail with VersionConflict unless the comment is still at that version.
+	UpdateComment(id string, body string, version int, author string) (*types.Comment, error)
+	// DeleteComment removes the comment along with its replies and edits.
+	DeleteComment(id string) error
+	// GetCommentEdits returns the earlier bodies of the comment, oldest first.
+	GetCommentEdits(commentId string) ([]types.CommentEdit, error)
+}
+
+type ChecklistStorage interface {
+	GetChecklist(id string) (*types.Checklist, error)
+	GetChecklistProjectId(id string) (string, error)
+	// GetChecklists returns the checklists of the card in order, each with its items in order.
+	GetChecklists(cardId string) ([]types.ChecklistJson, error)
+	// GetChecklistProgress counts the done items and all items in the checklists of the card.
+	GetChecklistProgress(cardId string) (*types.ChecklistProgressJson, error)
+	// CreateChecklist adds the checklist at the given position among the checklists of the card,
+	// positions start at 1 and 0 appends it.
+	CreateChecklist(cardId string, name string, position int, author string) (*types.Checklist, error)
+	// RenameChecklist writes the name of the checklist. Like in the other checklist writes taking a version,
+	// a version other than 0 makes it fail with VersionConflict unless the checklist is still at that version.
+	RenameChecklist(id string, name string, version int, author string) (*types.Checklist, error)
+	// MoveChecklist puts the checklist at the given position among the checklists of its card.
+	// Positions start at 1, 0 or a position past the last checklist moves it to the end.
+	MoveChecklist(id string, position int, version int, author string) (*types.Checklist, error)
+	// DeleteChecklist removes the checklist with its items.
+	DeleteChecklist(id string) error
+	GetChecklistItem(id string) (*types.ChecklistItem, error)
+	GetChecklistItemProjectId(id string) (string, error)
+	// GetChecklistItems returns the items of the checklist in order.
+	GetChecklistItems(checklistId string) ([]types.ChecklistItem, error)
+	// CreateChecklistItem adds an item that is not done at the given position of the checklist,
+	// positions start at 1 and 0 appends it.
+	CreateChecklistItem(checklistId string, text string, position int, author string) (*types.ChecklistItem, error)
+	// UpdateChecklistItem writes the text and the done flag of the item.
+	UpdateChecklistItem(id string, text string, done bool, version int, author string) (*types.ChecklistItem, error)
+	// ToggleChecklistItem flips the done flag of the item.
+	ToggleChecklistItem(id string, author string) (*types.ChecklistItem, error)
+	// MoveChecklistItem puts the item at the given position of its checklist.
+	// Positions start at 1, 0 or a position past the last item moves it to the end.
+	MoveChecklistItem(id string, position int, version int, author string) (*types.ChecklistItem, error)
+	DeleteChecklistItem(id string) error
+}
+
+type MemberStorage interface {
+	AddProjectMember(projectId string, userId string, role string, author string) error
+	GetProjectMember(projectId string, userId string) (*types.Member, error)
+	GetProjectMembers(projectId string) ([]types.Member, error)
+	// UpdateProjectMember changes the role of the member, it fails with LastOwnerError when that
+	// would leave the project without an owner.
+	UpdateProjectMember(projectId string, userId string, role string, author string) error
+	// RemoveProjectMember fails with LastOwnerError when the member is the last owner.
+	RemoveProjectMember(projectId string, userId string) error
+	CountProjectOwners(projectId string) (int, error)
+}
+
+type UserStorage interface {
+	CreateUser(login string, passwordHash string) (*types.User, error)
+	GetUserById(id string) (*types.User, error)
+	GetUserByLogin(login string) (*types.User, error)
+	CreateSession(userId string, tokenHash string, expiresAt int) error
+	// GetSessionUser resolves a hashed session token to its user, expired sessions are treated as missing.
+	GetSessionUser(tokenHash string) (*types.User, error)
+	DeleteSession(tokenHash string) error
+}
+
+type TokenStorage interface {
+	CreateAccessToken(userId string, name string, tokenHash string, scopes []string) (*types.AccessToken, error)
+	GetAccessTokens(userId string) ([]types.AccessToken, error)
+	// GetAccessTokenUser resolves a hashed access token to the token and its owner, revoked tokens are treated as missing.
+	GetAccessTokenUser(tokenHash string) (*types.AccessToken, *types.User, error)
+	TouchAccessToken(id string) error
+	RevokeAccessToken(userId string, id string) error
+}
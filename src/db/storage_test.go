@@ -0,0 +1,537 @@
+package db_driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+	"types"
+)
+
+// testStorages are the backends every conformance case runs against, each case gets an empty storage.
+// MySQL only runs when MYSQL_TEST_CONNECTION_STRING names a database the tests may fill, the schema is
+// migrated and the rows the cases create are left behind.
+func testStorages() map[string]func(t *testing.T) Storage {
+	storages := map[string]func(t *testing.T) Storage{
+		"memory": func(t *testing.T) Storage {
+			return NewMemoryStorage()
+		},
+		"sqlite": func(t *testing.T) Storage {
+			db := GetSQLiteDb(filepath.Join(t.TempDir(), "kanban.db"))
+			_, err := Migrate(db, DialectSQLite, false)
+			if err != nil {
+				t.Fatalf("can't migrate sqlite: %s", err)
+			}
+			storage := NewSQLiteStorage(db)
+			t.Cleanup(func() { storage.Close() })
+			return storage
+		},
+	}
+	connectionString := os.Getenv("MYSQL_TEST_CONNECTION_STRING")
+	if connectionString != "" {
+		storages["mysql"] = func(t *testing.T) Storage {
+			db := GetDb(connectionString)
+			_, err := Migrate(db, DialectMySQL, false)
+			if err != nil {
+				t.Fatalf("can't migrate mysql: %s", err)
+			}
+			storage := NewMySQLStorage(db)
+			t.Cleanup(func() { storage.Close() })
+			return storage
+		}
+	}
+	return storages
+}
+
+// testBoard is the board every case starts from: columns todo and done, cards a, b and c in todo
+// and an unlinked tag.
+type testBoard struct {
+	author    string
+	projectId string
+	todo      string
+	done      string
+	cards     map[string]string
+	tag       string
+}
+
+func createTestBoard(t *testing.T, s Storage) *testBoard {
+	user, err := s.CreateUser(fmt.Sprintf("tester-%d", time.Now().UnixNano()), "hash")
+	if err != nil {
+		t.Fatalf("can't create user: %s", err)
+	}
+	board := testBoard{author: user.Id, projectId: "project-" + user.Id, cards: map[string]string{}}
+	project := types.KanbanJson{
+		Name: "board",
+		Columns: []types.ColumnJson{
+			{Name: "todo", Cards: []types.CardJson{{Name: "a"}, {Name: "b"}, {Name: "c"}}},
+			{Name: "done"},
+		},
+		Tags: []types.TagJson{{Name: "bug", Color: "red"}},
+	}
+	err = s.CreateProject(board.projectId, &project, board.author)
+	if err != nil {
+		t.Fatalf("can't create project: %s", err)
+	}
+	stored := readTestBoard(t, s, board.projectId)
+	if len(stored.Columns) != 2 || len(stored.Tags) != 1 {
+		t.Fatalf("created board has %d columns and %d tags, want 2 and 1", len(stored.Columns), len(stored.Tags))
+	}
+	board.todo = stored.Columns[0].Id
+	board.done = stored.Columns[1].Id
+	for _, card := range stored.Columns[0].Cards {
+		board.cards[card.Name] = card.Id
+	}
+	board.tag = stored.Tags[0].Id
+	return &board
+}
+
+func readTestBoard(t *testing.T, s Storage, projectId string) *types.KanbanJson {
+	project, err := s.GetProject(projectId)
+	if err != nil {
+		t.Fatalf("can't read project: %s", err)
+	}
+	return project
+}
+
+// boardLayout names the columns and their cards in board order.
+func boardLayout(project *types.KanbanJson) map[string][]string {
+	layout := map[string][]string{}
+	for _, column := range project.Columns {
+		names := []string{}
+		for _, card := range column.Cards {
+			names = append(names, card.Name)
+		}
+		layout[column.Name] = names
+	}
+	return layout
+}
+
+func columnNames(columns []types.ColumnJson) []string {
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+func expectConflict(t *testing.T, err error) {
+	t.Helper()
+	var conflict VersionConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got error %v, want VersionConflict", err)
+	}
+}
+
+func expectNotFound(t *testing.T, err error) {
+	t.Helper()
+	var nfe NotFoundError
+	if !errors.As(err, &nfe) {
+		t.Fatalf("got error %v, want NotFoundError", err)
+	}
+}
+
+var storageCases = []struct {
+	name string
+	run  func(t *testing.T, s Storage, board *testBoard)
+}{
+	{"create and read board", func(t *testing.T, s Storage, board *testBoard) {
+		project := readTestBoard(t, s, board.projectId)
+		if project.Name != "board" || project.Version != 1 {
+			t.Errorf("project is %q at version %d, want board at 1", project.Name, project.Version)
+		}
+		want := map[string][]string{"todo": {"a", "b", "c"}, "done": {}}
+		if got := boardLayout(project); !reflect.DeepEqual(got, want) {
+			t.Errorf("board layout is %v, want %v", got, want)
+		}
+		if got := columnNames(project.Columns); !slices.Equal(got, []string{"todo", "done"}) {
+			t.Errorf("columns are %v, want todo and done", got)
+		}
+		card, err := s.GetCard(board.cards["b"])
+		if err != nil {
+			t.Fatalf("can't read card: %s", err)
+		}
+		if card.ColumnId != board.todo || card.Order != 2 || card.Version != 1 {
+			t.Errorf("card b is in %s at %d with version %d, want %s at 2 with version 1", card.ColumnId, card.Order, card.Version, board.todo)
+		}
+		projectId, err := s.GetCardProjectId(card.Id)
+		if err != nil || projectId != board.projectId {
+			t.Errorf("card belongs to %q (%v), want %s", projectId, err, board.projectId)
+		}
+	}},
+	{"create at position", func(t *testing.T, s Storage, board *testBoard) {
+		_, err := s.CreateCardAt(&types.CardJson{ColumnId: board.todo, Name: "first"}, 1, board.author)
+		if err != nil {
+			t.Fatalf("can't create card: %s", err)
+		}
+		_, err = s.CreateColumnAt(board.projectId, &types.ColumnJson{Name: "doing"}, 2, board.author)
+		if err != nil {
+			t.Fatalf("can't create column: %s", err)
+		}
+		project := readTestBoard(t, s, board.projectId)
+		if got := boardLayout(project)["todo"]; !slices.Equal(got, []string{"first", "a", "b", "c"}) {
+			t.Errorf("todo holds %v, want first, a, b, c", got)
+		}
+		if got := columnNames(project.Columns); !slices.Equal(got, []string{"todo", "doing", "done"}) {
+			t.Errorf("columns are %v, want todo, doing, done", got)
+		}
+	}},
+	{"update card", func(t *testing.T, s Storage, board *testBoard) {
+		card, err := s.GetCard(board.cards["a"])
+		if err != nil {
+			t.Fatalf("can't read card: %s", err)
+		}
+		update := card.Json()
+		update.Name = "renamed"
+		update.Description = "text"
+		updated, err := s.UpdateCard(update, board.author)
+		if err != nil {
+			t.Fatalf("can't update card: %s", err)
+		}
+		if updated.Version != 2 {
+			t.Errorf("updated card is at version %d, want 2", updated.Version)
+		}
+		stored, err := s.GetCard(card.Id)
+		if err != nil {
+			t.Fatalf("can't read card: %s", err)
+		}
+		if stored.Name != "renamed" || stored.Description != "text" || stored.Version != 2 {
+			t.Errorf("stored card is %q %q at version %d, want renamed text at 2", stored.Name, stored.Description, stored.Version)
+		}
+
+		update.Name = "stale"
+		update.Version = 1
+		_, err = s.UpdateCard(update, board.author)
+		expectConflict(t, err)
+		update.Version = 2
+		_, err = s.UpdateCard(update, board.author)
+		if err != nil {
+			t.Fatalf("can't update card at its version: %s", err)
+		}
+	}},
+	{"update records", func(t *testing.T, s Storage, board *testBoard) {
+		card, err := s.GetCard(board.cards["a"])
+		if err != nil {
+			t.Fatalf("can't read card: %s", err)
+		}
+		update := card.Json()
+		update.Name = "renamed"
+		_, err = s.UpdateCard(update, board.author)
+		if err != nil {
+			t.Fatalf("can't update card: %s", err)
+		}
+		history, err := s.GetCardHistory(card.Id)
+		if err != nil {
+			t.Fatalf("can't read history: %s", err)
+		}
+		if len(history) != 1 {
+			t.Fatalf("card has %d update records, want 1", len(history))
+		}
+		record := history[0]
+		if record.CardId != card.Id || record.CreatedBy != board.author {
+			t.Errorf("record is of card %s by %s, want %s by %s", record.CardId, record.CreatedBy, card.Id, board.author)
+		}
+		found := false
+		for _, change := range record.Changes {
+			if change.Field == "name" {
+				found = string(change.Old) == `"a"` && string(change.New) == `"renamed"`
+			}
+		}
+		if !found {
+			t.Errorf("record has no name change from a to renamed: %+v", record.Changes)
+		}
+
+		reverted, err := s.RevertCard(card.Id, 0, board.author)
+		if err != nil {
+			t.Fatalf("can't revert card: %s", err)
+		}
+		if reverted.Name != "a" {
+			t.Errorf("reverted card is named %q, want a", reverted.Name)
+		}
+	}},
+	{"move card", func(t *testing.T, s Storage, board *testBoard) {
+		_, err := s.MoveCard(board.cards["a"], board.todo, 3, 0, board.author)
+		if err != nil {
+			t.Fatalf("can't move card: %s", err)
+		}
+		moved, err := s.MoveCard(board.cards["b"], board.done, 0, 1, board.author)
+		if err != nil {
+			t.Fatalf("can't move card: %s", err)
+		}
+		if moved.ColumnId != board.done || moved.Version != 2 {
+			t.Errorf("moved card is in %s at version %d, want %s at 2", moved.ColumnId, moved.Version, board.done)
+		}
+		want := map[string][]string{"todo": {"c", "a"}, "done": {"b"}}
+		if got := boardLayout(readTestBoard(t, s, board.projectId)); !reflect.DeepEqual(got, want) {
+			t.Errorf("board layout is %v, want %v", got, want)
+		}
+		_, err = s.MoveCard(board.cards["b"], board.todo, 1, 1, board.author)
+		expectConflict(t, err)
+	}},
+	{"move column", func(t *testing.T, s Storage, board *testBoard) {
+		columns, err := s.MoveColumn(board.done, 1, 1, board.author)
+		if err != nil {
+			t.Fatalf("can't move column: %s", err)
+		}
+		if got := columnNames(columns); !slices.Equal(got, []string{"done", "todo"}) {
+			t.Errorf("moved columns are %v, want done and todo", got)
+		}
+		if got := columnNames(readTestBoard(t, s, board.projectId).Columns); !slices.Equal(got, []string{"done", "todo"}) {
+			t.Errorf("stored columns are %v, want done and todo", got)
+		}
+		_, err = s.MoveColumn(board.done, 2, 1, board.author)
+		expectConflict(t, err)
+	}},
+	{"update column", func(t *testing.T, s Storage, board *testBoard) {
+		err := s.UpdateColumnData(&types.Column{Id: board.todo, Name: "backlog", Version: 1}, board.author)
+		if err != nil {
+			t.Fatalf("can't update column: %s", err)
+		}
+		column, err := s.GetColumn(board.todo)
+		if err != nil {
+			t.Fatalf("can't read column: %s", err)
+		}
+		if column.Name != "backlog" || column.Order != 1 || column.Version != 2 {
+			t.Errorf("column is %q at %d with version %d, want backlog at 1 with version 2", column.Name, column.Order, column.Version)
+		}
+		err = s.UpdateColumnData(&types.Column{Id: board.todo, Name: "stale", Version: 1}, board.author)
+		expectConflict(t, err)
+	}},
+	{"update tag and project", func(t *testing.T, s Storage, board *testBoard) {
+		err := s.UpdateTag(&types.TagJson{Id: board.tag, Name: "feature", Color: "green", Version: 1}, board.author)
+		if err != nil {
+			t.Fatalf("can't update tag: %s", err)
+		}
+		tag, err := s.GetTag(board.tag)
+		if err != nil {
+			t.Fatalf("can't read tag: %s", err)
+		}
+		if tag.Name != "feature" || tag.Color != "green" || tag.Version != 2 {
+			t.Errorf("tag is %q %q at version %d, want feature green at 2", tag.Name, tag.Color, tag.Version)
+		}
+		err = s.UpdateTag(&types.TagJson{Id: board.tag, Name: "stale", Version: 1}, board.author)
+		expectConflict(t, err)
+
+		err = s.UpdateProjectData(board.projectId, "renamed", 1, board.author)
+		if err != nil {
+			t.Fatalf("can't update project: %s", err)
+		}
+		project := readTestBoard(t, s, board.projectId)
+		if project.Name != "renamed" || project.Version != 2 {
+			t.Errorf("project is %q at version %d, want renamed at 2", project.Name, project.Version)
+		}
+		err = s.UpdateProjectData(board.projectId, "stale", 1, board.author)
+		expectConflict(t, err)
+	}},
+	{"tag linking", func(t *testing.T, s Storage, board *testBoard) {
+		cardId := board.cards["a"]
+		err := s.CreateCardTags(cardId, board.tag)
+		if err != nil {
+			t.Fatalf("can't link tag: %s", err)
+		}
+		tags, err := s.GetTagsByCard(cardId)
+		if err != nil {
+			t.Fatalf("can't read card tags: %s", err)
+		}
+		if len(tags) != 1 || tags[0].Id != board.tag {
+			t.Errorf("card has tags %+v, want %s", tags, board.tag)
+		}
+		card := readTestBoard(t, s, board.projectId).Columns[0].Cards[0]
+		if !slices.Equal(card.TagIds, []string{board.tag}) {
+			t.Errorf("board card has tag ids %v, want %s", card.TagIds, board.tag)
+		}
+
+		err = s.RemoveCardTags(cardId, board.tag)
+		if err != nil {
+			t.Fatalf("can't unlink tag: %s", err)
+		}
+		tags, err = s.GetTagsByCard(cardId)
+		if err != nil {
+			t.Fatalf("can't read card tags: %s", err)
+		}
+		if len(tags) != 0 {
+			t.Errorf("unlinked card has tags %+v", tags)
+		}
+	}},
+	{"rolled back transaction", func(t *testing.T, s Storage, board *testBoard) {
+		failure := errors.New("failure")
+		err := s.Tx(func(tx Storage) error {
+			_, err := tx.MoveCard(board.cards["a"], board.done, 0, 0, board.author)
+			if err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("transaction returned %v, want its own error", err)
+		}
+		want := map[string][]string{"todo": {"a", "b", "c"}, "done": {}}
+		if got := boardLayout(readTestBoard(t, s, board.projectId)); !reflect.DeepEqual(got, want) {
+			t.Errorf("board layout is %v after the rollback, want %v", got, want)
+		}
+	}},
+	{"update comment", func(t *testing.T, s Storage, board *testBoard) {
+		comment, err := s.CreateComment(board.cards["a"], "", "first", board.author)
+		if err != nil {
+			t.Fatalf("can't create comment: %s", err)
+		}
+		if comment.Version != 1 {
+			t.Fatalf("created comment is at version %d, want 1", comment.Version)
+		}
+		updated, err := s.UpdateComment(comment.Id, "second", 1, board.author)
+		if err != nil {
+			t.Fatalf("can't update comment: %s", err)
+		}
+		if updated.Body != "second" || updated.Version != 2 {
+			t.Errorf("updated comment is %q at version %d, want second at 2", updated.Body, updated.Version)
+		}
+		_, err = s.UpdateComment(comment.Id, "stale", 1, board.author)
+		expectConflict(t, err)
+		stored, err := s.GetComment(comment.Id)
+		if err != nil {
+			t.Fatalf("can't read comment: %s", err)
+		}
+		if stored.Body != "second" || stored.Version != 2 {
+			t.Errorf("comment is %q at version %d after a stale update, want second at 2", stored.Body, stored.Version)
+		}
+		edits, err := s.GetCommentEdits(comment.Id)
+		if err != nil {
+			t.Fatalf("can't read comment edits: %s", err)
+		}
+		if len(edits) != 1 || edits[0].Body != "first" {
+			t.Errorf("comment edits are %+v, want only the first body", edits)
+		}
+		_, err = s.UpdateComment("missing", "body", 0, board.author)
+		expectNotFound(t, err)
+	}},
+	{"checklist versions", func(t *testing.T, s Storage, board *testBoard) {
+		first, err := s.CreateChecklist(board.cards["a"], "first", 0, board.author)
+		if err != nil {
+			t.Fatalf("can't create checklist: %s", err)
+		}
+		_, err = s.CreateChecklist(board.cards["a"], "second", 0, board.author)
+		if err != nil {
+			t.Fatalf("can't create checklist: %s", err)
+		}
+		renamed, err := s.RenameChecklist(first.Id, "renamed", 1, board.author)
+		if err != nil {
+			t.Fatalf("can't rename checklist: %s", err)
+		}
+		if renamed.Name != "renamed" || renamed.Version != 2 {
+			t.Errorf("renamed checklist is %q at version %d, want renamed at 2", renamed.Name, renamed.Version)
+		}
+		_, err = s.RenameChecklist(first.Id, "stale", 1, board.author)
+		expectConflict(t, err)
+		_, err = s.MoveChecklist(first.Id, 2, 1, board.author)
+		expectConflict(t, err)
+		moved, err := s.MoveChecklist(first.Id, 2, 2, board.author)
+		if err != nil {
+			t.Fatalf("can't move checklist: %s", err)
+		}
+		if moved.Order != 2 || moved.Version != 3 {
+			t.Errorf("moved checklist is at %d and version %d, want 2 and 3", moved.Order, moved.Version)
+		}
+
+		item, err := s.CreateChecklistItem(first.Id, "item", 0, board.author)
+		if err != nil {
+			t.Fatalf("can't create checklist item: %s", err)
+		}
+		if item.Version != 1 {
+			t.Fatalf("created item is at version %d, want 1", item.Version)
+		}
+		item, err = s.ToggleChecklistItem(item.Id, board.author)
+		if err != nil {
+			t.Fatalf("can't toggle checklist item: %s", err)
+		}
+		if !item.Done || item.Version != 2 {
+			t.Errorf("toggled item is done %t at version %d, want done at 2", item.Done, item.Version)
+		}
+		_, err = s.UpdateChecklistItem(item.Id, "stale", false, 1, board.author)
+		expectConflict(t, err)
+		_, err = s.MoveChecklistItem(item.Id, 1, 1, board.author)
+		expectConflict(t, err)
+		item, err = s.UpdateChecklistItem(item.Id, "edited", true, 2, board.author)
+		if err != nil {
+			t.Fatalf("can't update checklist item: %s", err)
+		}
+		if item.Text != "edited" || item.Version != 3 {
+			t.Errorf("updated item is %q at version %d, want edited at 3", item.Text, item.Version)
+		}
+		_, err = s.UpdateChecklistItem("missing", "text", false, 1, board.author)
+		expectNotFound(t, err)
+	}},
+	{"last owner", func(t *testing.T, s Storage, board *testBoard) {
+		var lastOwner LastOwnerError
+		err := s.UpdateProjectMember(board.projectId, board.author, types.RoleAdmin, board.author)
+		if !errors.As(err, &lastOwner) {
+			t.Fatalf("demoting the only owner returned %v, want LastOwnerError", err)
+		}
+		err = s.RemoveProjectMember(board.projectId, board.author)
+		if !errors.As(err, &lastOwner) {
+			t.Fatalf("removing the only owner returned %v, want LastOwnerError", err)
+		}
+
+		other, err := s.CreateUser(fmt.Sprintf("owner-%d", time.Now().UnixNano()), "hash")
+		if err != nil {
+			t.Fatalf("can't create user: %s", err)
+		}
+		err = s.AddProjectMember(board.projectId, other.Id, types.RoleOwner, board.author)
+		if err != nil {
+			t.Fatalf("can't add owner: %s", err)
+		}
+		err = s.UpdateProjectMember(board.projectId, board.author, types.RoleAdmin, board.author)
+		if err != nil {
+			t.Fatalf("can't demote one of two owners: %s", err)
+		}
+		err = s.RemoveProjectMember(board.projectId, other.Id)
+		if !errors.As(err, &lastOwner) {
+			t.Fatalf("removing the remaining owner returned %v, want LastOwnerError", err)
+		}
+		owners, err := s.CountProjectOwners(board.projectId)
+		if err != nil || owners != 1 {
+			t.Fatalf("project has %d owners (%v), want 1", owners, err)
+		}
+		err = s.RemoveProjectMember(board.projectId, "missing")
+		var noEffect NoEffect
+		if !errors.As(err, &noEffect) {
+			t.Errorf("removing a missing member returned %v, want NoEffect", err)
+		}
+	}},
+	{"not found", func(t *testing.T, s Storage, board *testBoard) {
+		_, err := s.GetProject("missing")
+		expectNotFound(t, err)
+		_, err = s.GetColumn("missing")
+		expectNotFound(t, err)
+		_, err = s.GetCard("missing")
+		expectNotFound(t, err)
+		_, err = s.GetTag("missing")
+		expectNotFound(t, err)
+		_, err = s.UpdateCard(&types.CardJson{Id: "missing", ColumnId: board.todo, Name: "x"}, board.author)
+		expectNotFound(t, err)
+		_, err = s.MoveCard("missing", board.todo, 1, 0, board.author)
+		expectNotFound(t, err)
+
+		err = s.DeleteCard(board.cards["a"])
+		if err != nil {
+			t.Fatalf("can't delete card: %s", err)
+		}
+		_, err = s.GetCard(board.cards["a"])
+		expectNotFound(t, err)
+	}},
+}
+
+func TestStorageConformance(t *testing.T) {
+	for name, open := range testStorages() {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range storageCases {
+				t.Run(tc.name, func(t *testing.T) {
+					s := open(t)
+					tc.run(t, s, createTestBoard(t, s))
+				})
+			}
+		})
+	}
+}
@@ -5,19 +5,14 @@ import (
 	"utils"
 )
 
-func CreateTags(agent *Agent, projectId string, tags *[]types.TagJson) ([]types.TagJson, error) {
-	stmt, err := agent.Prepare(`CALL create_tag(?, ?, ?, ?, ?);`)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
+func CreateTags(agent *Agent, projectId string, tags *[]types.TagJson, author string) ([]types.TagJson, error) {
 	var tagErr error
 	createdTags := make([]types.TagJson, len(*tags))
 	for idx, tag := range *tags {
 		newTag := tag
 		newTag.Id = utils.GetUUID()
 		createdTags[idx] = newTag
-		_, err := stmt.Exec(projectId, newTag.Id, tag.Name, tag.Color, "placeholder")
+		_, err := agent.Exec(`CALL create_tag(?, ?, ?, ?, ?);`, projectId, newTag.Id, tag.Name, tag.Color, author)
 		if err != nil {
 			tagErr = err
 		}
@@ -28,6 +23,36 @@ func CreateTags(agent *Agent, projectId string, tags *[]types.TagJson) ([]types.
 	return createdTags, nil
 }
 
+// UpdateTag writes the name and color of the tag, a tag.Version other than 0 is the one the tag is expected at.
+func UpdateTag(agent *Agent, tag *types.TagJson, author string) error {
+	if tag.Version != 0 {
+		_, err := GetTag(agent, tag.Id)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := agent.Exec(`CALL update_tag(?, ?, ?, ?, ?);`, tag.Id, tag.Name, tag.Color, author, tag.Version)
+	if err != nil {
+		return err
+	}
+	return checkVersioned(res, "tag", tag.Id, tag.Version)
+}
+
+func DeleteTag(agent *Agent, id string) error {
+	res, err := agent.Exec("DELETE FROM Tags WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
+
 type NoEffect struct{}
 
 func (NoEffect) Error() string {
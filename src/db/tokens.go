@@ -0,0 +1,120 @@
+package db_driver
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+	"utils"
+)
+
+const accessTokenColumns = `t.id, t.user_id, t.name, t.scopes, t.created_at, t.last_used_at, t.revoked_at`
+
+func readAccessToken(columns []string, values []sql.RawBytes) (*types.AccessToken, error) {
+	var token types.AccessToken
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			token.Id = string(col)
+		case "user_id":
+			token.UserId = string(col)
+		case "name":
+			token.Name = string(col)
+		case "scopes":
+			token.Scopes = strings.Fields(string(col))
+		case "created_at", "last_used_at", "revoked_at":
+			if len(col) == 0 {
+				continue
+			}
+			val, err := strconv.Atoi(string(col))
+			if err != nil {
+				return nil, err
+			}
+			switch columns[i] {
+			case "created_at":
+				token.CreatedAt = val
+			case "last_used_at":
+				token.LastUsedAt = val
+			case "revoked_at":
+				token.RevokedAt = val
+			}
+		}
+	}
+	return &token, nil
+}
+
+func CreateAccessToken(agent *Agent, userId string, name string, tokenHash string, scopes []string) (*types.AccessToken, error) {
+	stmt, err := agent.Prepare(`
+	INSERT INTO AccessTokens
+		(id, user_id, name, token_hash, scopes, created_at, last_used_at, revoked_at)
+	VALUES
+		(?, ?, ?, ?, ?, ?, 0, 0);`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	token := types.AccessToken{Id: utils.GetUUID(), UserId: userId, Name: name, Scopes: scopes, CreatedAt: int(time.Now().Unix())}
+	_, err = stmt.Exec(token.Id, token.UserId, token.Name, tokenHash, strings.Join(scopes, " "), token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func GetAccessTokens(agent *Agent, userId string) ([]types.AccessToken, error) {
+	var tokens []types.AccessToken
+	columns, values, err := readMultiRow(agent, userId, `
+	SELECT `+accessTokenColumns+` FROM AccessTokens t WHERE t.user_id = ? ORDER BY t.created_at;`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range values {
+		token, err := readAccessToken(columns, row)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, nil
+}
+
+// GetAccessTokenUser resolves a hashed access token to the token and its owner, revoked tokens are treated as missing.
+func GetAccessTokenUser(agent *Agent, tokenHash string) (*types.AccessToken, *types.User, error) {
+	columns, values, err := readOneRow(agent, tokenHash, `
+	SELECT `+accessTokenColumns+` FROM AccessTokens t WHERE t.token_hash = ? AND t.revoked_at = 0;`)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := readAccessToken(columns, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := GetUserById(agent, token.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, user, nil
+}
+
+func TouchAccessToken(agent *Agent, id string) error {
+	_, err := agent.Exec("UPDATE AccessTokens SET last_used_at = ? WHERE id = ?;", time.Now().Unix(), id)
+	return err
+}
+
+func RevokeAccessToken(agent *Agent, userId string, id string) error {
+	res, err := agent.Exec(`
+	UPDATE AccessTokens SET revoked_at = ?
+	WHERE id = ? AND user_id = ? AND revoked_at = 0;`, time.Now().Unix(), id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
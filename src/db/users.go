@@ -0,0 +1,115 @@
+package db_driver
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+	"types"
+	"utils"
+)
+
+func readUser(columns []string, values []sql.RawBytes) (*types.User, error) {
+	var user types.User
+	for i, col := range values {
+		switch columns[i] {
+		case "id":
+			user.Id = string(col)
+		case "login":
+			user.Login = string(col)
+		case "password_hash":
+			user.PasswordHash = string(col)
+		case "created_at":
+			val, err := strconv.Atoi(string(col))
+			if err != nil {
+				return nil, err
+			}
+			user.CreatedAt = val
+		case "updated_at":
+			val, err := strconv.Atoi(string(col))
+			if err != nil {
+				return nil, err
+			}
+			user.UpdatedAt = val
+		}
+	}
+	return &user, nil
+}
+
+func CreateUser(agent *Agent, login string, passwordHash string) (*types.User, error) {
+	stmt, err := agent.Prepare(`
+	INSERT INTO Users
+		(id, login, password_hash, created_at, updated_at)
+	VALUES
+		(?, ?, ?, ?, ?);`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	now := int(time.Now().Unix())
+	user := types.User{Id: utils.GetUUID(), Login: login, PasswordHash: passwordHash, CreatedAt: now, UpdatedAt: now}
+	_, err = stmt.Exec(user.Id, user.Login, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func GetUserById(agent *Agent, id string) (*types.User, error) {
+	columns, values, err := readOneRow(agent, id, `
+	SELECT id, login, password_hash, created_at, updated_at FROM Users WHERE id = ?;`)
+	if err != nil {
+		return nil, err
+	}
+	return readUser(columns, values)
+}
+
+func GetUserByLogin(agent *Agent, login string) (*types.User, error) {
+	columns, values, err := readOneRow(agent, login, `
+	SELECT id, login, password_hash, created_at, updated_at FROM Users WHERE login = ?;`)
+	if err != nil {
+		return nil, err
+	}
+	return readUser(columns, values)
+}
+
+func CreateSession(agent *Agent, userId string, tokenHash string, expiresAt int) error {
+	stmt, err := agent.Prepare(`
+	INSERT INTO Sessions
+		(token_hash, user_id, created_at, expires_at)
+	VALUES
+		(?, ?, ?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(tokenHash, userId, time.Now().Unix(), expiresAt)
+	return err
+}
+
+// GetSessionUser resolves a hashed session token to its user, expired sessions are treated as missing.
+func GetSessionUser(agent *Agent, tokenHash string) (*types.User, error) {
+	columns, values, err := readOneRowArgs(agent, fmt.Sprintf("item with id %s", tokenHash), `
+	SELECT u.id, u.login, u.password_hash, u.created_at, u.updated_at
+	FROM Sessions s JOIN Users u ON u.id = s.user_id
+	WHERE s.token_hash = ? AND s.expires_at > ?;`, tokenHash, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	return readUser(columns, values)
+}
+
+func DeleteSession(agent *Agent, tokenHash string) error {
+	res, err := agent.Exec("DELETE FROM Sessions WHERE token_hash = ?;", tokenHash)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NoEffect{}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"db_driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"types"
+)
+
+// errForbidden marks role checks that failed, as opposed to failed lookups.
+var errForbidden = errors.New("forbidden")
+
+// checkRole returns an error wrapping errForbidden when the user lacks the given role in the project.
+func checkRole(db db_driver.Storage, projectId string, userId string, role string) error {
+	member, err := db.GetProjectMember(projectId, userId)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if errors.As(err, &nfe) {
+			return fmt.Errorf("%w: not a member of project %s", errForbidden, projectId)
+		}
+		return err
+	}
+	if types.RoleRank(member.Role) < types.RoleRank(role) {
+		return fmt.Errorf("%w: role %s is required, have %s", errForbidden, role, member.Role)
+	}
+	return nil
+}
+
+// authorize checks that the current user has at least the given role in the project,
+// writing an error response and returning false otherwise.
+func authorize(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, role string) bool {
+	err := checkRole(db, projectId, getUser(r).Id, role)
+	if err != nil {
+		if errors.Is(err, errForbidden) {
+			forbidden(w, r, err)
+			return false
+		}
+		badResponse(w, r, err)
+		return false
+	}
+	return true
+}
+
+func authorizeBy(db db_driver.Storage, w http.ResponseWriter, r *http.Request, getProjectId func(string) (string, error), id string, role string) (string, bool) {
+	projectId, err := getProjectId(id)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if errors.As(err, &nfe) {
+			notFound(w, r, err)
+			return "", false
+		}
+		badResponse(w, r, err)
+		return "", false
+	}
+	return projectId, authorize(db, w, r, projectId, role)
+}
+
+// authorizePath authorizes the item named by the path parameter. Nested /api/v1 routes also name the project,
+// an item of another project is reported as missing there.
+func authorizePath(db db_driver.Storage, w http.ResponseWriter, r *http.Request, getProjectId func(string) (string, error), param string, role string) (string, string, bool) {
+	id := r.PathValue(param)
+	pathProjectId := r.PathValue("projectId")
+	if pathProjectId == "" {
+		projectId, ok := authorizeBy(db, w, r, getProjectId, id, role)
+		return projectId, id, ok
+	}
+	projectId, err := getProjectId(id)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if !errors.As(err, &nfe) {
+			badResponse(w, r, err)
+			return "", "", false
+		}
+	}
+	if err != nil || projectId != pathProjectId {
+		notFound(w, r, fmt.Errorf("item with id %s was not found in project %s", id, pathProjectId))
+		return "", "", false
+	}
+	return projectId, id, authorize(db, w, r, projectId, role)
+}
+
+func authorizeCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, cardId string, role string) (string, bool) {
+	return authorizeBy(db, w, r, db.GetCardProjectId, cardId, role)
+}
+
+func authorizeColumn(db db_driver.Storage, w http.ResponseWriter, r *http.Request, columnId string, role string) (string, bool) {
+	return authorizeBy(db, w, r, db.GetColumnProjectId, columnId, role)
+}
+
+func authorizeTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, tagId string, role string) (string, bool) {
+	return authorizeBy(db, w, r, db.GetTagProjectId, tagId, role)
+}
+
+// checkColumnInProject guards against moving or creating things into a column of another project.
+func checkColumnInProject(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, columnId string) bool {
+	columnProjectId, err := db.GetColumnProjectId(columnId)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if errors.As(err, &nfe) {
+			notFound(w, r, err)
+			return false
+		}
+		badResponse(w, r, err)
+		return false
+	}
+	if columnProjectId != projectId {
+		badRequest(w, r, fmt.Errorf("column %s does not belong to project %s", columnId, projectId))
+		return false
+	}
+	return true
+}
+
+func checkTagInProject(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, tagId string) bool {
+	tagProjectId, err := db.GetTagProjectId(tagId)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if errors.As(err, &nfe) {
+			notFound(w, r, err)
+			return false
+		}
+		badResponse(w, r, err)
+		return false
+	}
+	if tagProjectId != projectId {
+		badRequest(w, r, fmt.Errorf("tag %s does not belong to project %s", tagId, projectId))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"types"
+)
+
+const (
+	actionCreateCard    = "create_card"
+	actionUpdateCard    = "update_card"
+	actionMoveCard      = "move_card"
+	actionDeleteCard    = "delete_card"
+	actionLinkTag       = "link_tag"
+	actionUnlinkTag     = "unlink_tag"
+	actionAssignCard    = "assign_card"
+	actionUnassignCard  = "unassign_card"
+	actionCreateColumn  = "create_column"
+	actionUpdateColumn  = "update_column"
+	actionMoveColumn    = "move_column"
+	actionDeleteColumn  = "delete_column"
+	actionCreateTag     = "create_tag"
+	actionUpdateTag     = "update_tag"
+	actionDeleteTag     = "delete_tag"
+	actionUpdateProject = "update_project"
+)
+
+// postRequest is a single typed board command, only the payload matching the type is read.
+// Position is used by inserts and moves, it starts at 1 like draw_order and 0 appends.
+// ExpectedVersion makes updates fail with 409 when the item was changed since that version.
+// AssigneeId is the project member assigned to or unassigned from the card.
+// Updates apply the raw card, column, tag and project payloads as merge patches over the stored item like PATCH does,
+// so fields left out keep their values.
+type postRequest struct {
+	ActionType      string           `json:"type"`
+	Position        int              `json:"position"`
+	ExpectedVersion *int             `json:"expectedVersion"`
+	AssigneeId      string           `json:"assigneeId"`
+	TagPayload      types.TagJson    `json:"tag"`
+	CardPayload     types.CardJson   `json:"card"`
+	ColumnPayload   types.ColumnJson `json:"column"`
+	tagPatch        json.RawMessage
+	cardPatch       json.RawMessage
+	columnPatch     json.RawMessage
+	projectPatch    json.RawMessage
+}
+
+func (req *postRequest) UnmarshalJSON(data []byte) error {
+	type plainRequest postRequest
+	var patches struct {
+		Tag     json.RawMessage `json:"tag"`
+		Card    json.RawMessage `json:"card"`
+		Column  json.RawMessage `json:"column"`
+		Project json.RawMessage `json:"project"`
+	}
+	err := json.Unmarshal(data, &patches)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, (*plainRequest)(req))
+	if err != nil {
+		return err
+	}
+	req.tagPatch, req.cardPatch, req.columnPatch = patches.Tag, patches.Card, patches.Column
+	req.projectPatch = patches.Project
+	return nil
+}
+
+// patchPayload applies the raw payload of an update action to value holding the stored state.
+func patchPayload(value any, patch json.RawMessage) error {
+	if len(patch) == 0 {
+		return nil
+	}
+	err := mergePatch(value, patch)
+	if err != nil {
+		return actionError{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+type actionResult struct {
+	ActionType string             `json:"type"`
+	Card       *types.CardJson    `json:"card,omitempty"`
+	Column     *types.ColumnJson  `json:"column,omitempty"`
+	Columns    []types.ColumnJson `json:"columns,omitempty"`
+	Tag        *types.TagJson     `json:"tag,omitempty"`
+	Project    *projectPayload    `json:"project,omitempty"`
+}
+
+// actionError is returned for actions that can't be applied because of the payload.
+type actionError struct {
+	status int
+	err    error
+}
+
+func (e actionError) Error() string {
+	return e.err.Error()
+}
+
+// actionAccess returns the scope and project role an action requires.
+func actionAccess(actionType string) (string, string, error) {
+	switch actionType {
+	case actionCreateCard, actionUpdateCard, actionMoveCard, actionDeleteCard, actionLinkTag, actionUnlinkTag,
+		actionAssignCard, actionUnassignCard:
+		return "cards:write", types.RoleEditor, nil
+	case actionCreateColumn, actionUpdateColumn, actionMoveColumn, actionDeleteColumn:
+		return "columns:write", types.RoleEditor, nil
+	case actionCreateTag, actionUpdateTag, actionDeleteTag:
+		return "tags:write", types.RoleEditor, nil
+	case actionUpdateProject:
+		return "projects:write", types.RoleAdmin, nil
+	}
+	return "", "", actionError{http.StatusBadRequest, fmt.Errorf("unknown action type %q", actionType)}
+}
+
+func checkInProject(getProjectId func(string) (string, error), id string, projectId string) error {
+	itemProjectId, err := getProjectId(id)
+	if err != nil {
+		return err
+	}
+	if itemProjectId != projectId {
+		return actionError{http.StatusBadRequest, fmt.Errorf("item %s does not belong to project %s", id, projectId)}
+	}
+	return nil
+}
+
+// expectedVersion is the version the writes of the action are conditioned on, 0 writes unconditionally.
+func (req *postRequest) expectedVersion() int {
+	if req.ExpectedVersion == nil {
+		return 0
+	}
+	return *req.ExpectedVersion
+}
+
+// staleAction turns a write that found the item at another version into the 409 of the action,
+// which carries the current version.
+func staleAction(err error, thing string, id string, load func() (any, int, error)) error {
+	var conflict db_driver.VersionConflict
+	if !errors.As(err, &conflict) {
+		return err
+	}
+	_, version, loadErr := load()
+	if loadErr != nil {
+		return loadErr
+	}
+	return staleVersionError{thing, id, version}
+}
+
+// applyAction runs one action against the project, tx is expected to be bound to a transaction.
+func applyAction(tx db_driver.Storage, projectId string, req *postRequest, author string) (*actionResult, error) {
+	result := actionResult{ActionType: req.ActionType}
+	card := req.CardPayload
+	column := req.ColumnPayload
+	tag := req.TagPayload
+	switch req.ActionType {
+	case actionCreateCard:
+		err := checkInProject(tx.GetColumnProjectId, card.ColumnId, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = checkCardDates(&card)
+		if err != nil {
+			return nil, err
+		}
+		for _, tagId := range card.TagIds {
+			err = checkInProject(tx.GetTagProjectId, tagId, projectId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		newCard, err := tx.CreateCardAt(&card, req.Position, author)
+		if err != nil {
+			return nil, err
+		}
+		result.Card = newCard
+	case actionUpdateCard:
+		err := checkInProject(tx.GetCardProjectId, card.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := readCardJson(tx, card.Id)
+		if err != nil {
+			return nil, err
+		}
+		err = patchPayload(stored, req.cardPatch)
+		if err != nil {
+			return nil, err
+		}
+		stored.Id = card.Id
+		stored.Version = req.expectedVersion()
+		card = *stored
+		err = checkInProject(tx.GetColumnProjectId, card.ColumnId, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = checkCardDates(&card)
+		if err != nil {
+			return nil, err
+		}
+		newCard, err := tx.UpdateCard(&card, author)
+		if err != nil {
+			return nil, staleAction(err, "card", card.Id, cardVersion(tx, card.Id))
+		}
+		result.Card = newCard
+	case actionMoveCard:
+		err := checkInProject(tx.GetCardProjectId, card.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = checkInProject(tx.GetColumnProjectId, card.ColumnId, projectId)
+		if err != nil {
+			return nil, err
+		}
+		newCard, err := tx.MoveCard(card.Id, card.ColumnId, req.Position, req.expectedVersion(), author)
+		if err != nil {
+			return nil, staleAction(err, "card", card.Id, cardVersion(tx, card.Id))
+		}
+		result.Card = newCard
+	case actionDeleteCard:
+		err := checkInProject(tx.GetCardProjectId, card.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteCard(card.Id)
+		if err != nil {
+			return nil, err
+		}
+	case actionLinkTag, actionUnlinkTag:
+		err := checkInProject(tx.GetCardProjectId, card.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = checkInProject(tx.GetTagProjectId, tag.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		if req.ActionType == actionLinkTag {
+			err = tx.CreateCardTags(card.Id, tag.Id)
+		} else {
+			err = tx.RemoveCardTags(card.Id, tag.Id)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case actionAssignCard, actionUnassignCard:
+		err := checkInProject(tx.GetCardProjectId, card.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		if req.ActionType == actionAssignCard {
+			err = checkAssignee(tx, projectId, req.AssigneeId)
+			if err == nil {
+				err = tx.AssignCard(card.Id, req.AssigneeId, author)
+			}
+		} else {
+			err = tx.UnassignCard(card.Id, req.AssigneeId)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case actionCreateColumn:
+		newColumn, err := tx.CreateColumnAt(projectId, &column, req.Position, author)
+		if err != nil {
+			return nil, err
+		}
+		result.Column = newColumn
+	case actionUpdateColumn:
+		err := checkInProject(tx.GetColumnProjectId, column.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := tx.GetColumn(column.Id)
+		if err != nil {
+			return nil, err
+		}
+		patched := stored.Json()
+		err = patchPayload(patched, req.columnPatch)
+		if err != nil {
+			return nil, err
+		}
+		colData := types.Column{Id: column.Id, Name: patched.Name, Order: patched.Order, ProjectId: projectId,
+			Version: req.expectedVersion()}
+		err = tx.UpdateColumnData(&colData, author)
+		if err != nil {
+			return nil, staleAction(err, "column", column.Id, columnVersion(tx, column.Id))
+		}
+		newColumn, err := tx.GetColumn(column.Id)
+		if err != nil {
+			return nil, err
+		}
+		result.Column = newColumn.Json()
+	case actionMoveColumn:
+		err := checkInProject(tx.GetColumnProjectId, column.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		result.Columns, err = tx.MoveColumn(column.Id, req.Position, req.expectedVersion(), author)
+		if err != nil {
+			return nil, staleAction(err, "column", column.Id, columnVersion(tx, column.Id))
+		}
+	case actionDeleteColumn:
+		err := checkInProject(tx.GetColumnProjectId, column.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteColumn(column.Id)
+		if err != nil {
+			return nil, err
+		}
+	case actionCreateTag:
+		tags := []types.TagJson{tag}
+		newTags, err := tx.CreateTags(projectId, &tags, author)
+		if err != nil {
+			return nil, err
+		}
+		result.Tag = &newTags[0]
+	case actionUpdateTag:
+		err := checkInProject(tx.GetTagProjectId, tag.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := tx.GetTag(tag.Id)
+		if err != nil {
+			return nil, err
+		}
+		patched := stored.Json()
+		err = patchPayload(patched, req.tagPatch)
+		if err != nil {
+			return nil, err
+		}
+		patched.Id = tag.Id
+		patched.Version = req.expectedVersion()
+		err = tx.UpdateTag(patched, author)
+		if err != nil {
+			return nil, staleAction(err, "tag", tag.Id, tagVersion(tx, tag.Id))
+		}
+		newTag, err := tx.GetTag(tag.Id)
+		if err != nil {
+			return nil, err
+		}
+		result.Tag = newTag.Json()
+	case actionDeleteTag:
+		err := checkInProject(tx.GetTagProjectId, tag.Id, projectId)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteTag(tag.Id)
+		if err != nil {
+			return nil, err
+		}
+	case actionUpdateProject:
+		project, err := tx.GetProject(projectId)
+		if err != nil {
+			return nil, err
+		}
+		patched := projectPayload{projectId, project.Name}
+		err = patchPayload(&patched, req.projectPatch)
+		if err != nil {
+			return nil, err
+		}
+		patched.Id = projectId
+		err = tx.UpdateProjectData(projectId, patched.Name, req.expectedVersion(), author)
+		if err != nil {
+			return nil, staleAction(err, "project", projectId, projectVersion(tx, projectId))
+		}
+		result.Project = &patched
+	default:
+		return nil, actionError{http.StatusBadRequest, fmt.Errorf("unknown action type %q", req.ActionType)}
+	}
+	return &result, nil
+}
+
+// runAction applies a single action in its own transaction and publishes it once committed.
+func runAction(db db_driver.Storage, projectId string, req *postRequest, author string) (*actionResult, error) {
+	var result *actionResult
+	err := db.Tx(func(tx db_driver.Storage) error {
+		var err error
+		result, err = applyAction(tx, projectId, req, author)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishAction(db, projectId, req, result, author)
+	return result, nil
+}
+
+func GetActionHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [POST] Received an action request from %s\n", projectId, r.Host)
+		decoder := json.NewDecoder(r.Body)
+		var reqData postRequest
+		err := decoder.Decode(&reqData)
+		if err != nil {
+			if err != io.EOF {
+				badRequest(w, r, err)
+				return
+			}
+		}
+		scope, role, err := actionAccess(reqData.ActionType)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		if !checkScope(w, r, scope) || !authorize(db, w, r, projectId, role) {
+			return
+		}
+		result, err := runAction(db, projectId, &reqData, getUser(r).Id)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, string(data))
+		log.Printf("[%s] Applied %s action\n", projectId, reqData.ActionType)
+	}
+	return handler
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"db_driver"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"types"
+	"utils"
+)
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	scopesKey
+	requestIdKey
+)
+
+// accessTokenPrefix tells personal access tokens apart from session tokens.
+const accessTokenPrefix = "mkp_"
+
+var accessTokenResources = []string{"projects", "columns", "cards", "tags", "members", "comments"}
+
+func isStreamRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+func getBearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		// Browsers can't set headers on EventSource and WebSocket connections, so those may pass the token in the query.
+		if isStreamRequest(r) {
+			return r.URL.Query().Get("access_token")
+		}
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func getUser(r *http.Request) *types.User {
+	user, _ := r.Context().Value(userKey).(*types.User)
+	return user
+}
+
+// getScopes returns the scopes of the access token used for the request, nil for sessions.
+func getScopes(r *http.Request) []string {
+	scopes, _ := r.Context().Value(scopesKey).([]string)
+	return scopes
+}
+
+func isValidScope(scope string) bool {
+	resource, access, found := strings.Cut(scope, ":")
+	if !found || (access != "read" && access != "write") {
+		return false
+	}
+	return slices.Contains(accessTokenResources, resource)
+}
+
+func authenticateAccessToken(db db_driver.Storage, token string) (*types.User, []string, error) {
+	accessToken, user, err := db.GetAccessTokenUser(utils.HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = db.TouchAccessToken(accessToken.Id)
+	if err != nil {
+		log.Printf("[%s] Failed to update token last use: %s\n", accessToken.Id, err)
+	}
+	return user, accessToken.Scopes, nil
+}
+
+// Authenticated resolves the bearer session or access token and stores its user in the request context,
+// requests without a valid token never reach the handler.
+func Authenticated(db db_driver.Storage, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := getBearerToken(r)
+		if token == "" {
+			unauthorized(w, r, fmt.Errorf("bearer token is missing"))
+			return
+		}
+		var user *types.User
+		var scopes []string
+		var err error
+		if strings.HasPrefix(token, accessTokenPrefix) {
+			user, scopes, err = authenticateAccessToken(db, token)
+		} else {
+			user, err = db.GetSessionUser(utils.HashToken(token))
+		}
+		if err != nil {
+			var nfe db_driver.NotFoundError
+			if errors.As(err, &nfe) {
+				unauthorized(w, r, fmt.Errorf("token is invalid, expired or revoked"))
+				return
+			}
+			badResponse(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userKey, user)
+		if scopes != nil {
+			ctx = context.WithValue(ctx, scopesKey, scopes)
+		}
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// RequireScope limits access tokens to the resource, reading methods need the read scope
+// and everything else needs the write scope. Sessions are not limited.
+func RequireScope(resource string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope := resource + ":write"
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+		if !checkScope(w, r, scope) {
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// SessionOnly rejects access tokens, so a leaked token can't be used to mint new ones.
+func SessionOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if getScopes(r) != nil {
+			forbidden(w, r, fmt.Errorf("access tokens can't be used here, log in instead"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func hasScope(r *http.Request, scope string) bool {
+	scopes := getScopes(r)
+	return scopes == nil || slices.Contains(scopes, scope)
+}
+
+// checkScope is RequireScope for handlers that only know the needed scope after reading the payload.
+func checkScope(w http.ResponseWriter, r *http.Request, scope string) bool {
+	if !hasScope(r, scope) {
+		forbidden(w, r, fmt.Errorf("access token is missing %s scope", scope))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"types"
+)
+
+const maxBatchOperations = 100
+
+const (
+	operationApplied = "applied"
+	operationFailed  = "failed"
+	operationSkipped = "skipped"
+)
+
+type batchRequest struct {
+	Operations []postRequest `json:"operations"`
+}
+
+type batchOperationResult struct {
+	Index      int           `json:"index"`
+	ActionType string        `json:"type"`
+	Status     string        `json:"status"`
+	Code       string        `json:"code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Result     *actionResult `json:"result,omitempty"`
+}
+
+type batchResponse struct {
+	Committed bool                   `json:"committed"`
+	Results   []batchOperationResult `json:"results"`
+}
+
+// runBatch applies every operation through one transaction storage and stops at the first failure,
+// the operations after it are reported as skipped.
+func runBatch(tx db_driver.Storage, projectId string, operations []postRequest, author string) ([]batchOperationResult, error) {
+	results := make([]batchOperationResult, len(operations))
+	var batchErr error
+	for idx := range operations {
+		results[idx] = batchOperationResult{Index: idx, ActionType: operations[idx].ActionType, Status: operationSkipped}
+		if batchErr != nil {
+			continue
+		}
+		result, err := applyAction(tx, projectId, &operations[idx], author)
+		if err != nil {
+			batchErr = err
+			results[idx].Status = operationFailed
+			_, results[idx].Code, results[idx].Error = classifyError(err)
+			continue
+		}
+		results[idx].Status = operationApplied
+		results[idx].Result = result
+	}
+	return results, batchErr
+}
+
+func writeBatchResponse(w http.ResponseWriter, r *http.Request, status int, response batchResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, string(data))
+}
+
+func GetBatchHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [POST] Received a batch request from %s\n", projectId, r.Host)
+		decoder := json.NewDecoder(r.Body)
+		var reqData batchRequest
+		err := decoder.Decode(&reqData)
+		if err != nil {
+			if err != io.EOF {
+				badRequest(w, r, err)
+				return
+			}
+		}
+		if len(reqData.Operations) == 0 || len(reqData.Operations) > maxBatchOperations {
+			badRequest(w, r, fmt.Errorf("batch must contain from 1 to %d operations", maxBatchOperations))
+			return
+		}
+		role := types.RoleViewer
+		for _, operation := range reqData.Operations {
+			scope, operationRole, err := actionAccess(operation.ActionType)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			if !checkScope(w, r, scope) {
+				return
+			}
+			if types.RoleRank(operationRole) > types.RoleRank(role) {
+				role = operationRole
+			}
+		}
+		if !authorize(db, w, r, projectId, role) {
+			return
+		}
+		var results []batchOperationResult
+		var batchErr error
+		err = db.Tx(func(tx db_driver.Storage) error {
+			results, batchErr = runBatch(tx, projectId, reqData.Operations, getUser(r).Id)
+			return batchErr
+		})
+		if batchErr != nil {
+			status, _, _ := classifyError(batchErr)
+			if status == http.StatusInternalServerError {
+				log.Printf("[%s] Batch failed: %s\n", projectId, batchErr)
+			}
+			writeBatchResponse(w, r, status, batchResponse{false, results})
+			log.Printf("[%s] Batch rolled back\n", projectId)
+			return
+		}
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		writeBatchResponse(w, r, http.StatusOK, batchResponse{true, results})
+		log.Printf("[%s] Applied batch of %d operations\n", projectId, len(results))
+		for idx := range reqData.Operations {
+			publishAction(db, projectId, &reqData.Operations[idx], results[idx].Result, getUser(r).Id)
+		}
+	}
+	return handler
+}
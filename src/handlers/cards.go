@@ -1,18 +1,198 @@
 package handlers
 
 import (
-	"database/sql"
 	"db_driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"types"
 )
 
-func GetCardCreator(db *sql.DB) http.HandlerFunc {
+// readCardJson returns the card as stored, with its tag and assignee ids and its checklist progress.
+func readCardJson(db db_driver.Storage, cardId string) (*types.CardJson, error) {
+	card, err := db.GetCard(cardId)
+	if err != nil {
+		return nil, err
+	}
+	output := card.Json()
+	tags, err := db.GetTagsByCard(cardId)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag.Id != "" {
+			output.TagIds = append(output.TagIds, tag.Id)
+		}
+	}
+	assigneeIds, err := db.GetCardAssigneeIds(cardId)
+	if err != nil {
+		return nil, err
+	}
+	output.AssigneeIds = append(output.AssigneeIds, assigneeIds...)
+	progress, err := db.GetChecklistProgress(cardId)
+	if err != nil {
+		return nil, err
+	}
+	output.ChecklistProgress = *progress
+	return output, nil
+}
+
+// cardVersion loads the card for updateRequest.checkVersion.
+func cardVersion(db db_driver.Storage, cardId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		card, err := readCardJson(db, cardId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return card, card.Version, nil
+	}
+}
+
+func createCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, card *types.CardJson) ([]types.CardJson, bool) {
+	err := checkCardDates(card)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	for _, tagId := range card.TagIds {
+		if !checkTagInProject(db, w, r, projectId, tagId) {
+			return nil, false
+		}
+	}
+	cards := []types.CardJson{*card}
+	newCards, err := db.CreateCards(card.ColumnId, &cards, getUser(r).Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	log.Printf("[%s] Created card\n", projectId)
+	for _, card := range newCards {
+		publishCard(db, projectId, eventCardCreated, card.Id, getUser(r).Id)
+	}
+	return newCards, true
+}
+
+func updateCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, card *types.CardJson, update *updateRequest) (*types.CardJson, bool) {
+	if !checkColumnInProject(db, w, r, projectId, card.ColumnId) {
+		return nil, false
+	}
+	err := checkCardDates(card)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	card.Version = update.version
+	res, err := db.UpdateCard(card, getUser(r).Id)
+	if err != nil {
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return nil, false
+	}
+	log.Printf("[%s] Updated card %s\n", projectId, card.Id)
+	publishCard(db, projectId, eventCardUpdated, res.Id, getUser(r).Id)
+	return res, true
+}
+
+// cardMove is the target of a card move, positions start at 1 and 0 appends the card to the column.
+type cardMove struct {
+	ColumnId string `json:"columnId"`
+	Position int    `json:"position"`
+}
+
+func moveCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string, move *cardMove, update *updateRequest) bool {
+	if !checkColumnInProject(db, w, r, projectId, move.ColumnId) {
+		return false
+	}
+	_, err := db.MoveCard(cardId, move.ColumnId, move.Position, update.version, getUser(r).Id)
+	if err != nil {
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return false
+	}
+	log.Printf("[%s] Moved card %s to column %s\n", projectId, cardId, move.ColumnId)
+	publishCard(db, projectId, eventCardMoved, cardId, getUser(r).Id)
+	return true
+}
+
+func deleteCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string) bool {
+	err := db.DeleteCard(cardId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Deleted card %s\n", projectId, cardId)
+	events.publish(projectId, eventCardDeleted, getUser(r).Id, deletedPayload{cardId})
+	return true
+}
+
+func linkCardTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string, tagId string) bool {
+	if !checkTagInProject(db, w, r, projectId, tagId) {
+		return false
+	}
+	err := db.CreateCardTags(cardId, tagId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Linked tag %s to card %s\n", projectId, tagId, cardId)
+	publishCard(db, projectId, eventCardUpdated, cardId, getUser(r).Id)
+	return true
+}
+
+func unlinkCardTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string, tagId string) bool {
+	err := db.RemoveCardTags(cardId, tagId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Unlinked tag %s from card %s\n", projectId, tagId, cardId)
+	publishCard(db, projectId, eventCardUpdated, cardId, getUser(r).Id)
+	return true
+}
+
+// checkAssignee rejects assigning users that are not members of the project.
+func checkAssignee(db db_driver.Storage, projectId string, userId string) error {
+	_, err := db.GetProjectMember(projectId, userId)
+	var nfe db_driver.NotFoundError
+	if errors.As(err, &nfe) {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("user %s is not a member of project %s", userId, projectId)}
+	}
+	return err
+}
+
+func assignCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string, userId string) bool {
+	err := checkAssignee(db, projectId, userId)
+	if err == nil {
+		err = db.AssignCard(cardId, userId, getUser(r).Id)
+	}
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Assigned user %s to card %s\n", projectId, userId, cardId)
+	publishCard(db, projectId, eventCardUpdated, cardId, getUser(r).Id)
+	return true
+}
+
+func unassignCard(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, cardId string, userId string) bool {
+	err := db.UnassignCard(cardId, userId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Unassigned user %s from card %s\n", projectId, userId, cardId)
+	publishCard(db, projectId, eventCardUpdated, cardId, getUser(r).Id)
+	return true
+}
+
+func GetCardCreator(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			badMethod(w, r, []string{"post"})
@@ -28,26 +208,20 @@ func GetCardCreator(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		cards := []types.CardJson{reqData}
-		newCards, err := db_driver.CreateCards(db_driver.CreateAgentDB(db), reqData.ColumnId, &cards)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeColumn(db, w, r, reqData.ColumnId, types.RoleEditor)
+		if !ok {
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-
-		data, err := json.Marshal(newCards)
-		if err != nil {
-			badResponse(w, r, err)
+		newCards, ok := createCard(db, w, r, projectId, &reqData)
+		if !ok {
 			return
 		}
-		fmt.Fprint(w, string(data))
-		log.Printf("[POST] Created succesfully\n")
+		writeJson(w, r, http.StatusOK, newCards)
 	}
 	return handler
 }
 
-func GetCardUpdater(db *sql.DB) http.HandlerFunc {
+func GetCardUpdater(db db_driver.Storage) http.HandlerFunc {
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -55,33 +229,33 @@ func GetCardUpdater(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		log.Printf("[PUT] Received a update card request from %s\n", r.Host)
-		decoder := json.NewDecoder(r.Body)
+		update, ok := readUpdate(w, r)
 		var reqData types.CardJson
-		err := decoder.Decode(&reqData)
-		if err != nil {
-			if err != io.EOF {
-				badRequest(w, r, err)
-				return
-			}
+		if !ok || !update.decode(w, r, &reqData) {
+			return
 		}
-		res, err := db_driver.UpdateCard(db, &reqData)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeCard(db, w, r, reqData.Id, types.RoleEditor)
+		if !ok || !update.checkVersion(w, r, cardVersion(db, reqData.Id)) {
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		marshRes, err := json.Marshal(res)
+		card, err := readCardJson(db, reqData.Id)
 		if err != nil {
 			badResponse(w, r, err)
 			return
 		}
-		fmt.Fprint(w, string(marshRes))
-		log.Printf("[PUT] Updated succesfully\n")
+		if !update.decode(w, r, card) {
+			return
+		}
+		res, ok := updateCard(db, w, r, projectId, card, update)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusOK, res)
 	}
 	return handler
 }
 
-func GetCardDeleter(db *sql.DB) http.HandlerFunc {
+func GetCardDeleter(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			badMethod(w, r, []string{"delete"})
@@ -99,19 +273,20 @@ func GetCardDeleter(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		err = db_driver.DeleteCard(db, reqData.Id)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeCard(db, w, r, reqData.Id, types.RoleEditor)
+		if !ok {
+			return
+		}
+		if !deleteCard(db, w, r, projectId, reqData.Id) {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Deleted succesfully")
-		log.Printf("Deleted succesfully")
 	}
 	return handler
 }
 
-func GetCardTagAdder(db *sql.DB) http.HandlerFunc {
+func GetCardTagAdder(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
 			badMethod(w, r, []string{"put"})
@@ -130,19 +305,20 @@ func GetCardTagAdder(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		err = db_driver.CreateCardTags(db_driver.CreateAgentDB(db), reqData.CardId, reqData.TagId)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeCard(db, w, r, reqData.CardId, types.RoleEditor)
+		if !ok {
+			return
+		}
+		if !linkCardTag(db, w, r, projectId, reqData.CardId, reqData.TagId) {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Tag linked succesfully")
-		log.Printf("Tag linked succesfully")
 	}
 	return handler
 }
 
-func GetCardTagRemover(db *sql.DB) http.HandlerFunc {
+func GetCardTagRemover(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			badMethod(w, r, []string{"delete"})
@@ -163,14 +339,295 @@ func GetCardTagRemover(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		err = db_driver.RemoveCardTags(db_driver.CreateAgentDB(db), reqData.CardId, reqData.TagId)
+		projectId, ok := authorizeCard(db, w, r, reqData.CardId, types.RoleEditor)
+		if !ok {
+			return
+		}
+		if !unlinkCardTag(db, w, r, projectId, reqData.CardId, reqData.TagId) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Deleted succesfully")
+	}
+	return handler
+}
+
+// GetCardsHandler serves the card collection of a project. GET lists the cards with a due date soonest first,
+// narrowed down by the due filter of the query, POST creates a card in the column named by the payload.
+func GetCardsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("projectId")
+		switch r.Method {
+		case http.MethodGet:
+			readDueCards(db, w, r, projectId)
+			return
+		case http.MethodPost:
+		default:
+			badMethod(w, r, []string{"get", "post"})
+			return
+		}
+		log.Printf("[%s] [POST] Received a create card request from %s\n", projectId, r.Host)
+		var reqData types.CardJson
+		if !decodeJson(w, r, &reqData) || !authorize(db, w, r, projectId, types.RoleEditor) {
+			return
+		}
+		if !checkColumnInProject(db, w, r, projectId, reqData.ColumnId) {
+			return
+		}
+		newCards, ok := createCard(db, w, r, projectId, &reqData)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusCreated, newCards[0])
+	}
+}
+
+func readDueCards(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string) {
+	log.Printf("[%s] [GET] Received a due cards request from %s\n", projectId, r.Host)
+	window, ok := readDueWindow(w, r)
+	if !ok || !authorize(db, w, r, projectId, types.RoleViewer) {
+		return
+	}
+	if window == nil {
+		window = &dueWindow{math.MinInt, math.MaxInt}
+	}
+	cards, err := db.GetDueCards(projectId, window.After, window.Before)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	writeJson(w, r, http.StatusOK, cards)
+}
+
+// GetCardHandler serves a single card, PATCH changes only the fields present in the payload.
+func GetCardHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			card, err := readCardJson(db, cardId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, card.Version)
+			writeJson(w, r, http.StatusOK, card)
+			log.Printf("[%s] Readed card %s to %s\n", projectId, cardId, r.Host)
+		case http.MethodPatch:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, cardVersion(db, cardId)) {
+				return
+			}
+			card, err := readCardJson(db, cardId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			if !update.decode(w, r, card) {
+				return
+			}
+			card.Id = cardId
+			_, ok = updateCard(db, w, r, projectId, card, update)
+			if !ok {
+				return
+			}
+			card, err = readCardJson(db, cardId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, card.Version)
+			writeJson(w, r, http.StatusOK, card)
+		case http.MethodDelete:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+			if !ok || !deleteCard(db, w, r, projectId, cardId) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
+
+// GetCardTagHandler links the tag in the path to the card with PUT and unlinks it with DELETE.
+func GetCardTagHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+			badMethod(w, r, []string{"put", "delete"})
+			return
+		}
+		projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		tagId := r.PathValue("tagId")
+		if r.Method == http.MethodPut {
+			ok = linkCardTag(db, w, r, projectId, cardId, tagId)
+		} else {
+			ok = unlinkCardTag(db, w, r, projectId, cardId, tagId)
+		}
+		if !ok {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetCardAssigneeHandler assigns the project member in the path to the card with PUT and unassigns them with DELETE.
+func GetCardAssigneeHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+			badMethod(w, r, []string{"put", "delete"})
+			return
+		}
+		projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		userId := r.PathValue("userId")
+		if r.Method == http.MethodPut {
+			ok = assignCard(db, w, r, projectId, cardId, userId)
+		} else {
+			ok = unassignCard(db, w, r, projectId, cardId, userId)
+		}
+		if !ok {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetAssignedCardsHandler lists the cards assigned to the current user across all of their projects.
+func GetAssignedCardsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		user := getUser(r)
+		log.Printf("[%s] [GET] Received an assigned cards request from %s\n", user.Id, r.Host)
+		cards, err := db.GetAssignedCards(user.Id)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		writeJson(w, r, http.StatusOK, cards)
+	}
+}
+
+// GetCardMoveHandler moves the card to a position of its own column or of the column named by the payload.
+func GetCardMoveHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		log.Printf("[%s] [POST] Received a move card request from %s\n", projectId, r.Host)
+		update, ok := readUpdate(w, r)
+		if !ok || !update.checkVersion(w, r, cardVersion(db, cardId)) {
+			return
+		}
+		card, err := db.GetCard(cardId)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		move := cardMove{ColumnId: card.ColumnId}
+		if !update.decode(w, r, &move) || !moveCard(db, w, r, projectId, cardId, &move, update) {
+			return
+		}
+		moved, err := readCardJson(db, cardId)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		setVersion(w, moved.Version)
+		writeJson(w, r, http.StatusOK, moved)
+	}
+}
+
+func GetCardHistoryReader(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		id := r.PathValue("cardId")
+		log.Printf("[%s] [GET] Received a card history request from %s\n", id, r.Host)
+		_, _, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleViewer)
+		if !ok {
+			return
+		}
+		history, err := db.GetCardHistory(id)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		data, err := json.Marshal(history)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.Write(data)
+		log.Printf("[%s] Readed card history to %s\n", id, r.Host)
+	}
+	return handler
+}
+
+func GetCardReverter(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		id := r.PathValue("cardId")
+		log.Printf("[%s] [POST] Received a revert card request from %s\n", id, r.Host)
+		decoder := json.NewDecoder(r.Body)
+		var reqData struct {
+			RecordId int `json:"recordId"`
+		}
+		err := decoder.Decode(&reqData)
+		if err != nil {
+			if err != io.EOF {
+				badRequest(w, r, err)
+				return
+			}
+		}
+		projectId, _, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		res, err := db.RevertCard(id, reqData.RecordId, getUser(r).Id)
+		if err != nil {
+			var nfe db_driver.NotFoundError
+			if errors.As(err, &nfe) {
+				notFound(w, r, err)
+				return
+			}
+			badResponse(w, r, err)
+			return
+		}
+		data, err := json.Marshal(res)
 		if err != nil {
 			badResponse(w, r, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "Deleted succesfully")
-		log.Printf("[%s] Deleted succesfully", id)
+		fmt.Fprint(w, string(data))
+		log.Printf("[%s] Reverted succesfully\n", id)
+		publishCard(db, projectId, eventCardUpdated, id, getUser(r).Id)
 	}
 	return handler
 }
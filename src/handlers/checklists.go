@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"db_driver"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"types"
+)
+
+// checklistPosition is the payload of the checklist and item moves, positions start at 1 and 0 moves to the end.
+type checklistPosition struct {
+	Position int `json:"position"`
+}
+
+// readChecklistJson returns the checklist with its items in order.
+func readChecklistJson(db db_driver.Storage, checklistId string) (*types.ChecklistJson, error) {
+	checklist, err := db.GetChecklist(checklistId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := db.GetChecklistItems(checklistId)
+	if err != nil {
+		return nil, err
+	}
+	output := checklist.Json()
+	for _, item := range items {
+		output.Items = append(output.Items, *item.Json())
+	}
+	return output, nil
+}
+
+// checklistVersion loads the checklist for updateRequest.checkVersion, only its name and place change its version.
+func checklistVersion(db db_driver.Storage, checklistId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		checklist, err := readChecklistJson(db, checklistId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return checklist, checklist.Version, nil
+	}
+}
+
+// checklistItemVersion loads the item for updateRequest.checkVersion.
+func checklistItemVersion(db db_driver.Storage, itemId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		item, err := db.GetChecklistItem(itemId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return item.Json(), item.Version, nil
+	}
+}
+
+func checkChecklistName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("checklist name must not be empty")}
+	}
+	return nil
+}
+
+func checkChecklistItemText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("checklist item text must not be empty")}
+	}
+	return nil
+}
+
+// publishChecklist sends the checklist with all of its items, item changes are published this way too.
+func publishChecklist(db db_driver.Storage, projectId string, eventType string, checklistId string, actor string) {
+	payload, err := readChecklistJson(db, checklistId)
+	if err != nil {
+		log.Printf("[%s] Can't publish %s event: %s\n", projectId, eventType, err)
+		return
+	}
+	events.publish(projectId, eventType, actor, payload)
+}
+
+// publishItemChange sends the checklist of the item and the card, whose checklist progress changed with it.
+func publishItemChange(db db_driver.Storage, projectId string, checklistId string, actor string) {
+	publishChecklist(db, projectId, eventChecklistUpdated, checklistId, actor)
+	checklist, err := db.GetChecklist(checklistId)
+	if err != nil {
+		log.Printf("[%s] Can't publish %s event: %s\n", projectId, eventCardUpdated, err)
+		return
+	}
+	publishCard(db, projectId, eventCardUpdated, checklist.CardId, actor)
+}
+
+// GetChecklistsHandler serves the checklists of a card. GET reads all of them with their items,
+// POST adds a checklist at the position of the payload or after the others.
+func GetChecklistsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			checklists, err := db.GetChecklists(cardId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			output := []types.ChecklistJson{}
+			output = append(output, checklists...)
+			writeJson(w, r, http.StatusOK, output)
+			log.Printf("[%s] Readed checklists of card %s to %s\n", projectId, cardId, r.Host)
+		case http.MethodPost:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			log.Printf("[%s] [POST] Received a create checklist request from %s\n", projectId, r.Host)
+			var reqData struct {
+				Name     string `json:"name"`
+				Position int    `json:"position"`
+			}
+			if !decodeJson(w, r, &reqData) {
+				return
+			}
+			err := checkChecklistName(reqData.Name)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			checklist, err := db.CreateChecklist(cardId, reqData.Name, reqData.Position, getUser(r).Id)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Created checklist %s on card %s\n", projectId, checklist.Id, cardId)
+			events.publish(projectId, eventChecklistCreated, getUser(r).Id, checklist.Json())
+			setVersion(w, checklist.Version)
+			writeJson(w, r, http.StatusCreated, checklist.Json())
+		default:
+			badMethod(w, r, []string{"get", "post"})
+		}
+	}
+}
+
+// GetChecklistHandler serves a single checklist with its items. PATCH renames it,
+// DELETE removes it along with its items.
+func GetChecklistHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			checklist, err := readChecklistJson(db, checklistId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, checklist.Version)
+			writeJson(w, r, http.StatusOK, checklist)
+			log.Printf("[%s] Readed checklist %s to %s\n", projectId, checklistId, r.Host)
+		case http.MethodPatch:
+			projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, checklistVersion(db, checklistId)) {
+				return
+			}
+			checklist, err := readChecklistJson(db, checklistId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			name := checklist.Name
+			if !update.decode(w, r, checklist) {
+				return
+			}
+			err = checkChecklistName(checklist.Name)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			if checklist.Name != name {
+				_, err = db.RenameChecklist(checklistId, checklist.Name, update.version, getUser(r).Id)
+				if err != nil {
+					if !update.stale(w, r, err) {
+						badResponse(w, r, err)
+					}
+					return
+				}
+				log.Printf("[%s] Renamed checklist %s\n", projectId, checklistId)
+				publishChecklist(db, projectId, eventChecklistUpdated, checklistId, getUser(r).Id)
+			}
+			checklist, err = readChecklistJson(db, checklistId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, checklist.Version)
+			writeJson(w, r, http.StatusOK, checklist)
+		case http.MethodDelete:
+			projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			checklist, err := db.GetChecklist(checklistId)
+			if err == nil {
+				err = db.DeleteChecklist(checklistId)
+			}
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Deleted checklist %s\n", projectId, checklistId)
+			events.publish(projectId, eventChecklistDeleted, getUser(r).Id, deletedPayload{checklistId})
+			publishCard(db, projectId, eventCardUpdated, checklist.CardId, getUser(r).Id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
+
+// GetChecklistMoveHandler moves the checklist to a position among the checklists of its card.
+func GetChecklistMoveHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		log.Printf("[%s] [POST] Received a move checklist request from %s\n", projectId, r.Host)
+		update, ok := readUpdate(w, r)
+		var reqData checklistPosition
+		if !ok || !update.checkVersion(w, r, checklistVersion(db, checklistId)) || !update.decode(w, r, &reqData) {
+			return
+		}
+		_, err := db.MoveChecklist(checklistId, reqData.Position, update.version, getUser(r).Id)
+		if err != nil {
+			if !update.stale(w, r, err) {
+				badResponse(w, r, err)
+			}
+			return
+		}
+		log.Printf("[%s] Moved checklist %s to %d\n", projectId, checklistId, reqData.Position)
+		publishChecklist(db, projectId, eventChecklistUpdated, checklistId, getUser(r).Id)
+		checklist, err := readChecklistJson(db, checklistId)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		setVersion(w, checklist.Version)
+		writeJson(w, r, http.StatusOK, checklist)
+	}
+}
+
+// GetChecklistItemsHandler serves the items of a checklist. GET reads them in order,
+// POST adds an item that is not done at the position of the payload or after the others.
+func GetChecklistItemsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			items, err := db.GetChecklistItems(checklistId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			output := []types.ChecklistItemJson{}
+			for _, item := range items {
+				output = append(output, *item.Json())
+			}
+			writeJson(w, r, http.StatusOK, output)
+			log.Printf("[%s] Readed items of checklist %s to %s\n", projectId, checklistId, r.Host)
+		case http.MethodPost:
+			projectId, checklistId, ok := authorizePath(db, w, r, db.GetChecklistProjectId, "checklistId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			log.Printf("[%s] [POST] Received a create checklist item request from %s\n", projectId, r.Host)
+			var reqData struct {
+				Text     string `json:"text"`
+				Position int    `json:"position"`
+			}
+			if !decodeJson(w, r, &reqData) {
+				return
+			}
+			err := checkChecklistItemText(reqData.Text)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			item, err := db.CreateChecklistItem(checklistId, reqData.Text, reqData.Position, getUser(r).Id)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Created item %s in checklist %s\n", projectId, item.Id, checklistId)
+			publishItemChange(db, projectId, checklistId, getUser(r).Id)
+			setVersion(w, item.Version)
+			writeJson(w, r, http.StatusCreated, item.Json())
+		default:
+			badMethod(w, r, []string{"get", "post"})
+		}
+	}
+}
+
+// GetChecklistItemHandler serves a single checklist item. PATCH changes the text and the done flag present
+// in the payload, DELETE removes the item.
+func GetChecklistItemHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, itemId, ok := authorizePath(db, w, r, db.GetChecklistItemProjectId, "itemId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			item, err := db.GetChecklistItem(itemId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, item.Version)
+			writeJson(w, r, http.StatusOK, item.Json())
+		case http.MethodPatch:
+			projectId, itemId, ok := authorizePath(db, w, r, db.GetChecklistItemProjectId, "itemId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, checklistItemVersion(db, itemId)) {
+				return
+			}
+			item, err := db.GetChecklistItem(itemId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			reqData := item.Json()
+			if !update.decode(w, r, reqData) {
+				return
+			}
+			err = checkChecklistItemText(reqData.Text)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			if reqData.Text != item.Text || reqData.Done != item.Done {
+				item, err = db.UpdateChecklistItem(itemId, reqData.Text, reqData.Done, update.version, getUser(r).Id)
+				if err != nil {
+					if !update.stale(w, r, err) {
+						badResponse(w, r, err)
+					}
+					return
+				}
+				log.Printf("[%s] Updated checklist item %s\n", projectId, itemId)
+				publishItemChange(db, projectId, item.ChecklistId, getUser(r).Id)
+			}
+			setVersion(w, item.Version)
+			writeJson(w, r, http.StatusOK, item.Json())
+		case http.MethodDelete:
+			projectId, itemId, ok := authorizePath(db, w, r, db.GetChecklistItemProjectId, "itemId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			item, err := db.GetChecklistItem(itemId)
+			if err == nil {
+				err = db.DeleteChecklistItem(itemId)
+			}
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Deleted checklist item %s\n", projectId, itemId)
+			publishItemChange(db, projectId, item.ChecklistId, getUser(r).Id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
+
+// GetChecklistItemMoveHandler moves the item to a position of its checklist.
+func GetChecklistItemMoveHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId, itemId, ok := authorizePath(db, w, r, db.GetChecklistItemProjectId, "itemId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		log.Printf("[%s] [POST] Received a move checklist item request from %s\n", projectId, r.Host)
+		update, ok := readUpdate(w, r)
+		var reqData checklistPosition
+		if !ok || !update.checkVersion(w, r, checklistItemVersion(db, itemId)) || !update.decode(w, r, &reqData) {
+			return
+		}
+		item, err := db.MoveChecklistItem(itemId, reqData.Position, update.version, getUser(r).Id)
+		if err != nil {
+			if !update.stale(w, r, err) {
+				badResponse(w, r, err)
+			}
+			return
+		}
+		log.Printf("[%s] Moved checklist item %s to %d\n", projectId, itemId, item.Order)
+		publishChecklist(db, projectId, eventChecklistUpdated, item.ChecklistId, getUser(r).Id)
+		setVersion(w, item.Version)
+		writeJson(w, r, http.StatusOK, item.Json())
+	}
+}
+
+// GetChecklistItemToggleHandler flips the done flag of the item, concurrent toggles are applied one after the other.
+func GetChecklistItemToggleHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId, itemId, ok := authorizePath(db, w, r, db.GetChecklistItemProjectId, "itemId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		log.Printf("[%s] [POST] Received a toggle checklist item request from %s\n", projectId, r.Host)
+		item, err := db.ToggleChecklistItem(itemId, getUser(r).Id)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		log.Printf("[%s] Toggled checklist item %s to %t\n", projectId, itemId, item.Done)
+		publishItemChange(db, projectId, item.ChecklistId, getUser(r).Id)
+		setVersion(w, item.Version)
+		writeJson(w, r, http.StatusOK, item.Json())
+	}
+}
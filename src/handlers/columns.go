@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"db_driver"
 	"encoding/json"
 	"fmt"
@@ -11,7 +10,74 @@ import (
 	"types"
 )
 
-func GetColumnDataUpdater(db *sql.DB) http.HandlerFunc {
+// columnVersion loads the column for updateRequest.checkVersion.
+func columnVersion(db db_driver.Storage, columnId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		column, err := db.GetColumn(columnId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return column.Json(), column.Version, nil
+	}
+}
+
+func createColumn(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, column *types.ColumnJson) ([]types.ColumnJson, bool) {
+	newColumns, err := db.CreateColumns(projectId, []types.ColumnJson{*column}, getUser(r).Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	log.Printf("[%s] Created column\n", projectId)
+	for _, column := range newColumns {
+		events.publish(projectId, eventColumnCreated, getUser(r).Id, column)
+	}
+	return newColumns, true
+}
+
+func updateColumn(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, column *types.Column, update *updateRequest) (*types.ColumnJson, bool) {
+	column.Version = update.version
+	err := db.UpdateColumnData(column, getUser(r).Id)
+	if err != nil {
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return nil, false
+	}
+	newCol, err := db.GetColumn(column.Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	log.Printf("[%s] Updated column %s\n", projectId, column.Id)
+	events.publish(projectId, eventColumnUpdated, getUser(r).Id, newCol.Json())
+	return newCol.Json(), true
+}
+
+func moveColumn(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, columnId string, position int, update *updateRequest) ([]types.ColumnJson, bool) {
+	columns, err := db.MoveColumn(columnId, position, update.version, getUser(r).Id)
+	if err != nil {
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return nil, false
+	}
+	log.Printf("[%s] Moved column %s\n", projectId, columnId)
+	events.publish(projectId, eventColumnMoved, getUser(r).Id, columns)
+	return columns, true
+}
+
+func deleteColumn(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, columnId string) bool {
+	err := db.DeleteColumn(columnId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Deleted column %s\n", projectId, columnId)
+	events.publish(projectId, eventColumnDeleted, getUser(r).Id, deletedPayload{columnId})
+	return true
+}
+
+func GetColumnDataUpdater(db db_driver.Storage) http.HandlerFunc {
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -23,39 +89,35 @@ func GetColumnDataUpdater(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		log.Printf("[PUT] Received a update column data request from %s\n", r.Host)
-		decoder := json.NewDecoder(r.Body)
+		update, ok := readUpdate(w, r)
 		var reqData types.ColumnJson
-		err := decoder.Decode(&reqData)
-		if err != nil {
-			if err != io.EOF {
-				badRequest(w, r, err)
-				return
-			}
+		if !ok || !update.decode(w, r, &reqData) {
+			return
 		}
-		colData := types.Column{Id: reqData.Id, Name: reqData.Name, Order: reqData.Order, ProjectId: *id}
-		err = db_driver.UpdateColumnData(db, &colData)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeColumn(db, w, r, reqData.Id, types.RoleEditor)
+		if !ok || !update.checkVersion(w, r, columnVersion(db, reqData.Id)) {
 			return
 		}
-		newCol, err := db_driver.GetColumn(db_driver.CreateAgentDB(db), reqData.Id)
+		column, err := db.GetColumn(reqData.Id)
 		if err != nil {
 			badResponse(w, r, err)
 			return
 		}
-		marshRes, err := json.Marshal(newCol.Json())
-		if err != nil {
-			badResponse(w, r, err)
+		stored := column.Json()
+		if !update.decode(w, r, stored) {
 			return
 		}
-		fmt.Fprint(w, string(marshRes))
-		w.WriteHeader(http.StatusOK)
-		log.Printf("[PUT] Updated succesfully\n")
+		colData := types.Column{Id: reqData.Id, Name: stored.Name, Order: stored.Order, ProjectId: *id}
+		newCol, ok := updateColumn(db, w, r, projectId, &colData, update)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusOK, newCol)
 	}
 	return handler
 }
 
-func GetColumnDeleter(db *sql.DB) http.HandlerFunc {
+func GetColumnDeleter(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			badMethod(w, r, []string{"delete"})
@@ -73,19 +135,20 @@ func GetColumnDeleter(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		err = db_driver.DeleteColumn(db, reqData.Id)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeColumn(db, w, r, reqData.Id, types.RoleEditor)
+		if !ok {
+			return
+		}
+		if !deleteColumn(db, w, r, projectId, reqData.Id) {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Deleted succesfully")
-		log.Printf("Deleted succesfully")
 	}
 	return handler
 }
 
-func GetColumnCreator(db *sql.DB) http.HandlerFunc {
+func GetColumnCreator(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			badMethod(w, r, []string{"post"})
@@ -105,21 +168,103 @@ func GetColumnCreator(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		columns := make([]types.ColumnJson, 0)
-		columns = append(columns, reqData)
-		newColumns, err := db_driver.CreateColumns(db_driver.CreateAgentDB(db), *id, columns)
-		if err != nil {
-			badResponse(w, r, err)
+		if !authorize(db, w, r, *id, types.RoleEditor) {
 			return
 		}
-		data, err := json.Marshal(newColumns)
-		if err != nil {
-			badResponse(w, r, err)
+		newColumns, ok := createColumn(db, w, r, *id, &reqData)
+		if !ok {
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, string(data))
-		log.Printf("[%s] Updated succesfully", *id)
+		writeJson(w, r, http.StatusOK, newColumns)
 	}
 	return handler
 }
+
+// GetColumnsHandler serves the column collection of a project, POST appends a column.
+func GetColumnsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [POST] Received a create column request from %s\n", projectId, r.Host)
+		var reqData types.ColumnJson
+		if !decodeJson(w, r, &reqData) || !authorize(db, w, r, projectId, types.RoleEditor) {
+			return
+		}
+		newColumns, ok := createColumn(db, w, r, projectId, &reqData)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusCreated, newColumns[0])
+	}
+}
+
+// GetColumnHandler serves a single column, PATCH changes only the fields present in the payload.
+func GetColumnHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			projectId, columnId, ok := authorizePath(db, w, r, db.GetColumnProjectId, "columnId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, columnVersion(db, columnId)) {
+				return
+			}
+			column, err := db.GetColumn(columnId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			reqData := column.Json()
+			if !update.decode(w, r, reqData) {
+				return
+			}
+			colData := types.Column{Id: columnId, Name: reqData.Name, Order: reqData.Order, ProjectId: projectId}
+			newCol, ok := updateColumn(db, w, r, projectId, &colData, update)
+			if !ok {
+				return
+			}
+			setVersion(w, newCol.Version)
+			writeJson(w, r, http.StatusOK, newCol)
+		case http.MethodDelete:
+			projectId, columnId, ok := authorizePath(db, w, r, db.GetColumnProjectId, "columnId", types.RoleEditor)
+			if !ok || !deleteColumn(db, w, r, projectId, columnId) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"patch", "delete"})
+		}
+	}
+}
+
+// GetColumnMoveHandler moves the column to a position of its project and answers with the new column order.
+func GetColumnMoveHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId, columnId, ok := authorizePath(db, w, r, db.GetColumnProjectId, "columnId", types.RoleEditor)
+		if !ok {
+			return
+		}
+		log.Printf("[%s] [POST] Received a move column request from %s\n", projectId, r.Host)
+		update, ok := readUpdate(w, r)
+		var reqData struct {
+			Position int `json:"position"`
+		}
+		if !ok || !update.checkVersion(w, r, columnVersion(db, columnId)) || !update.decode(w, r, &reqData) {
+			return
+		}
+		columns, ok := moveColumn(db, w, r, projectId, columnId, reqData.Position, update)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusOK, columns)
+	}
+}
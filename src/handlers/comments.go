@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"types"
+)
+
+const (
+	// commentPageSize is how many comments a page holds when the query has no limit.
+	commentPageSize = 50
+	// maxCommentPageSize caps the limit of a comments query.
+	maxCommentPageSize = 200
+)
+
+// commentPage is one page of the comments of a card, NextCursor is passed as after to read the next page
+// and is empty on the last one.
+type commentPage struct {
+	Comments   []types.CommentJson `json:"comments"`
+	NextCursor string              `json:"nextCursor"`
+}
+
+// encodeCommentCursor makes the opaque cursor of the page ending with the comment, it holds the creation
+// time and id the comments are ordered by, so reading the next page doesn't need the comment to still exist.
+func encodeCommentCursor(comment *types.Comment) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", comment.CreatedAt, comment.Id)))
+}
+
+func decodeCommentCursor(cursor string) (int, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", err
+	}
+	createdAt, id, found := strings.Cut(string(raw), ":")
+	if !found || id == "" {
+		return 0, "", fmt.Errorf("cursor has no comment id")
+	}
+	afterAt, err := strconv.Atoi(createdAt)
+	if err != nil {
+		return 0, "", err
+	}
+	return afterAt, id, nil
+}
+
+// commentVersion loads the comment for updateRequest.checkVersion.
+func commentVersion(db db_driver.Storage, commentId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		comment, err := db.GetComment(commentId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return comment.Json(), comment.Version, nil
+	}
+}
+
+func checkCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("comment body must not be empty")}
+	}
+	return nil
+}
+
+// checkCommentParent lets replies point only at top level comments of the same card, so threads are one level deep.
+func checkCommentParent(db db_driver.Storage, cardId string, parentId string) error {
+	if parentId == "" {
+		return nil
+	}
+	parent, err := db.GetComment(parentId)
+	var nfe db_driver.NotFoundError
+	if errors.As(err, &nfe) {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("parent comment %s does not exist", parentId)}
+	}
+	if err != nil {
+		return err
+	}
+	if parent.CardId != cardId {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("comment %s is not on card %s", parentId, cardId)}
+	}
+	if parent.ParentId != "" {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("comment %s is a reply, replies can't be answered", parentId)}
+	}
+	return nil
+}
+
+// checkCommentAuthor lets only the author change a comment, admins may also delete it.
+func checkCommentAuthor(db db_driver.Storage, r *http.Request, projectId string, comment *types.Comment, adminAllowed bool) error {
+	user := getUser(r)
+	if comment.CreatedBy == user.Id {
+		return nil
+	}
+	if adminAllowed && checkRole(db, projectId, user.Id, types.RoleAdmin) == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: only the author can change comment %s", errForbidden, comment.Id)
+}
+
+// readCommentPage reads the page of comments after the cursor in the query, oldest first.
+func readCommentPage(db db_driver.Storage, w http.ResponseWriter, r *http.Request, cardId string) (*commentPage, bool) {
+	query := r.URL.Query()
+	limit := commentPageSize
+	if query.Has("limit") {
+		var err error
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 || limit > maxCommentPageSize {
+			badQuery(w, r, fmt.Errorf("limit must be a number from 1 to %d", maxCommentPageSize))
+			return nil, false
+		}
+	}
+	afterAt, afterId := 0, ""
+	if query.Has("after") {
+		var err error
+		afterAt, afterId, err = decodeCommentCursor(query.Get("after"))
+		if err != nil {
+			badQuery(w, r, fmt.Errorf("after is not a comment cursor: %w", err))
+			return nil, false
+		}
+	}
+	// One more comment than asked for tells whether there is a next page.
+	comments, err := db.GetComments(cardId, afterAt, afterId, limit+1)
+	if err != nil {
+		badResponse(w, r, err)
+		return nil, false
+	}
+	page := commentPage{Comments: []types.CommentJson{}}
+	for idx, comment := range comments {
+		if idx == limit {
+			page.NextCursor = encodeCommentCursor(&comments[idx-1])
+			break
+		}
+		page.Comments = append(page.Comments, *comment.Json())
+	}
+	return &page, true
+}
+
+// GetCommentsHandler serves the comments of a card. GET reads them a page at a time oldest first,
+// POST posts a comment or, with a parentId, a reply to a top level comment.
+func GetCommentsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			page, ok := readCommentPage(db, w, r, cardId)
+			if !ok {
+				return
+			}
+			writeJson(w, r, http.StatusOK, page)
+			log.Printf("[%s] Readed comments of card %s to %s\n", projectId, cardId, r.Host)
+		case http.MethodPost:
+			projectId, cardId, ok := authorizePath(db, w, r, db.GetCardProjectId, "cardId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			log.Printf("[%s] [POST] Received a create comment request from %s\n", projectId, r.Host)
+			var reqData types.CommentJson
+			if !decodeJson(w, r, &reqData) {
+				return
+			}
+			err := checkCommentBody(reqData.Body)
+			if err == nil {
+				err = checkCommentParent(db, cardId, reqData.ParentId)
+			}
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			comment, err := db.CreateComment(cardId, reqData.ParentId, reqData.Body, getUser(r).Id)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Created comment %s on card %s\n", projectId, comment.Id, cardId)
+			events.publish(projectId, eventCommentCreated, getUser(r).Id, comment.Json())
+			setVersion(w, comment.Version)
+			writeJson(w, r, http.StatusCreated, comment.Json())
+		default:
+			badMethod(w, r, []string{"get", "post"})
+		}
+	}
+}
+
+// GetCommentHandler serves a single comment. PATCH edits the body and keeps the previous one in the edit
+// history, only the author may edit. DELETE removes the comment with its replies, for the author or an admin.
+func GetCommentHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, commentId, ok := authorizePath(db, w, r, db.GetCommentProjectId, "commentId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			comment, err := db.GetComment(commentId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, comment.Version)
+			writeJson(w, r, http.StatusOK, comment.Json())
+		case http.MethodPatch:
+			projectId, commentId, ok := authorizePath(db, w, r, db.GetCommentProjectId, "commentId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, commentVersion(db, commentId)) {
+				return
+			}
+			comment, err := db.GetComment(commentId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			err = checkCommentAuthor(db, r, projectId, comment, false)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			reqData := comment.Json()
+			if !update.decode(w, r, reqData) {
+				return
+			}
+			err = checkCommentBody(reqData.Body)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			if reqData.Body != comment.Body {
+				comment, err = db.UpdateComment(commentId, reqData.Body, update.version, getUser(r).Id)
+				if err != nil {
+					if !update.stale(w, r, err) {
+						badResponse(w, r, err)
+					}
+					return
+				}
+				log.Printf("[%s] Updated comment %s\n", projectId, commentId)
+				events.publish(projectId, eventCommentUpdated, getUser(r).Id, comment.Json())
+			}
+			setVersion(w, comment.Version)
+			writeJson(w, r, http.StatusOK, comment.Json())
+		case http.MethodDelete:
+			projectId, commentId, ok := authorizePath(db, w, r, db.GetCommentProjectId, "commentId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			comment, err := db.GetComment(commentId)
+			if err == nil {
+				err = checkCommentAuthor(db, r, projectId, comment, true)
+			}
+			if err == nil {
+				err = db.DeleteComment(commentId)
+			}
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			log.Printf("[%s] Deleted comment %s\n", projectId, commentId)
+			events.publish(projectId, eventCommentDeleted, getUser(r).Id, deletedPayload{commentId})
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
+
+// GetCommentEditsHandler lists the earlier bodies of a comment, oldest first.
+func GetCommentEditsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		projectId, commentId, ok := authorizePath(db, w, r, db.GetCommentProjectId, "commentId", types.RoleViewer)
+		if !ok {
+			return
+		}
+		edits, err := db.GetCommentEdits(commentId)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		output := []types.CommentEditJson{}
+		for _, edit := range edits {
+			output = append(output, *edit.Json())
+		}
+		writeJson(w, r, http.StatusOK, output)
+		log.Printf("[%s] Readed edits of comment %s to %s\n", projectId, commentId, r.Host)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"types"
+)
+
+// dueSoonWindow is how many seconds ahead due=soon looks when the query has no within.
+const dueSoonWindow = 2 * 24 * 60 * 60
+
+// dueWindow is the range of due dates a query asks for, from After included up to Before excluded.
+type dueWindow struct {
+	After  int
+	Before int
+}
+
+func (d *dueWindow) contains(card *types.CardJson) bool {
+	return card.DueAt != nil && *card.DueAt >= d.After && *card.DueAt < d.Before
+}
+
+// filterCards keeps only the board cards due within the window.
+func (d *dueWindow) filterCards(project *types.KanbanJson) {
+	for idx := range project.Columns {
+		var cards []types.CardJson
+		for _, card := range project.Columns[idx].Cards {
+			if d.contains(&card) {
+				cards = append(cards, card)
+			}
+		}
+		project.Columns[idx].Cards = cards
+	}
+}
+
+// badQuery answers 400 for query parameters that can't be read.
+func badQuery(w http.ResponseWriter, r *http.Request, err error) {
+	badResponse(w, r, actionError{http.StatusBadRequest, err})
+}
+
+func queryTimestamp(r *http.Request, name string) (int, error) {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a unix timestamp", name)
+	}
+	return value, nil
+}
+
+// readDueWindow reads the due filter of the query, nil means there is none. due=overdue asks for the cards
+// due before now and due=soon for the ones due in the next within seconds. dueAfter and dueBefore take
+// unix timestamps, a missing one leaves the window open on that side.
+func readDueWindow(w http.ResponseWriter, r *http.Request) (*dueWindow, bool) {
+	query := r.URL.Query()
+	due := query.Get("due")
+	hasBounds := query.Has("dueAfter") || query.Has("dueBefore")
+	if due == "" && !hasBounds {
+		if query.Has("within") {
+			badQuery(w, r, fmt.Errorf("within is only read with due=soon"))
+			return nil, false
+		}
+		return nil, true
+	}
+	if due != "" && hasBounds {
+		badQuery(w, r, fmt.Errorf("due can't be combined with dueAfter or dueBefore"))
+		return nil, false
+	}
+	now := int(time.Now().Unix())
+	window := dueWindow{math.MinInt, math.MaxInt}
+	switch due {
+	case "":
+		var err error
+		if query.Has("dueAfter") {
+			window.After, err = queryTimestamp(r, "dueAfter")
+		}
+		if err == nil && query.Has("dueBefore") {
+			window.Before, err = queryTimestamp(r, "dueBefore")
+		}
+		if err != nil {
+			badQuery(w, r, err)
+			return nil, false
+		}
+	case "overdue":
+		window.Before = now
+	case "soon":
+		within := dueSoonWindow
+		if query.Has("within") {
+			var err error
+			within, err = strconv.Atoi(query.Get("within"))
+			if err != nil || within <= 0 {
+				badQuery(w, r, fmt.Errorf("within must be a positive number of seconds"))
+				return nil, false
+			}
+		}
+		window.After, window.Before = now, now+within
+	default:
+		badQuery(w, r, fmt.Errorf("unknown due filter %q, expected overdue or soon", due))
+		return nil, false
+	}
+	return &window, true
+}
+
+// checkCardDates rejects cards starting after they are due.
+func checkCardDates(card *types.CardJson) error {
+	if card.StartAt != nil && card.DueAt != nil && *card.StartAt > *card.DueAt {
+		return actionError{http.StatusUnprocessableEntity, fmt.Errorf("startAt must not be after dueAt")}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"utils"
+)
+
+// Error codes are stable, clients should branch on them instead of the message.
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeAlreadyExists    = "already_exists"
+	codeStillReferenced  = "still_referenced"
+	codeMissingReference = "missing_reference"
+	codeLastOwner        = "last_owner"
+	codeStaleVersion     = "stale_version"
+	codePrecondition     = "precondition_failed"
+	codeUnprocessable    = "unprocessable_entity"
+	codeInternal         = "internal_error"
+)
+
+const requestIdHeader = "X-Request-Id"
+
+// maxRequestIdLength keeps ids passed by clients from flooding logs and responses.
+const maxRequestIdLength = 128
+
+// problem is the RFC 7807 error body, code, requestId and details are its extension members.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	RequestId string `json:"requestId,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// WithRequestId keeps the X-Request-Id sent by the client or generates one,
+// it is echoed in the response headers and in every error body.
+func WithRequestId(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIdHeader)
+		if id == "" || len(id) > maxRequestIdLength || strings.ContainsFunc(id, func(c rune) bool { return c < '!' || c > '~' }) {
+			id = utils.GetUUID()
+		}
+		w.Header().Set(requestIdHeader, id)
+		handler(w, r.WithContext(context.WithValue(r.Context(), requestIdKey, id)))
+	}
+}
+
+func getRequestId(r *http.Request) string {
+	id, _ := r.Context().Value(requestIdKey).(string)
+	return id
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code string, detail string, details any) {
+	data, err := json.Marshal(problem{"about:blank", http.StatusText(status), status, detail, code, getRequestId(r), details})
+	if err != nil {
+		log.Printf("[%s] Can't encode error response: %s\n", r.Host, err)
+		data = []byte("{}")
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// statusCode is the generic code of a status, for errors that carry nothing more specific.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return codeBadRequest
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusMethodNotAllowed:
+		return codeMethodNotAllowed
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusPreconditionFailed:
+		return codePrecondition
+	case http.StatusUnprocessableEntity:
+		return codeUnprocessable
+	}
+	return codeInternal
+}
+
+// classifyError maps action and storage errors to a status, a code and a message safe to show to clients.
+// Driver errors are never shown as they are, they name tables and keys.
+func classifyError(err error) (int, string, string) {
+	var ae actionError
+	var nfe db_driver.NotFoundError
+	var noEffect db_driver.NoEffect
+	var duplicate db_driver.DuplicateError
+	var stale staleVersionError
+	var conflict db_driver.VersionConflict
+	var lastOwner db_driver.LastOwnerError
+	switch {
+	case errors.As(err, &ae):
+		return ae.status, statusCode(ae.status), err.Error()
+	case errors.As(err, &stale), errors.As(err, &conflict):
+		return http.StatusConflict, codeStaleVersion, err.Error()
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden, codeForbidden, err.Error()
+	case errors.As(err, &nfe):
+		return http.StatusNotFound, codeNotFound, err.Error()
+	case errors.As(err, &noEffect):
+		return http.StatusNotFound, codeNotFound, "resource was not found"
+	case errors.As(err, &duplicate):
+		return http.StatusConflict, codeAlreadyExists, err.Error()
+	case errors.As(err, &lastOwner):
+		return http.StatusConflict, codeLastOwner, "Project must keep at least one owner"
+	}
+	switch db_driver.ViolatedConstraint(err) {
+	case db_driver.ConstraintDuplicate:
+		return http.StatusConflict, codeAlreadyExists, "resource already exists"
+	case db_driver.ConstraintReferenced:
+		return http.StatusConflict, codeStillReferenced, "resource is still referenced by other resources"
+	case db_driver.ConstraintMissingReference:
+		return http.StatusUnprocessableEntity, codeMissingReference, "payload references a resource that doesn't exist"
+	}
+	return http.StatusInternalServerError, codeInternal, "internal error, contact api developers for more data"
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("Can't read payload: %s", err), nil)
+	log.Printf("Request from %s not fulfilled, bad request: %s\n", r.Host, err)
+}
+
+// badResponse answers with the status matching err, unknown errors are logged and reported as internal.
+func badResponse(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, message := classifyError(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] Bad Request: %s\n", getRequestId(r), err)
+	} else {
+		log.Printf("[%s] Request not fulfilled, %s: %s\n", r.Host, code, err)
+	}
+	writeProblem(w, r, status, code, message, nil)
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusUnauthorized, codeUnauthorized, fmt.Sprintf("Not authenticated: %s", err), nil)
+	log.Printf("[%s] Request not fulfilled, not authenticated: %s\n", r.Host, err)
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusForbidden, codeForbidden, fmt.Sprintf("Forbidden: %s", err), nil)
+	log.Printf("[%s] Request not fulfilled, forbidden: %s\n", r.Host, err)
+}
+
+func notFound(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusNotFound, codeNotFound, err.Error(), nil)
+	log.Printf("[%s] Request not fulfilled, not found: %s\n", r.Host, err)
+}
+
+func badMethod(w http.ResponseWriter, r *http.Request, methods []string) {
+	allowed := make([]string, len(methods))
+	for idx, method := range methods {
+		allowed[idx] = strings.ToUpper(method)
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, fmt.Sprintf("Method %s is not allowed", r.Method), map[string][]string{"allowed": allowed})
+	log.Printf("[%s] Request not fulfilled, bad method: %s, allowed: %s\n", r.Host, r.Method, methods)
+}
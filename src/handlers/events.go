@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"types"
+)
+
+const (
+	eventHistorySize    = 256
+	subscriberQueueSize = 64
+	heartbeatInterval   = time.Second * 25
+)
+
+const (
+	eventCardCreated      = "card.created"
+	eventCardUpdated      = "card.updated"
+	eventCardMoved        = "card.moved"
+	eventCardDeleted      = "card.deleted"
+	eventColumnCreated    = "column.created"
+	eventColumnUpdated    = "column.updated"
+	eventColumnMoved      = "column.moved"
+	eventColumnDeleted    = "column.deleted"
+	eventTagCreated       = "tag.created"
+	eventTagUpdated       = "tag.updated"
+	eventTagDeleted       = "tag.deleted"
+	eventCommentCreated   = "comment.created"
+	eventCommentUpdated   = "comment.updated"
+	eventCommentDeleted   = "comment.deleted"
+	eventChecklistCreated = "checklist.created"
+	eventChecklistUpdated = "checklist.updated"
+	eventChecklistDeleted = "checklist.deleted"
+	eventProjectUpdated   = "project.updated"
+	eventProjectDeleted   = "project.deleted"
+	// eventPresenceUpdated is transient, it carries who is viewing the board and is not kept for resuming.
+	eventPresenceUpdated = "presence.updated"
+	// eventResync tells a resuming client that events were lost and the board has to be reloaded.
+	eventResync = "resync"
+)
+
+type deletedPayload struct {
+	Id string `json:"id"`
+}
+
+type projectPayload struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Event struct {
+	Id        int64  `json:"id"`
+	ProjectId string `json:"projectId"`
+	Type      string `json:"type"`
+	Actor     string `json:"actor"`
+	CreatedAt int    `json:"createdAt"`
+	Payload   any    `json:"payload"`
+}
+
+type subscriber chan Event
+
+type projectStream struct {
+	nextId      int64
+	history     []Event
+	subscribers map[subscriber]struct{}
+}
+
+// broker fans project events out to subscribers and keeps a short history for resuming.
+// Subscribers that can't keep up are dropped, they are expected to reconnect with the last seen id.
+type broker struct {
+	mu       sync.Mutex
+	projects map[string]*projectStream
+}
+
+var events = newBroker()
+
+func newBroker() *broker {
+	return &broker{projects: make(map[string]*projectStream)}
+}
+
+func (b *broker) stream(projectId string) *projectStream {
+	stream, found := b.projects[projectId]
+	if !found {
+		// Ids continue from the clock, so ids seen before a restart are always older than new ones.
+		stream = &projectStream{
+			nextId:      time.Now().UnixMilli() * 1000,
+			subscribers: make(map[subscriber]struct{}),
+		}
+		b.projects[projectId] = stream
+	}
+	return stream
+}
+
+func (stream *projectStream) fanOut(projectId string, event Event) {
+	for sub := range stream.subscribers {
+		select {
+		case sub <- event:
+		default:
+			delete(stream.subscribers, sub)
+			close(sub)
+			log.Printf("[%s] Dropped slow event subscriber\n", projectId)
+		}
+	}
+}
+
+func (b *broker) publish(projectId string, eventType string, actor string, payload any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream := b.stream(projectId)
+	event := Event{stream.nextId, projectId, eventType, actor, int(time.Now().Unix()), payload}
+	stream.nextId++
+	stream.history = append(stream.history, event)
+	if len(stream.history) > eventHistorySize {
+		stream.history = stream.history[len(stream.history)-eventHistorySize:]
+	}
+	stream.fanOut(projectId, event)
+}
+
+// notify fans out a transient event, it gets no id and is not kept in the history.
+func (b *broker) notify(projectId string, eventType string, actor string, payload any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream := b.stream(projectId)
+	event := Event{0, projectId, eventType, actor, int(time.Now().Unix()), payload}
+	stream.fanOut(projectId, event)
+}
+
+// subscribe registers a subscriber and returns the events it missed after lastId along with the id
+// of the newest event. complete is false when some of the missed events are no longer kept.
+func (b *broker) subscribe(projectId string, lastId int64) (sub subscriber, missed []Event, latestId int64, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream := b.stream(projectId)
+	sub = make(subscriber, subscriberQueueSize)
+	stream.subscribers[sub] = struct{}{}
+	latestId = stream.nextId - 1
+	if lastId <= 0 {
+		return sub, nil, latestId, true
+	}
+	firstKept := stream.nextId
+	if len(stream.history) > 0 {
+		firstKept = stream.history[0].Id
+	}
+	if lastId+1 < firstKept || lastId > latestId {
+		return sub, nil, latestId, false
+	}
+	for _, event := range stream.history {
+		if event.Id > lastId {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, latestId, true
+}
+
+func (b *broker) unsubscribe(projectId string, sub subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream, found := b.projects[projectId]
+	if !found {
+		return
+	}
+	if _, found := stream.subscribers[sub]; found {
+		delete(stream.subscribers, sub)
+		close(sub)
+	}
+}
+
+// closeProject disconnects every subscriber of a deleted project and forgets its history.
+func (b *broker) closeProject(projectId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream, found := b.projects[projectId]
+	if !found {
+		return
+	}
+	for sub := range stream.subscribers {
+		close(sub)
+	}
+	delete(b.projects, projectId)
+}
+
+func (e *Event) data() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func writeEvent(w http.ResponseWriter, event *Event) error {
+	data, err := event.data()
+	if err != nil {
+		return err
+	}
+	if event.Id != 0 {
+		_, err = fmt.Fprintf(w, "id: %d\n", event.Id)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func getLastEventId(r *http.Request) int64 {
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = r.URL.Query().Get("lastEventId")
+	}
+	id, err := strconv.ParseInt(lastId, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func GetEventStreamHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [GET] Received an event stream request from %s\n", projectId, r.Host)
+		if !authorize(db, w, r, projectId, types.RoleViewer) {
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			badResponse(w, r, fmt.Errorf("streaming is not supported by the response writer"))
+			return
+		}
+		sub, missed, latestId, complete := events.subscribe(projectId, getLastEventId(r))
+		defer events.unsubscribe(projectId, sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		if !complete {
+			writeEvent(w, &Event{Id: latestId, Type: eventResync, ProjectId: projectId, CreatedAt: int(time.Now().Unix())})
+		}
+		for _, event := range missed {
+			writeEvent(w, &event)
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+			case event, open := <-sub:
+				if !open {
+					log.Printf("[%s] Event stream to %s closed\n", projectId, r.Host)
+					return
+				}
+				err := writeEvent(w, &event)
+				if err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+	return handler
+}
+
+// publishCard sends the card as stored, with its tag ids, since handler payloads may be partial.
+func publishCard(db db_driver.Storage, projectId string, eventType string, cardId string, actor string) {
+	payload, err := readCardJson(db, cardId)
+	if err != nil {
+		log.Printf("[%s] Can't publish %s event: %s\n", projectId, eventType, err)
+		return
+	}
+	events.publish(projectId, eventType, actor, payload)
+}
+
+// publishAction sends the event matching an applied action, it must be called after the commit.
+func publishAction(db db_driver.Storage, projectId string, req *postRequest, result *actionResult, actor string) {
+	switch req.ActionType {
+	case actionCreateCard:
+		publishCard(db, projectId, eventCardCreated, result.Card.Id, actor)
+	case actionUpdateCard:
+		publishCard(db, projectId, eventCardUpdated, result.Card.Id, actor)
+	case actionMoveCard:
+		publishCard(db, projectId, eventCardMoved, result.Card.Id, actor)
+	case actionLinkTag, actionUnlinkTag, actionAssignCard, actionUnassignCard:
+		publishCard(db, projectId, eventCardUpdated, req.CardPayload.Id, actor)
+	case actionDeleteCard:
+		events.publish(projectId, eventCardDeleted, actor, deletedPayload{req.CardPayload.Id})
+	case actionCreateColumn:
+		events.publish(projectId, eventColumnCreated, actor, result.Column)
+	case actionUpdateColumn:
+		events.publish(projectId, eventColumnUpdated, actor, result.Column)
+	case actionMoveColumn:
+		events.publish(projectId, eventColumnMoved, actor, result.Columns)
+	case actionDeleteColumn:
+		events.publish(projectId, eventColumnDeleted, actor, deletedPayload{req.ColumnPayload.Id})
+	case actionCreateTag:
+		events.publish(projectId, eventTagCreated, actor, result.Tag)
+	case actionUpdateTag:
+		events.publish(projectId, eventTagUpdated, actor, result.Tag)
+	case actionDeleteTag:
+		events.publish(projectId, eventTagDeleted, actor, deletedPayload{req.TagPayload.Id})
+	case actionUpdateProject:
+		events.publish(projectId, eventProjectUpdated, actor, result.Project)
+	}
+}
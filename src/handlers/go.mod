@@ -1,4 +1,5 @@
 module handlers
 
-go 1.21
+go 1.23
 
+require github.com/gorilla/websocket v1.5.3
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"db_driver"
 	"encoding/json"
 	"fmt"
@@ -12,75 +11,103 @@ import (
 	"types"
 )
 
-type postRequest struct {
-	ActionType     string           `json:"type"`
-	Position       int              `json:"position"`
-	TagPayload     types.TagJson    `json:"tag"`
-	CardPayload    types.CardJson   `json:"card"`
-	ColumnPayload  types.ColumnJson `json:"column"`
-	ProjectPayload types.KanbanJson `json:"project"`
-}
-
-func HandleDeleteRequest(db *sql.DB, id string, reader io.Reader) error {
-	return nil
+func writeJson(w http.ResponseWriter, r *http.Request, status int, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
 }
 
-func badRequest(w http.ResponseWriter, r *http.Request, err error) {
-	w.WriteHeader(http.StatusBadRequest)
-	fmt.Fprintf(w, "Can't read payload: %s\n", err)
-	log.Printf("Request from %s not fulfilled, bad request: %s\n", r.Host, err)
-}
-func badResponse(w http.ResponseWriter, r *http.Request, err error) {
-	w.WriteHeader(http.StatusInternalServerError)
-	log.Printf("Bad Request: %s\n", err)
-	fmt.Fprintf(w, "[%s] Request not fulfilled, contact api developers for more data\n", r.Host)
+// decodeJson reads the request body into value, an empty body leaves value as it is.
+func decodeJson(w http.ResponseWriter, r *http.Request, value any) bool {
+	err := json.NewDecoder(r.Body).Decode(value)
+	if err != nil && err != io.EOF {
+		badRequest(w, r, err)
+		return false
+	}
+	return true
 }
 
-func badMethod(w http.ResponseWriter, r *http.Request, methods []string) {
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	fmt.Fprintf(w, "Bad Method, allowed methods: %s\n", methods)
-	log.Printf("[%s] Request not fulfilled, bad method: %s, allowed: %s\n", r.Host, r.Method, methods)
+// Deprecated marks a route kept for old clients, the successor is the /api/v1 resource replacing it.
+func Deprecated(successor string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		handler(w, r)
+	}
 }
 
 func getProjectId(w http.ResponseWriter, r *http.Request) *string {
 	params, _ := url.ParseQuery(r.URL.RawQuery)
 	id := params.Get("id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "Bad project id provided \n")
+		writeProblem(w, r, http.StatusBadRequest, codeBadRequest, "Bad project id provided", nil)
 		log.Printf("[%s] Request failed, bad project id\n", r.Host)
 		return nil
 	}
 	return &id
 }
 
-func HandleRequest(db *sql.DB, id string, reader io.Reader) error {
-	decoder := json.NewDecoder(reader)
-	var reqData postRequest
-	err := decoder.Decode(&reqData)
+func createTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, tag *types.TagJson) ([]types.TagJson, bool) {
+	tags := []types.TagJson{*tag}
+	newTags, err := db.CreateTags(projectId, &tags, getUser(r).Id)
 	if err != nil {
-		if err != io.EOF {
-			return err
-		}
+		badResponse(w, r, err)
+		return nil, false
 	}
+	log.Printf("[%s] Created tag\n", projectId)
+	for _, tag := range newTags {
+		events.publish(projectId, eventTagCreated, getUser(r).Id, tag)
+	}
+	return newTags, true
+}
 
-	return nil
+// tagVersion loads the tag for updateRequest.checkVersion.
+func tagVersion(db db_driver.Storage, tagId string) func() (any, int, error) {
+	return func() (any, int, error) {
+		tag, err := db.GetTag(tagId)
+		if err != nil {
+			return nil, 0, err
+		}
+		return tag.Json(), tag.Version, nil
+	}
 }
 
-func readProjectById(db *sql.DB, id string) ([]byte, error) {
-	output, err := db_driver.GetProject(db, id)
+func updateTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, tag *types.TagJson, update *updateRequest) (*types.TagJson, bool) {
+	tag.Version = update.version
+	err := db.UpdateTag(tag, getUser(r).Id)
 	if err != nil {
-		return nil, err
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return nil, false
 	}
-	data, err := json.Marshal(output)
+	newTag, err := db.GetTag(tag.Id)
 	if err != nil {
-		return nil, err
+		badResponse(w, r, err)
+		return nil, false
 	}
+	log.Printf("[%s] Updated tag %s\n", projectId, tag.Id)
+	events.publish(projectId, eventTagUpdated, getUser(r).Id, newTag.Json())
+	return newTag.Json(), true
+}
 
-	return data, nil
+func deleteTag(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, tagId string) bool {
+	err := db.DeleteTag(tagId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Deleted tag %s\n", projectId, tagId)
+	events.publish(projectId, eventTagDeleted, getUser(r).Id, deletedPayload{tagId})
+	return true
 }
 
-func GetTagCreator(db *sql.DB) http.HandlerFunc {
+func GetTagCreator(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			badMethod(w, r, []string{"post"})
@@ -98,26 +125,19 @@ func GetTagCreator(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		tags := make([]types.TagJson, 0)
-		tags = append(tags, reqData)
-		newTags, err := db_driver.CreateTags(db_driver.CreateAgentDB(db), id, &tags)
-		if err != nil {
-			badResponse(w, r, err)
+		if !authorize(db, w, r, id, types.RoleEditor) {
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		data, err := json.Marshal(newTags)
-		if err != nil {
-			badResponse(w, r, err)
+		newTags, ok := createTag(db, w, r, id, &reqData)
+		if !ok {
 			return
 		}
-		fmt.Fprint(w, string(data))
-		log.Printf("[%s] Updated succesfully", id)
+		writeJson(w, r, http.StatusOK, newTags)
 	}
 	return handler
 }
 
-func GetTagDeleter(db *sql.DB) http.HandlerFunc {
+func GetTagDeleter(db db_driver.Storage) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			badMethod(w, r, []string{"delete"})
@@ -137,14 +157,89 @@ func GetTagDeleter(db *sql.DB) http.HandlerFunc {
 				return
 			}
 		}
-		_, err = db.Exec("DELETE FROM Tags WHERE id = ?", reqData.Id)
-		if err != nil {
-			badResponse(w, r, err)
+		projectId, ok := authorizeTag(db, w, r, reqData.Id, types.RoleEditor)
+		if !ok {
+			return
+		}
+		if !deleteTag(db, w, r, projectId, reqData.Id) {
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Deleted succesfully")
-		log.Printf("[%s] Deleted succesfully", id)
 	}
 	return handler
 }
+
+// GetTagsHandler serves the tag collection of a project, POST creates a tag.
+func GetTagsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [POST] Received a create tag request from %s\n", projectId, r.Host)
+		var reqData types.TagJson
+		if !decodeJson(w, r, &reqData) || !authorize(db, w, r, projectId, types.RoleEditor) {
+			return
+		}
+		newTags, ok := createTag(db, w, r, projectId, &reqData)
+		if !ok {
+			return
+		}
+		writeJson(w, r, http.StatusCreated, newTags[0])
+	}
+}
+
+// GetTagHandler serves a single tag, PATCH changes only the fields present in the payload.
+func GetTagHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, tagId, ok := authorizePath(db, w, r, db.GetTagProjectId, "tagId", types.RoleViewer)
+			if !ok {
+				return
+			}
+			tag, err := db.GetTag(tagId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			setVersion(w, tag.Version)
+			writeJson(w, r, http.StatusOK, tag.Json())
+		case http.MethodPatch:
+			projectId, tagId, ok := authorizePath(db, w, r, db.GetTagProjectId, "tagId", types.RoleEditor)
+			if !ok {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, tagVersion(db, tagId)) {
+				return
+			}
+			tag, err := db.GetTag(tagId)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			reqData := tag.Json()
+			if !update.decode(w, r, reqData) {
+				return
+			}
+			reqData.Id = tagId
+			newTag, ok := updateTag(db, w, r, projectId, reqData, update)
+			if !ok {
+				return
+			}
+			setVersion(w, newTag.Version)
+			writeJson(w, r, http.StatusOK, newTag)
+		case http.MethodDelete:
+			projectId, tagId, ok := authorizePath(db, w, r, db.GetTagProjectId, "tagId", types.RoleEditor)
+			if !ok || !deleteTag(db, w, r, projectId, tagId) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"bytes"
+	"db_driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+	"types"
+	"utils"
+)
+
+// testServer serves the /api/v1 board routes over memory storage the way main registers them.
+type testServer struct {
+	t     *testing.T
+	db    db_driver.Storage
+	mux   *http.ServeMux
+	token string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return serveStorage(t, db_driver.NewMemoryStorage())
+}
+
+func serveStorage(t *testing.T, db db_driver.Storage) *testServer {
+	mux := http.NewServeMux()
+	routes := map[string]http.HandlerFunc{
+		"/api/v1/projects":                                         GetProjectsHandler(db),
+		"/api/v1/projects/{projectId}":                             GetProjectHandler(db),
+		"/api/v1/projects/{projectId}/actions":                     GetActionHandler(db),
+		"/api/v1/projects/{projectId}/columns":                     GetColumnsHandler(db),
+		"/api/v1/projects/{projectId}/columns/{columnId}":          GetColumnHandler(db),
+		"/api/v1/projects/{projectId}/columns/{columnId}/move":     GetColumnMoveHandler(db),
+		"/api/v1/projects/{projectId}/cards":                       GetCardsHandler(db),
+		"/api/v1/projects/{projectId}/cards/{cardId}":              GetCardHandler(db),
+		"/api/v1/projects/{projectId}/cards/{cardId}/move":         GetCardMoveHandler(db),
+		"/api/v1/projects/{projectId}/cards/{cardId}/tags/{tagId}": GetCardTagHandler(db),
+		"/api/v1/projects/{projectId}/tags":                        GetTagsHandler(db),
+		"/api/v1/projects/{projectId}/tags/{tagId}":                GetTagHandler(db),
+		"/api/v1/projects/{projectId}/members":                     GetProjectMembersHandler(db),
+		"/api/v1/projects/{projectId}/members/{userId}":            GetProjectMemberHandler(db),
+		"/members": GetMembersHandler(db),
+	}
+	for pattern, handler := range routes {
+		mux.Handle(pattern, WithRequestId(Authenticated(db, handler)))
+	}
+	s := &testServer{t: t, db: db, mux: mux}
+	s.token = s.login("alice")
+	return s
+}
+
+// login creates the user with a session and returns the session token.
+func (s *testServer) login(name string) string {
+	user, err := s.db.CreateUser(name, "hash")
+	if err != nil {
+		s.t.Fatalf("can't create user %s: %s", name, err)
+	}
+	token := "session-" + name
+	err = s.db.CreateSession(user.Id, utils.HashToken(token), int(time.Now().Add(time.Hour).Unix()))
+	if err != nil {
+		s.t.Fatalf("can't create session of %s: %s", name, err)
+	}
+	return token
+}
+
+// request sends body as JSON with the token of alice, headers are name and value pairs.
+func (s *testServer) request(method string, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	return s.requestAs(s.token, method, path, body, headers...)
+}
+
+func (s *testServer) requestAs(token string, method string, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("can't encode request body: %s", err)
+		}
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	for idx := 0; idx+1 < len(headers); idx += 2 {
+		r.Header.Set(headers[idx], headers[idx+1])
+	}
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
+
+func decodeResponse[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var value T
+	err := json.Unmarshal(w.Body.Bytes(), &value)
+	if err != nil {
+		t.Fatalf("can't decode response %q: %s", w.Body.String(), err)
+	}
+	return value
+}
+
+func expectProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	expectStatus(t, w, status)
+	body := decodeResponse[problem](t, w)
+	if body.Code != code {
+		t.Fatalf("got problem code %q, want %q: %s", body.Code, code, w.Body.String())
+	}
+}
+
+func expectVersion(t *testing.T, w *httptest.ResponseRecorder, version string) {
+	t.Helper()
+	if got := w.Header().Get("ETag"); got != version {
+		t.Fatalf("got ETag %s, want %s", got, version)
+	}
+}
+
+// createTestProject creates a board with columns todo and done and returns its id.
+func (s *testServer) createTestProject() string {
+	s.t.Helper()
+	w := s.request(http.MethodPost, "/api/v1/projects", types.KanbanJson{
+		Name:    "board",
+		Columns: []types.ColumnJson{{Name: "todo"}, {Name: "done"}},
+	})
+	expectStatus(s.t, w, http.StatusCreated)
+	return decodeResponse[projectPayload](s.t, w).Id
+}
+
+func (s *testServer) readBoard(projectId string) *types.KanbanJson {
+	s.t.Helper()
+	w := s.request(http.MethodGet, "/api/v1/projects/"+projectId, nil)
+	expectStatus(s.t, w, http.StatusOK)
+	return decodeResponse[*types.KanbanJson](s.t, w)
+}
+
+func TestProjectRoutes(t *testing.T) {
+	s := newTestServer(t)
+	w := s.request(http.MethodPost, "/api/v1/projects", types.KanbanJson{Name: "board"})
+	expectStatus(t, w, http.StatusCreated)
+	projectId := decodeResponse[projectPayload](t, w).Id
+	if got := w.Header().Get("Location"); got != "/api/v1/projects/"+projectId {
+		t.Errorf("got Location %q for project %s", got, projectId)
+	}
+
+	w = s.request(http.MethodGet, "/api/v1/projects/"+projectId, nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("board answered with ETag %s, boards have no version", got)
+	}
+	board := decodeResponse[types.KanbanJson](t, w)
+	if board.Name != "board" || board.Version != 1 {
+		t.Errorf("board is %q at version %d, want board at 1", board.Name, board.Version)
+	}
+
+	w = s.request(http.MethodPatch, "/api/v1/projects/"+projectId, map[string]any{"name": "renamed", "expectedVersion": 1})
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[projectPayload](t, w).Name; got != "renamed" {
+		t.Errorf("patched project is named %q, want renamed", got)
+	}
+	w = s.request(http.MethodPatch, "/api/v1/projects/"+projectId, map[string]any{"name": "stale", "expectedVersion": 1})
+	expectProblem(t, w, http.StatusConflict, codeStaleVersion)
+	if got := s.readBoard(projectId).Name; got != "renamed" {
+		t.Errorf("stale patch renamed the project to %q", got)
+	}
+
+	stranger := s.login("bob")
+	w = s.requestAs(stranger, http.MethodGet, "/api/v1/projects/"+projectId, nil)
+	expectProblem(t, w, http.StatusForbidden, codeForbidden)
+	w = s.requestAs("", http.MethodGet, "/api/v1/projects/"+projectId, nil)
+	expectProblem(t, w, http.StatusUnauthorized, codeUnauthorized)
+
+	w = s.request(http.MethodDelete, "/api/v1/projects/"+projectId, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	w = s.request(http.MethodGet, "/api/v1/projects/"+projectId, nil)
+	if w.Code == http.StatusOK {
+		t.Errorf("deleted project is still served")
+	}
+}
+
+func TestUpdateProjectAction(t *testing.T) {
+	s := newTestServer(t)
+	projectId := s.createTestProject()
+	actions := "/api/v1/projects/" + projectId + "/actions"
+
+	w := s.request(http.MethodPost, actions, map[string]any{"type": actionUpdateProject})
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[actionResult](t, w).Project; got == nil || got.Name != "board" {
+		t.Errorf("empty update answered with project %+v, want board", got)
+	}
+	if got := s.readBoard(projectId).Name; got != "board" {
+		t.Errorf("empty update renamed the project to %q", got)
+	}
+
+	w = s.request(http.MethodPost, actions, map[string]any{"type": actionUpdateProject, "project": map[string]any{"name": "renamed"}})
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[actionResult](t, w).Project; got == nil || got.Id != projectId || got.Name != "renamed" {
+		t.Errorf("update answered with project %+v, want renamed", got)
+	}
+	if got := s.readBoard(projectId).Name; got != "renamed" {
+		t.Errorf("project is named %q after the update, want renamed", got)
+	}
+}
+
+func TestColumnRoutes(t *testing.T) {
+	s := newTestServer(t)
+	projectId := s.createTestProject()
+	base := "/api/v1/projects/" + projectId + "/columns"
+
+	w := s.request(http.MethodPost, base, types.ColumnJson{Name: "doing"})
+	expectStatus(t, w, http.StatusCreated)
+	column := decodeResponse[types.ColumnJson](t, w)
+	if column.Name != "doing" || column.Order != 3 {
+		t.Errorf("created column is %q at %d, want doing at 3", column.Name, column.Order)
+	}
+
+	w = s.request(http.MethodPatch, base+"/"+column.Id, map[string]any{"name": "review"}, "If-Match", `"1"`)
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"2"`)
+	if got := decodeResponse[types.ColumnJson](t, w); got.Name != "review" || got.Order != 3 {
+		t.Errorf("patched column is %q at %d, want review at 3", got.Name, got.Order)
+	}
+	w = s.request(http.MethodPatch, base+"/"+column.Id, map[string]any{"name": "stale"}, "If-Match", `"1"`)
+	expectProblem(t, w, http.StatusPreconditionFailed, codeStaleVersion)
+	expectVersion(t, w, `"2"`)
+
+	w = s.request(http.MethodPost, base+"/"+column.Id+"/move", map[string]any{"position": 1})
+	expectStatus(t, w, http.StatusOK)
+	var names []string
+	for _, moved := range decodeResponse[[]types.ColumnJson](t, w) {
+		names = append(names, moved.Name)
+	}
+	if !slices.Equal(names, []string{"review", "todo", "done"}) {
+		t.Errorf("moved columns are %v, want review, todo, done", names)
+	}
+
+	w = s.request(http.MethodDelete, base+"/"+column.Id, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	w = s.request(http.MethodPatch, base+"/"+column.Id, map[string]any{"name": "gone"})
+	expectProblem(t, w, http.StatusNotFound, codeNotFound)
+	if got := len(s.readBoard(projectId).Columns); got != 2 {
+		t.Errorf("board has %d columns after the delete, want 2", got)
+	}
+}
+
+func TestCardRoutes(t *testing.T) {
+	s := newTestServer(t)
+	projectId := s.createTestProject()
+	board := s.readBoard(projectId)
+	todo, done := board.Columns[0].Id, board.Columns[1].Id
+	base := "/api/v1/projects/" + projectId + "/cards"
+
+	w := s.request(http.MethodPost, base, types.CardJson{ColumnId: todo, Name: "card", Description: "text"})
+	expectStatus(t, w, http.StatusCreated)
+	cardId := decodeResponse[types.CardJson](t, w).Id
+
+	w = s.request(http.MethodGet, base+"/"+cardId, nil)
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"1"`)
+
+	w = s.request(http.MethodPatch, base+"/"+cardId, map[string]any{"name": "renamed"})
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"2"`)
+	card := decodeResponse[types.CardJson](t, w)
+	if card.Name != "renamed" || card.Description != "text" {
+		t.Errorf("patched card is %q %q, want renamed with its description kept", card.Name, card.Description)
+	}
+	w = s.request(http.MethodPatch, base+"/"+cardId, map[string]any{"name": "stale", "expectedVersion": 1})
+	expectProblem(t, w, http.StatusConflict, codeStaleVersion)
+
+	w = s.request(http.MethodPost, base+"/"+cardId+"/move", map[string]any{"columnId": done, "position": 1})
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"3"`)
+	if got := decodeResponse[types.CardJson](t, w).ColumnId; got != done {
+		t.Errorf("moved card is in column %s, want %s", got, done)
+	}
+
+	w = s.request(http.MethodPost, "/api/v1/projects/"+projectId+"/tags", types.TagJson{Name: "bug", Color: "red"})
+	expectStatus(t, w, http.StatusCreated)
+	tagId := decodeResponse[types.TagJson](t, w).Id
+	w = s.request(http.MethodPut, base+"/"+cardId+"/tags/"+tagId, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	w = s.request(http.MethodGet, base+"/"+cardId, nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[types.CardJson](t, w).TagIds; !slices.Equal(got, []string{tagId}) {
+		t.Errorf("linked card has tag ids %v, want %s", got, tagId)
+	}
+	w = s.request(http.MethodDelete, base+"/"+cardId+"/tags/"+tagId, nil)
+	expectStatus(t, w, http.StatusNoContent)
+
+	otherProject := s.createTestProject()
+	otherColumn := s.readBoard(otherProject).Columns[0].Id
+	w = s.request(http.MethodPost, base, types.CardJson{ColumnId: otherColumn, Name: "foreign"})
+	if w.Code < 400 {
+		t.Errorf("card was created in a column of another project with status %d", w.Code)
+	}
+
+	w = s.request(http.MethodDelete, base+"/"+cardId, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	w = s.request(http.MethodGet, base+"/"+cardId, nil)
+	expectProblem(t, w, http.StatusNotFound, codeNotFound)
+}
+
+func TestTagRoutes(t *testing.T) {
+	s := newTestServer(t)
+	projectId := s.createTestProject()
+	base := "/api/v1/projects/" + projectId + "/tags"
+
+	w := s.request(http.MethodPost, base, types.TagJson{Name: "bug", Color: "red"})
+	expectStatus(t, w, http.StatusCreated)
+	tagId := decodeResponse[types.TagJson](t, w).Id
+
+	w = s.request(http.MethodGet, base+"/"+tagId, nil)
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"1"`)
+
+	w = s.request(http.MethodPatch, base+"/"+tagId, map[string]any{"color": "green"}, "If-Match", `"1"`)
+	expectStatus(t, w, http.StatusOK)
+	expectVersion(t, w, `"2"`)
+	if got := decodeResponse[types.TagJson](t, w); got.Name != "bug" || got.Color != "green" {
+		t.Errorf("patched tag is %q %q, want bug green", got.Name, got.Color)
+	}
+	w = s.request(http.MethodPatch, base+"/"+tagId, map[string]any{"name": "stale", "expectedVersion": 1})
+	expectProblem(t, w, http.StatusConflict, codeStaleVersion)
+
+	viewer := s.login("bob")
+	bob, err := s.db.GetUserByLogin("bob")
+	if err != nil {
+		t.Fatalf("can't read user bob: %s", err)
+	}
+	err = s.db.AddProjectMember(projectId, bob.Id, types.RoleViewer, bob.Id)
+	if err != nil {
+		t.Fatalf("can't add bob to the project: %s", err)
+	}
+	w = s.requestAs(viewer, http.MethodGet, base+"/"+tagId, nil)
+	expectStatus(t, w, http.StatusOK)
+	w = s.requestAs(viewer, http.MethodPatch, base+"/"+tagId, map[string]any{"name": "viewer"})
+	expectProblem(t, w, http.StatusForbidden, codeForbidden)
+
+	w = s.request(http.MethodDelete, base+"/"+tagId, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	w = s.request(http.MethodGet, base+"/"+tagId, nil)
+	expectProblem(t, w, http.StatusNotFound, codeNotFound)
+}
+
+func TestMemberRoutes(t *testing.T) {
+	s := newTestServer(t)
+	projectId := s.createTestProject()
+	base := "/api/v1/projects/" + projectId + "/members/"
+	alice, err := s.db.GetUserByLogin("alice")
+	if err != nil {
+		t.Fatalf("can't read user alice: %s", err)
+	}
+	s.login("bob")
+	s.login("carol")
+	for _, login := range []string{"bob", "carol"} {
+		w := s.request(http.MethodPost, "/api/v1/projects/"+projectId+"/members", memberRequest{Login: login, Role: types.RoleViewer})
+		expectStatus(t, w, http.StatusOK)
+	}
+	bob, err := s.db.GetUserByLogin("bob")
+	if err != nil {
+		t.Fatalf("can't read user bob: %s", err)
+	}
+	carol, err := s.db.GetUserByLogin("carol")
+	if err != nil {
+		t.Fatalf("can't read user carol: %s", err)
+	}
+
+	w := s.request(http.MethodDelete, base+alice.Id, nil)
+	expectProblem(t, w, http.StatusConflict, codeLastOwner)
+	w = s.request(http.MethodPatch, base+alice.Id, memberRequest{Role: types.RoleAdmin})
+	expectProblem(t, w, http.StatusConflict, codeLastOwner)
+
+	w = s.request(http.MethodDelete, base+bob.Id, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	if w.Body.Len() != 0 {
+		t.Errorf("member delete answered with body %q, want none", w.Body.String())
+	}
+
+	w = s.request(http.MethodDelete, "/members?id="+projectId, memberRequest{UserId: carol.Id})
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Body.String(); got != "Deleted succesfully" {
+		t.Errorf("legacy member delete answered %q, want the legacy text", got)
+	}
+}
+
+// racingStorage renames the tag before every tag update, like a client writing between the version check
+// of a request and its write.
+type racingStorage struct {
+	db_driver.Storage
+}
+
+func (s racingStorage) UpdateTag(tag *types.TagJson, author string) error {
+	stored, err := s.Storage.GetTag(tag.Id)
+	if err != nil {
+		return err
+	}
+	racer := stored.Json()
+	racer.Name = "racer"
+	err = s.Storage.UpdateTag(racer, author)
+	if err != nil {
+		return err
+	}
+	return s.Storage.UpdateTag(tag, author)
+}
+
+func TestWriteLosingTheRace(t *testing.T) {
+	s := serveStorage(t, racingStorage{db_driver.NewMemoryStorage()})
+	projectId := s.createTestProject()
+	base := "/api/v1/projects/" + projectId + "/tags"
+	w := s.request(http.MethodPost, base, types.TagJson{Name: "bug", Color: "red"})
+	expectStatus(t, w, http.StatusCreated)
+	tagId := decodeResponse[types.TagJson](t, w).Id
+
+	w = s.request(http.MethodPatch, base+"/"+tagId, map[string]any{"name": "mine"}, "If-Match", `"1"`)
+	expectProblem(t, w, http.StatusPreconditionFailed, codeStaleVersion)
+	expectVersion(t, w, `"2"`)
+	w = s.request(http.MethodGet, base+"/"+tagId, nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[types.TagJson](t, w).Name; got != "racer" {
+		t.Errorf("tag is named %q after the lost race, want racer", got)
+	}
+
+	w = s.request(http.MethodPatch, base+"/"+tagId, map[string]any{"name": "mine"})
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeResponse[types.TagJson](t, w).Name; got != "mine" {
+		t.Errorf("unconditional patch named the tag %q, want mine", got)
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"db_driver"
 	"encoding/json"
 	"errors"
@@ -14,28 +13,38 @@ import (
 	"utils"
 )
 
-func readProject(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	params, _ := url.ParseQuery(r.URL.RawQuery)
-	id := params.Get("id")
+// readProject answers with the whole board, a due filter in the query leaves only the cards due within it.
+func readProject(db db_driver.Storage, w http.ResponseWriter, r *http.Request, id string) {
 	log.Printf("[%s] Received a get request from %s\n", id, r.Host)
-	data, err := readProjectById(db, id)
+	window, ok := readDueWindow(w, r)
+	if !ok || !authorize(db, w, r, id, types.RoleViewer) {
+		return
+	}
+	project, err := db.GetProject(id)
 	if err != nil {
-		var nfe db_driver.NotFoundError
-		if errors.As(err, &nfe) {
-			w.WriteHeader(http.StatusNotFound)
-			log.Printf("[%s] Get request not fulfilled, project not found\n", id)
-			fmt.Fprint(w, err.Error())
-			return
-		} else {
-			badResponse(w, r, err)
-			return
-		}
+		badResponse(w, r, err)
+		return
+	}
+	if window != nil {
+		window.filterCards(project)
 	}
-	w.Write(data)
+	writeJson(w, r, http.StatusOK, project)
 	log.Printf("[%s] Readed project to %s\n", id, r.Host)
 }
 
-func createProject(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+// projectVersion loads the board for updateRequest.checkVersion, the version is the one of the project row
+// and changes with its name only.
+func projectVersion(db db_driver.Storage, id string) func() (any, int, error) {
+	return func() (any, int, error) {
+		project, err := db.GetProject(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return project, project.Version, nil
+	}
+}
+
+func createProject(db db_driver.Storage, w http.ResponseWriter, r *http.Request) (string, bool) {
 	log.Printf("[NEW] Received a post request from %s\n", r.Host)
 	defer r.Body.Close()
 	decoder := json.NewDecoder(r.Body)
@@ -44,39 +53,54 @@ func createProject(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if err != io.EOF {
 			badRequest(w, r, err)
-			return
+			return "", false
 		}
 	}
 	id := utils.GetUUID()
-	err = db_driver.CreateProject(db, id, &reqData)
+	err = db.CreateProject(id, &reqData, getUser(r).Id)
 	if err != nil {
 		badResponse(w, r, err)
-		return
+		return "", false
 	}
-	fmt.Fprint(w, id)
 	log.Printf("[%s] Created project\n", id)
+	return id, true
 }
 
-func deleteProject(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	params, _ := url.ParseQuery(r.URL.RawQuery)
-	id := params.Get("id")
+func deleteProject(db db_driver.Storage, w http.ResponseWriter, r *http.Request, id string) bool {
 	log.Printf("[%s] Received a delete request from %s\n", id, r.Host)
-	res, err := db.Exec("DELETE FROM Projects WHERE id = ?", id)
+	if !authorize(db, w, r, id, types.RoleOwner) {
+		return false
+	}
+	err := db.DeleteProject(id)
 	if err != nil {
+		var ne db_driver.NoEffect
+		if errors.As(err, &ne) {
+			notFound(w, r, fmt.Errorf("project %s was not found", id))
+			return false
+		}
 		badResponse(w, r, err)
-		return
-	}
-	affected, _ := res.RowsAffected()
-	if affected <= 0 {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "Project %s not found\n", id)
-		log.Printf("[%s] Delete request not fulfilled, project not found\n", id)
-		return
+		return false
 	}
 	log.Printf("[%s] Deleted project\n", id)
+	events.publish(id, eventProjectDeleted, getUser(r).Id, deletedPayload{id})
+	events.closeProject(id)
+	return true
 }
 
-func GetProjectDataUpdater(db *sql.DB) http.HandlerFunc {
+func updateProjectData(db db_driver.Storage, w http.ResponseWriter, r *http.Request, id string, name string, update *updateRequest) bool {
+	err := db.UpdateProjectData(id, name, update.version, getUser(r).Id)
+	if err != nil {
+		if !update.stale(w, r, err) {
+			badResponse(w, r, err)
+		}
+		return false
+	}
+	log.Printf("[%s] Updated project data\n", id)
+	events.publish(id, eventProjectUpdated, getUser(r).Id, projectPayload{id, name})
+	return true
+}
+
+func GetProjectDataUpdater(db db_driver.Storage) http.HandlerFunc {
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPatch {
@@ -88,43 +112,107 @@ func GetProjectDataUpdater(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		log.Printf("[PUT] Received a update project data request from %s\n", r.Host)
-		decoder := json.NewDecoder(r.Body)
-		var reqData struct {
-			Name string `json:"name"`
+		if !authorize(db, w, r, *id, types.RoleAdmin) {
+			return
 		}
-		err := decoder.Decode(&reqData)
-		if err != nil {
-			if err != io.EOF {
-				badRequest(w, r, err)
-				return
-			}
+		update, ok := readUpdate(w, r)
+		if !ok || !update.checkVersion(w, r, projectVersion(db, *id)) {
+			return
 		}
-		_, err = db.Exec("CALL update_project_data(?, ?, ?)", id, reqData.Name, "placeholder")
+		project, err := db.GetProject(*id)
 		if err != nil {
 			badResponse(w, r, err)
 			return
 		}
+		reqData := projectPayload{*id, project.Name}
+		if !update.decode(w, r, &reqData) {
+			return
+		}
+		if !updateProjectData(db, w, r, *id, reqData.Name, update) {
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "Updated succesfully")
-		log.Printf("[PUT] Updated succesfully\n")
 	}
 	return handler
 }
 
-func GetProjectRequestHandler(db *sql.DB) http.HandlerFunc {
+func GetProjectRequestHandler(db db_driver.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		params, _ := url.ParseQuery(r.URL.RawQuery)
 		switch r.Method {
 		case http.MethodPost:
-			createProject(db, w, r)
+			id, ok := createProject(db, w, r)
+			if ok {
+				fmt.Fprint(w, id)
+			}
 			return
 		case http.MethodGet:
-			readProject(db, w, r)
+			readProject(db, w, r, params.Get("id"))
 			return
 		case http.MethodDelete:
-			deleteProject(db, w, r)
+			deleteProject(db, w, r, params.Get("id"))
 			return
 		default:
 			badMethod(w, r, []string{"get", "delete", "post"})
 		}
 	}
 }
+
+// GetProjectsHandler serves the project collection, POST creates a project owned by the current user.
+func GetProjectsHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		id, ok := createProject(db, w, r)
+		if !ok {
+			return
+		}
+		project, err := db.GetProject(id)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.Header().Set("Location", "/api/v1/projects/"+id)
+		writeJson(w, r, http.StatusCreated, projectPayload{id, project.Name})
+	}
+}
+
+// GetProjectHandler serves a whole board, PATCH changes only the fields present in the payload.
+func GetProjectHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("projectId")
+		switch r.Method {
+		case http.MethodGet:
+			readProject(db, w, r, id)
+		case http.MethodPatch:
+			log.Printf("[%s] [PATCH] Received a update project data request from %s\n", id, r.Host)
+			if !authorize(db, w, r, id, types.RoleAdmin) {
+				return
+			}
+			update, ok := readUpdate(w, r)
+			if !ok || !update.checkVersion(w, r, projectVersion(db, id)) {
+				return
+			}
+			project, err := db.GetProject(id)
+			if err != nil {
+				badResponse(w, r, err)
+				return
+			}
+			reqData := projectPayload{id, project.Name}
+			if !update.decode(w, r, &reqData) || !updateProjectData(db, w, r, id, reqData.Name, update) {
+				return
+			}
+			writeJson(w, r, http.StatusOK, projectPayload{id, reqData.Name})
+		case http.MethodDelete:
+			if !deleteProject(db, w, r, id) {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethod(w, r, []string{"get", "patch", "delete"})
+		}
+	}
+}
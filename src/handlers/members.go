@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"types"
+)
+
+type memberRequest struct {
+	UserId string `json:"userId"`
+	Login  string `json:"login"`
+	Role   string `json:"role"`
+}
+
+func decodeMemberRequest(w http.ResponseWriter, r *http.Request) (*memberRequest, bool) {
+	decoder := json.NewDecoder(r.Body)
+	var reqData memberRequest
+	err := decoder.Decode(&reqData)
+	if err != nil {
+		if err != io.EOF {
+			badRequest(w, r, err)
+			return nil, false
+		}
+	}
+	return &reqData, true
+}
+
+// canManageRole reports whether the current user may grant or take away the given role,
+// only owners are allowed to touch other owners.
+func canManageRole(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, role string) bool {
+	if role != types.RoleOwner {
+		return true
+	}
+	return authorize(db, w, r, projectId, types.RoleOwner)
+}
+
+func getMember(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, userId string) (*types.Member, bool) {
+	member, err := db.GetProjectMember(projectId, userId)
+	if err != nil {
+		var nfe db_driver.NotFoundError
+		if errors.As(err, &nfe) {
+			notFound(w, r, err)
+			return nil, false
+		}
+		badResponse(w, r, err)
+		return nil, false
+	}
+	return member, true
+}
+
+func listMembers(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string) {
+	if !authorize(db, w, r, projectId, types.RoleViewer) {
+		return
+	}
+	members, err := db.GetProjectMembers(projectId)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	output := make([]types.MemberJson, 0, len(members))
+	for _, member := range members {
+		output = append(output, *member.Json())
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.Write(data)
+	log.Printf("[%s] Listed members to %s\n", projectId, r.Host)
+}
+
+func addMember(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, reqData *memberRequest) {
+	if types.RoleRank(reqData.Role) == 0 {
+		badRequest(w, r, fmt.Errorf("unknown role %q", reqData.Role))
+		return
+	}
+	if !authorize(db, w, r, projectId, types.RoleAdmin) || !canManageRole(db, w, r, projectId, reqData.Role) {
+		return
+	}
+	userId := reqData.UserId
+	if userId == "" {
+		user, err := db.GetUserByLogin(reqData.Login)
+		if err != nil {
+			var nfe db_driver.NotFoundError
+			if errors.As(err, &nfe) {
+				notFound(w, r, err)
+				return
+			}
+			badResponse(w, r, err)
+			return
+		}
+		userId = user.Id
+	}
+	_, err := db.GetProjectMember(projectId, userId)
+	if err == nil {
+		writeProblem(w, r, http.StatusConflict, codeAlreadyExists, fmt.Sprintf("User %s is already a member", userId), nil)
+		log.Printf("[%s] Add member request not fulfilled, already a member\n", projectId)
+		return
+	}
+	var nfe db_driver.NotFoundError
+	if !errors.As(err, &nfe) {
+		badResponse(w, r, err)
+		return
+	}
+	err = db.AddProjectMember(projectId, userId, reqData.Role, getUser(r).Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	member, ok := getMember(db, w, r, projectId, userId)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(member.Json())
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(data))
+	log.Printf("[%s] Added member %s\n", projectId, userId)
+}
+
+func changeMember(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, reqData *memberRequest) {
+	if types.RoleRank(reqData.Role) == 0 {
+		badRequest(w, r, fmt.Errorf("unknown role %q", reqData.Role))
+		return
+	}
+	if !authorize(db, w, r, projectId, types.RoleAdmin) {
+		return
+	}
+	member, ok := getMember(db, w, r, projectId, reqData.UserId)
+	if !ok {
+		return
+	}
+	if !canManageRole(db, w, r, projectId, member.Role) || !canManageRole(db, w, r, projectId, reqData.Role) {
+		return
+	}
+	// The storage refuses to demote the last owner, so the check holds against concurrent writes.
+	err := db.UpdateProjectMember(projectId, reqData.UserId, reqData.Role, getUser(r).Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	member, ok = getMember(db, w, r, projectId, reqData.UserId)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(member.Json())
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(data))
+	log.Printf("[%s] Changed member %s role to %s\n", projectId, reqData.UserId, reqData.Role)
+}
+
+func removeMember(db db_driver.Storage, w http.ResponseWriter, r *http.Request, projectId string, reqData *memberRequest) bool {
+	// Any member may leave a project, removing someone else needs admin rights.
+	role := types.RoleAdmin
+	if reqData.UserId == getUser(r).Id {
+		role = types.RoleViewer
+	}
+	if !authorize(db, w, r, projectId, role) {
+		return false
+	}
+	member, ok := getMember(db, w, r, projectId, reqData.UserId)
+	if !ok {
+		return false
+	}
+	if reqData.UserId != getUser(r).Id && !canManageRole(db, w, r, projectId, member.Role) {
+		return false
+	}
+	// The storage refuses to remove the last owner, so the check holds against concurrent writes.
+	err := db.RemoveProjectMember(projectId, reqData.UserId)
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Removed member %s\n", projectId, reqData.UserId)
+	return true
+}
+
+func GetMembersHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := getProjectId(w, r)
+		if id == nil {
+			return
+		}
+		if r.Method == http.MethodGet {
+			listMembers(db, w, r, *id)
+			return
+		}
+		reqData, ok := decodeMemberRequest(w, r)
+		if !ok {
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			addMember(db, w, r, *id, reqData)
+		case http.MethodPatch:
+			changeMember(db, w, r, *id, reqData)
+		case http.MethodDelete:
+			if removeMember(db, w, r, *id, reqData) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, "Deleted succesfully")
+			}
+		default:
+			badMethod(w, r, []string{"get", "post", "patch", "delete"})
+		}
+	}
+}
+
+// GetProjectMembersHandler serves the member collection of a project.
+func GetProjectMembersHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("projectId")
+		switch r.Method {
+		case http.MethodGet:
+			listMembers(db, w, r, projectId)
+		case http.MethodPost:
+			reqData, ok := decodeMemberRequest(w, r)
+			if ok {
+				addMember(db, w, r, projectId, reqData)
+			}
+		default:
+			badMethod(w, r, []string{"get", "post"})
+		}
+	}
+}
+
+// GetProjectMemberHandler changes the role of the member named in the path with PATCH and removes them with DELETE.
+func GetProjectMemberHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectId := r.PathValue("projectId")
+		switch r.Method {
+		case http.MethodPatch:
+			reqData, ok := decodeMemberRequest(w, r)
+			if !ok {
+				return
+			}
+			reqData.UserId = r.PathValue("userId")
+			changeMember(db, w, r, projectId, reqData)
+		case http.MethodDelete:
+			if removeMember(db, w, r, projectId, &memberRequest{UserId: r.PathValue("userId")}) {
+				w.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			badMethod(w, r, []string{"patch", "delete"})
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// mergePatch applies an RFC 7386 merge patch to the struct value points to. Members present in the patch
+// replace the stored ones, null members are reset to their zero value and missing members are kept.
+func mergePatch(value any, patch []byte) error {
+	var patchDoc any
+	err := json.Unmarshal(patch, &patchDoc)
+	if err != nil {
+		return err
+	}
+	patchFields, ok := patchDoc.(map[string]any)
+	if !ok {
+		return fmt.Errorf("merge patch must be a JSON object")
+	}
+	current, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var target map[string]any
+	err = json.Unmarshal(current, &target)
+	if err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergeFields(target, patchFields))
+	if err != nil {
+		return err
+	}
+	// Unmarshal keeps fields missing from its input, the value is cleared so removed members end up zero.
+	reflect.ValueOf(value).Elem().SetZero()
+	return json.Unmarshal(merged, value)
+}
+
+func mergeFields(target map[string]any, patch map[string]any) map[string]any {
+	if target == nil {
+		target = make(map[string]any)
+	}
+	for name, patchValue := range patch {
+		if patchValue == nil {
+			delete(target, name)
+			continue
+		}
+		patchObject, isObject := patchValue.(map[string]any)
+		if !isObject {
+			target[name] = patchValue
+			continue
+		}
+		targetObject, _ := target[name].(map[string]any)
+		target[name] = mergeFields(targetObject, patchObject)
+	}
+	return target
+}
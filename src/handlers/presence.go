@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"sort"
+	"sync"
+)
+
+type presenceEntry struct {
+	ConnectionId string `json:"connectionId"`
+	UserId       string `json:"userId"`
+	Login        string `json:"login"`
+	CardId       string `json:"cardId"`
+}
+
+// presenceTracker keeps the open board connections of every project and the card each one has open.
+type presenceTracker struct {
+	mu       sync.Mutex
+	projects map[string]map[string]*presenceEntry
+}
+
+var presence = newPresenceTracker()
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{projects: make(map[string]map[string]*presenceEntry)}
+}
+
+func (p *presenceTracker) list(projectId string) []presenceEntry {
+	entries := make([]presenceEntry, 0, len(p.projects[projectId]))
+	for _, entry := range p.projects[projectId] {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ConnectionId < entries[j].ConnectionId
+	})
+	return entries
+}
+
+// join, setCard and leave return the presence list after the change, ready to be broadcast.
+func (p *presenceTracker) join(projectId string, entry presenceEntry) []presenceEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	connections, found := p.projects[projectId]
+	if !found {
+		connections = make(map[string]*presenceEntry)
+		p.projects[projectId] = connections
+	}
+	connections[entry.ConnectionId] = &entry
+	return p.list(projectId)
+}
+
+func (p *presenceTracker) setCard(projectId string, connectionId string, cardId string) []presenceEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, found := p.projects[projectId][connectionId]; found {
+		entry.CardId = cardId
+	}
+	return p.list(projectId)
+}
+
+func (p *presenceTracker) leave(projectId string, connectionId string) []presenceEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.projects[projectId], connectionId)
+	if len(p.projects[projectId]) == 0 {
+		delete(p.projects, projectId)
+	}
+	return p.list(projectId)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"types"
+	"utils"
+)
+
+type createdTokenJson struct {
+	Token       string                `json:"token"`
+	AccessToken types.AccessTokenJson `json:"accessToken"`
+}
+
+func listTokens(db db_driver.Storage, w http.ResponseWriter, r *http.Request) {
+	tokens, err := db.GetAccessTokens(getUser(r).Id)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	output := make([]types.AccessTokenJson, 0, len(tokens))
+	for _, token := range tokens {
+		output = append(output, *token.Json())
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.Write(data)
+}
+
+func createToken(db db_driver.Storage, w http.ResponseWriter, r *http.Request) {
+	log.Printf("[POST] Received a create token request from %s\n", r.Host)
+	decoder := json.NewDecoder(r.Body)
+	var reqData struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	err := decoder.Decode(&reqData)
+	if err != nil {
+		if err != io.EOF {
+			badRequest(w, r, err)
+			return
+		}
+	}
+	if len(reqData.Scopes) == 0 {
+		badRequest(w, r, fmt.Errorf("at least one scope is required"))
+		return
+	}
+	for _, scope := range reqData.Scopes {
+		if !isValidScope(scope) {
+			badRequest(w, r, fmt.Errorf("unknown scope %q", scope))
+			return
+		}
+	}
+	secret, err := utils.GetToken()
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	secret = accessTokenPrefix + secret
+	token, err := db.CreateAccessToken(getUser(r).Id, reqData.Name, utils.HashToken(secret), reqData.Scopes)
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	data, err := json.Marshal(createdTokenJson{secret, *token.Json()})
+	if err != nil {
+		badResponse(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(data))
+	log.Printf("[%s] Created access token\n", token.Id)
+}
+
+func revokeToken(db db_driver.Storage, w http.ResponseWriter, r *http.Request, id string) bool {
+	log.Printf("[DELETE] Received a revoke token request from %s\n", r.Host)
+	err := db.RevokeAccessToken(getUser(r).Id, id)
+	if err != nil {
+		var noEffect db_driver.NoEffect
+		if errors.As(err, &noEffect) {
+			notFound(w, r, fmt.Errorf("access token %s was not found", id))
+			return false
+		}
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Revoked access token\n", id)
+	return true
+}
+
+func GetTokensHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listTokens(db, w, r)
+		case http.MethodPost:
+			createToken(db, w, r)
+		case http.MethodDelete:
+			var reqData struct {
+				Id string `json:"id"`
+			}
+			if !decodeJson(w, r, &reqData) || !revokeToken(db, w, r, reqData.Id) {
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, "Revoked succesfully")
+		default:
+			badMethod(w, r, []string{"get", "post", "delete"})
+		}
+	}
+}
+
+// GetTokenHandler revokes the access token named in the path.
+func GetTokenHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			badMethod(w, r, []string{"delete"})
+			return
+		}
+		if !revokeToken(db, w, r, r.PathValue("tokenId")) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
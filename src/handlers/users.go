@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"types"
+	"utils"
+)
+
+const sessionLifetime = time.Hour * 24 * 30
+
+const minPasswordLength = 8
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type sessionJson struct {
+	Token     string         `json:"token"`
+	ExpiresAt int            `json:"expiresAt"`
+	User      types.UserJson `json:"user"`
+}
+
+func GetUserRegistrar(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		log.Printf("[POST] Received a register request from %s\n", r.Host)
+		decoder := json.NewDecoder(r.Body)
+		var reqData credentials
+		err := decoder.Decode(&reqData)
+		if err != nil {
+			if err != io.EOF {
+				badRequest(w, r, err)
+				return
+			}
+		}
+		if reqData.Login == "" {
+			badRequest(w, r, fmt.Errorf("login is required"))
+			return
+		}
+		if len(reqData.Password) < minPasswordLength {
+			badRequest(w, r, fmt.Errorf("password must be at least %d characters long", minPasswordLength))
+			return
+		}
+		_, err = db.GetUserByLogin(reqData.Login)
+		if err == nil {
+			writeProblem(w, r, http.StatusConflict, codeAlreadyExists, fmt.Sprintf("Login %s is already taken", reqData.Login), nil)
+			log.Printf("Register request not fulfilled, login is taken\n")
+			return
+		}
+		var nfe db_driver.NotFoundError
+		if !errors.As(err, &nfe) {
+			badResponse(w, r, err)
+			return
+		}
+		hash, err := utils.HashPassword(reqData.Password)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		user, err := db.CreateUser(reqData.Login, hash)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		data, err := json.Marshal(user.Json())
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, string(data))
+		log.Printf("[%s] Registered succesfully\n", user.Id)
+	}
+	return handler
+}
+
+func GetUserLoginHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		log.Printf("[POST] Received a login request from %s\n", r.Host)
+		decoder := json.NewDecoder(r.Body)
+		var reqData credentials
+		err := decoder.Decode(&reqData)
+		if err != nil {
+			if err != io.EOF {
+				badRequest(w, r, err)
+				return
+			}
+		}
+		user, err := db.GetUserByLogin(reqData.Login)
+		if err != nil {
+			var nfe db_driver.NotFoundError
+			if errors.As(err, &nfe) {
+				unauthorized(w, r, fmt.Errorf("wrong login or password"))
+				return
+			}
+			badResponse(w, r, err)
+			return
+		}
+		if !utils.CheckPassword(user.PasswordHash, reqData.Password) {
+			unauthorized(w, r, fmt.Errorf("wrong login or password"))
+			return
+		}
+		token, err := utils.GetToken()
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		expiresAt := int(time.Now().Add(sessionLifetime).Unix())
+		err = db.CreateSession(user.Id, utils.HashToken(token), expiresAt)
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		data, err := json.Marshal(sessionJson{token, expiresAt, *user.Json()})
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, string(data))
+		log.Printf("[%s] Logged in succesfully\n", user.Id)
+	}
+	return handler
+}
+
+func logout(db db_driver.Storage, w http.ResponseWriter, r *http.Request) bool {
+	log.Printf("[%s] Received a logout request from %s\n", r.Method, r.Host)
+	err := db.DeleteSession(utils.HashToken(getBearerToken(r)))
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	log.Printf("[%s] Logged out succesfully\n", getUser(r).Id)
+	return true
+}
+
+func GetUserLogoutHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			badMethod(w, r, []string{"post"})
+			return
+		}
+		if !logout(db, w, r) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "Logged out succesfully")
+	}
+	return handler
+}
+
+// GetCurrentSessionHandler ends the session used for the request on DELETE.
+func GetCurrentSessionHandler(db db_driver.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			badMethod(w, r, []string{"delete"})
+			return
+		}
+		if !logout(db, w, r) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetCurrentUserHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		data, err := json.Marshal(getUser(r).Json())
+		if err != nil {
+			badResponse(w, r, err)
+			return
+		}
+		w.Write(data)
+	}
+	return handler
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"db_driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The version of a card, column, tag or project is the counter of its writes, the storage checks it inside
+// the write. Comments and checklists are versioned by their updatedAt timestamp. The version is sent as
+// the ETag of single resources and clients send it back with If-Match or as the expectedVersion of an
+// update payload. Whole boards have no ETag, their version would have to cover every row.
+
+func versionTag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+func setVersion(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", versionTag(version))
+}
+
+// matchesVersion applies If-Match with strong comparison, "*" matches any stored version.
+func matchesVersion(ifMatch string, version int) bool {
+	current := versionTag(version)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// staleVersionError is an action expecting another version than the stored one.
+type staleVersionError struct {
+	thing   string
+	id      string
+	version int
+}
+
+func (e staleVersionError) Error() string {
+	return fmt.Sprintf("%s %s was changed, current version is %d", e.thing, e.id, e.version)
+}
+
+// updateRequest is an update payload read ahead of the stored state, so the version can be checked
+// before the payload is applied. version is the version the write expects once checkVersion passed,
+// 0 when the client sent no precondition or If-Match: *, and status is the answer when the write finds
+// the item at another one.
+type updateRequest struct {
+	data            []byte
+	ExpectedVersion *int `json:"expectedVersion"`
+	version         int
+	status          int
+	load            func() (any, int, error)
+}
+
+func readUpdate(w http.ResponseWriter, r *http.Request) (*updateRequest, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, r, err)
+		return nil, false
+	}
+	update := updateRequest{data: bytes.TrimSpace(data)}
+	if len(update.data) > 0 {
+		if update.data[0] != '{' {
+			badRequest(w, r, fmt.Errorf("update payload must be a JSON object"))
+			return nil, false
+		}
+		err = json.Unmarshal(update.data, &update)
+		if err != nil {
+			badRequest(w, r, err)
+			return nil, false
+		}
+	}
+	return &update, true
+}
+
+// decode applies the payload to value as a merge patch, so value should hold the stored state.
+// Both application/json and application/merge-patch+json bodies are read this way.
+func (u *updateRequest) decode(w http.ResponseWriter, r *http.Request, value any) bool {
+	if len(u.data) == 0 {
+		return true
+	}
+	err := mergePatch(value, u.data)
+	if err != nil {
+		badRequest(w, r, err)
+		return false
+	}
+	return true
+}
+
+// checkVersion loads the stored resource only when the client sent a precondition. A stale If-Match
+// answers 412 and a stale expectedVersion 409, both carry the current state so the client can merge.
+func (u *updateRequest) checkVersion(w http.ResponseWriter, r *http.Request, load func() (any, int, error)) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" && u.ExpectedVersion == nil {
+		return true
+	}
+	current, version, err := load()
+	if err != nil {
+		badResponse(w, r, err)
+		return false
+	}
+	switch {
+	case ifMatch != "" && !matchesVersion(ifMatch, version):
+		writeStale(w, r, http.StatusPreconditionFailed, current, version)
+		return false
+	case u.ExpectedVersion != nil && *u.ExpectedVersion != version:
+		writeStale(w, r, http.StatusConflict, current, version)
+		return false
+	}
+	u.load = load
+	u.status = http.StatusConflict
+	if u.ExpectedVersion != nil {
+		u.version = version
+	}
+	if ifMatch != "" && strings.TrimSpace(ifMatch) != "*" {
+		u.version = version
+		u.status = http.StatusPreconditionFailed
+	}
+	return true
+}
+
+// stale answers a write that found the item changed after checkVersion passed the same way checkVersion
+// does, it returns false when err is not a version conflict.
+func (u *updateRequest) stale(w http.ResponseWriter, r *http.Request, err error) bool {
+	var conflict db_driver.VersionConflict
+	if !errors.As(err, &conflict) || u.load == nil {
+		return false
+	}
+	current, version, err := u.load()
+	if err != nil {
+		badResponse(w, r, err)
+		return true
+	}
+	writeStale(w, r, u.status, current, version)
+	return true
+}
+
+func writeStale(w http.ResponseWriter, r *http.Request, status int, current any, version int) {
+	setVersion(w, version)
+	writeProblem(w, r, status, codeStaleVersion, fmt.Sprintf("Resource was changed, current version is %d", version),
+		staleDetails{version, current})
+}
+
+type staleDetails struct {
+	CurrentVersion int `json:"currentVersion"`
+	Current        any `json:"current"`
+}
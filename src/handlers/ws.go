@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"db_driver"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"types"
+	"utils"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = time.Second * 10
+	wsPongWait       = time.Second * 60
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 1 << 16
+)
+
+const (
+	wsMessageAction   = "action"
+	wsMessagePresence = "presence"
+	wsMessageEvent    = "event"
+	wsMessageResult   = "result"
+	wsMessageError    = "error"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The api is open to any origin like cors says, clients authenticate with bearer tokens, not cookies.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsIncoming is a client message, actions use the same payload as the actions endpoint
+// and presence messages tell which card the client has open, an empty card id closes it.
+type wsIncoming struct {
+	Type      string      `json:"type"`
+	RequestId string      `json:"requestId"`
+	Action    postRequest `json:"action"`
+	CardId    string      `json:"cardId"`
+}
+
+type wsOutgoing struct {
+	Type      string        `json:"type"`
+	RequestId string        `json:"requestId,omitempty"`
+	Event     *Event        `json:"event,omitempty"`
+	Result    *actionResult `json:"result,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// wsWriter owns the writing side of the connection, it stops when done is closed,
+// when the broker drops the subscriber or when a write fails.
+func wsWriter(conn *websocket.Conn, sub subscriber, replies <-chan wsOutgoing, done <-chan struct{}) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	defer conn.Close()
+	for {
+		var message wsOutgoing
+		select {
+		case <-done:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+			return
+		case <-ping.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			if err != nil {
+				return
+			}
+			continue
+		case event, open := <-sub:
+			if !open {
+				return
+			}
+			message = wsOutgoing{Type: wsMessageEvent, Event: &event}
+		case message = <-replies:
+		}
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		err := conn.WriteJSON(message)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func handleWsAction(db db_driver.Storage, r *http.Request, projectId string, message *wsIncoming) wsOutgoing {
+	reply := wsOutgoing{Type: wsMessageResult, RequestId: message.RequestId}
+	scope, role, err := actionAccess(message.Action.ActionType)
+	if err == nil && !hasScope(r, scope) {
+		err = fmt.Errorf("%w: access token is missing %s scope", errForbidden, scope)
+	}
+	if err == nil {
+		err = checkRole(db, projectId, getUser(r).Id, role)
+	}
+	if err == nil {
+		reply.Result, err = runAction(db, projectId, &message.Action, getUser(r).Id)
+	}
+	if err != nil {
+		log.Printf("[%s] WebSocket action not fulfilled: %s\n", projectId, err)
+		_, code, errorMessage := classifyError(err)
+		return wsOutgoing{Type: wsMessageError, RequestId: message.RequestId, Code: code, Error: errorMessage}
+	}
+	return reply
+}
+
+func handleWsPresence(db db_driver.Storage, r *http.Request, projectId string, connectionId string, message *wsIncoming) *wsOutgoing {
+	if message.CardId != "" {
+		cardProjectId, err := db.GetCardProjectId(message.CardId)
+		if err != nil || cardProjectId != projectId {
+			return &wsOutgoing{Type: wsMessageError, RequestId: message.RequestId, Code: codeNotFound, Error: fmt.Sprintf("card %s was not found", message.CardId)}
+		}
+	}
+	entries := presence.setCard(projectId, connectionId, message.CardId)
+	events.notify(projectId, eventPresenceUpdated, getUser(r).Id, entries)
+	return nil
+}
+
+func GetWebSocketHandler(db db_driver.Storage) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			badMethod(w, r, []string{"get"})
+			return
+		}
+		projectId := r.PathValue("projectId")
+		log.Printf("[%s] [GET] Received a websocket request from %s\n", projectId, r.Host)
+		if !authorize(db, w, r, projectId, types.RoleViewer) {
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("[%s] WebSocket upgrade failed: %s\n", projectId, err)
+			return
+		}
+		user := getUser(r)
+		connectionId := utils.GetUUID()
+
+		sub, _, _, _ := events.subscribe(projectId, 0)
+		defer events.unsubscribe(projectId, sub)
+		replies := make(chan wsOutgoing, subscriberQueueSize)
+		done := make(chan struct{})
+		writerDone := make(chan struct{})
+		go func() {
+			wsWriter(conn, sub, replies, done)
+			close(writerDone)
+		}()
+
+		entries := presence.join(projectId, presenceEntry{connectionId, user.Id, user.Login, ""})
+		events.notify(projectId, eventPresenceUpdated, user.Id, entries)
+		defer func() {
+			entries := presence.leave(projectId, connectionId)
+			events.notify(projectId, eventPresenceUpdated, user.Id, entries)
+		}()
+
+		conn.SetReadLimit(wsMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+	read:
+		for {
+			var message wsIncoming
+			err := conn.ReadJSON(&message)
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Printf("[%s] WebSocket read failed: %s\n", projectId, err)
+				}
+				break
+			}
+			var reply *wsOutgoing
+			switch message.Type {
+			case wsMessageAction:
+				result := handleWsAction(db, r, projectId, &message)
+				reply = &result
+			case wsMessagePresence:
+				reply = handleWsPresence(db, r, projectId, connectionId, &message)
+			default:
+				reply = &wsOutgoing{Type: wsMessageError, RequestId: message.RequestId, Error: fmt.Sprintf("unknown message type %q", message.Type)}
+			}
+			if reply == nil {
+				continue
+			}
+			select {
+			case replies <- *reply:
+			case <-writerDone:
+				break read
+			}
+		}
+		close(done)
+		<-writerDone
+		log.Printf("[%s] WebSocket connection from %s closed\n", projectId, r.Host)
+	}
+	return handler
+}
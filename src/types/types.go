@@ -1,5 +1,7 @@
 package types
 
+import "encoding/json"
+
 type Tag struct {
 	Id        string
 	ProjectId string
@@ -9,6 +11,7 @@ type Tag struct {
 	UpdatedAt int
 	CreatedBy string
 	UpdatedBy string
+	Version   int
 }
 
 type TagJson struct {
@@ -19,22 +22,28 @@ type TagJson struct {
 	UpdatedAt int    `json:"updatedAt"`
 	CreatedBy string `json:"createdBy"`
 	UpdatedBy string `json:"updatedBy"`
+	Version   int    `json:"version"`
 }
 
 func (t *Tag) Json() *TagJson {
-	return &TagJson{t.Id, t.Name, t.Color, t.CreatedAt, t.UpdatedAt, t.CreatedBy, t.UpdatedBy}
+	return &TagJson{t.Id, t.Name, t.Color, t.CreatedAt, t.UpdatedAt, t.CreatedBy, t.UpdatedBy, t.Version}
 }
 
+// Card is a stored card, StartAt and DueAt are unix timestamps and nil when the card has no such date.
+// Version counts the writes to the card, board items carry it for optimistic concurrency.
 type Card struct {
 	Id          string
 	ColumnId    string
 	Name        string
 	Order       int
 	Description string
+	StartAt     *int
+	DueAt       *int
 	CreatedAt   int
 	UpdatedAt   int
 	CreatedBy   string
 	UpdatedBy   string
+	Version     int
 }
 type CardJson struct {
 	Id          string   `json:"id"`
@@ -42,16 +51,30 @@ type CardJson struct {
 	Name        string   `json:"name"`
 	Order       int      `json:"order"`
 	Description string   `json:"description"`
+	StartAt     *int     `json:"startAt"`
+	DueAt       *int     `json:"dueAt"`
 	TagIds      []string `json:"tagIds"`
-	CreatedAt   int      `json:"createdAt"`
-	UpdatedAt   int      `json:"updatedAt"`
-	CreatedBy   string   `json:"createdBy"`
-	UpdatedBy   string   `json:"updatedBy"`
+	AssigneeIds []string `json:"assigneeIds"`
+	// ChecklistProgress sums up the items of all checklists of the card, the items are read from the checklists endpoint.
+	ChecklistProgress ChecklistProgressJson `json:"checklistProgress"`
+	CreatedAt         int                   `json:"createdAt"`
+	UpdatedAt         int                   `json:"updatedAt"`
+	CreatedBy         string                `json:"createdBy"`
+	UpdatedBy         string                `json:"updatedBy"`
+	Version           int                   `json:"version"`
 }
 
 func (c *Card) Json() *CardJson {
 	var tagIds [0]string
-	return &CardJson{c.Id, c.ColumnId, c.Name, c.Order, c.Description, tagIds[:], c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy}
+	var assigneeIds [0]string
+	return &CardJson{c.Id, c.ColumnId, c.Name, c.Order, c.Description, c.StartAt, c.DueAt, tagIds[:], assigneeIds[:],
+		ChecklistProgressJson{}, c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy, c.Version}
+}
+
+// AssignedCardJson is a card listed outside of its board, along with the project it belongs to.
+type AssignedCardJson struct {
+	ProjectId string `json:"projectId"`
+	CardJson
 }
 
 type Column struct {
@@ -63,6 +86,7 @@ type Column struct {
 	UpdatedAt int
 	CreatedBy string
 	UpdatedBy string
+	Version   int
 }
 type ColumnJson struct {
 	Id        string     `json:"id"`
@@ -73,11 +97,12 @@ type ColumnJson struct {
 	UpdatedAt int        `json:"updatedAt"`
 	CreatedBy string     `json:"createdBy"`
 	UpdatedBy string     `json:"updatedBy"`
+	Version   int        `json:"version"`
 }
 
 func (c *Column) Json() *ColumnJson {
 	var cards [0]CardJson
-	return &ColumnJson{c.Id, c.Name, c.Order, cards[:], c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy}
+	return &ColumnJson{c.Id, c.Name, c.Order, cards[:], c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy, c.Version}
 }
 
 type Kanban struct {
@@ -87,6 +112,7 @@ type Kanban struct {
 	Updated_At int
 	Created_By string
 	Updated_By string
+	Version    int
 }
 type KanbanJson struct {
 	Name      string       `json:"name"`
@@ -96,10 +122,231 @@ type KanbanJson struct {
 	UpdatedAt int          `json:"updatedAt"`
 	CreatedBy string       `json:"createdBy"`
 	UpdatedBy string       `json:"updatedBy"`
+	Version   int          `json:"version"`
 }
 
 func (k *Kanban) Json() *KanbanJson {
 	var columns [0]ColumnJson
 	var tags [0]TagJson
-	return &KanbanJson{k.Name, columns[:], tags[:], k.Created_At, k.Updated_At, k.Created_By, k.Updated_By}
+	return &KanbanJson{k.Name, columns[:], tags[:], k.Created_At, k.Updated_At, k.Created_By, k.Updated_By, k.Version}
+}
+
+type User struct {
+	Id           string
+	Login        string
+	PasswordHash string
+	CreatedAt    int
+	UpdatedAt    int
+}
+type UserJson struct {
+	Id        string `json:"id"`
+	Login     string `json:"login"`
+	CreatedAt int    `json:"createdAt"`
+	UpdatedAt int    `json:"updatedAt"`
+}
+
+func (u *User) Json() *UserJson {
+	return &UserJson{u.Id, u.Login, u.CreatedAt, u.UpdatedAt}
+}
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// RoleRank orders roles by privilege, unknown roles rank below viewer.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+type Member struct {
+	ProjectId string
+	UserId    string
+	Login     string
+	Role      string
+	CreatedAt int
+	UpdatedAt int
+	CreatedBy string
+	UpdatedBy string
+}
+type MemberJson struct {
+	UserId    string `json:"userId"`
+	Login     string `json:"login"`
+	Role      string `json:"role"`
+	CreatedAt int    `json:"createdAt"`
+	UpdatedAt int    `json:"updatedAt"`
+	CreatedBy string `json:"createdBy"`
+	UpdatedBy string `json:"updatedBy"`
+}
+
+func (m *Member) Json() *MemberJson {
+	return &MemberJson{m.UserId, m.Login, m.Role, m.CreatedAt, m.UpdatedAt, m.CreatedBy, m.UpdatedBy}
+}
+
+type AccessToken struct {
+	Id         string
+	UserId     string
+	Name       string
+	Scopes     []string
+	CreatedAt  int
+	LastUsedAt int
+	RevokedAt  int
+}
+type AccessTokenJson struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int      `json:"createdAt"`
+	LastUsedAt int      `json:"lastUsedAt"`
+	RevokedAt  int      `json:"revokedAt"`
+}
+
+func (t *AccessToken) Json() *AccessTokenJson {
+	return &AccessTokenJson{t.Id, t.Name, t.Scopes, t.CreatedAt, t.LastUsedAt, t.RevokedAt}
+}
+
+type CardUpdateRecord struct {
+	Id          int
+	CardId      string
+	ReverseDiff string
+	ForwardDiff string
+	CreatedAt   int
+	CreatedBy   string
+}
+type FieldChangeJson struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+type CardUpdateRecordJson struct {
+	Id        int               `json:"id"`
+	CardId    string            `json:"cardId"`
+	Changes   []FieldChangeJson `json:"changes"`
+	CreatedAt int               `json:"createdAt"`
+	CreatedBy string            `json:"createdBy"`
+}
+
+// Comment is a remark on a card. ParentId is the top level comment it replies to, empty for top level comments.
+type Comment struct {
+	Id        string
+	CardId    string
+	ParentId  string
+	Body      string
+	CreatedAt int
+	UpdatedAt int
+	CreatedBy string
+	UpdatedBy string
+	Version   int
+}
+type CommentJson struct {
+	Id        string `json:"id"`
+	CardId    string `json:"cardId"`
+	ParentId  string `json:"parentId"`
+	Body      string `json:"body"`
+	CreatedAt int    `json:"createdAt"`
+	UpdatedAt int    `json:"updatedAt"`
+	CreatedBy string `json:"createdBy"`
+	UpdatedBy string `json:"updatedBy"`
+	Version   int    `json:"version"`
+}
+
+func (c *Comment) Json() *CommentJson {
+	return &CommentJson{c.Id, c.CardId, c.ParentId, c.Body, c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy, c.Version}
+}
+
+// CommentEdit keeps the body a comment had before an edit.
+type CommentEdit struct {
+	Id        int
+	CommentId string
+	Body      string
+	CreatedAt int
+	CreatedBy string
+}
+type CommentEditJson struct {
+	Id        int    `json:"id"`
+	CommentId string `json:"commentId"`
+	Body      string `json:"body"`
+	CreatedAt int    `json:"createdAt"`
+	CreatedBy string `json:"createdBy"`
+}
+
+func (e *CommentEdit) Json() *CommentEditJson {
+	return &CommentEditJson{e.Id, e.CommentId, e.Body, e.CreatedAt, e.CreatedBy}
+}
+
+// ChecklistProgressJson counts the done items out of all items in the checklists of a card.
+type ChecklistProgressJson struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Checklist is a named list of items on a card, Order is its position among the checklists of the card.
+type Checklist struct {
+	Id        string
+	CardId    string
+	Name      string
+	Order     int
+	CreatedAt int
+	UpdatedAt int
+	CreatedBy string
+	UpdatedBy string
+	Version   int
+}
+type ChecklistJson struct {
+	Id        string              `json:"id"`
+	CardId    string              `json:"cardId"`
+	Name      string              `json:"name"`
+	Order     int                 `json:"order"`
+	Items     []ChecklistItemJson `json:"items"`
+	CreatedAt int                 `json:"createdAt"`
+	UpdatedAt int                 `json:"updatedAt"`
+	CreatedBy string              `json:"createdBy"`
+	UpdatedBy string              `json:"updatedBy"`
+	Version   int                 `json:"version"`
+}
+
+func (c *Checklist) Json() *ChecklistJson {
+	var items [0]ChecklistItemJson
+	return &ChecklistJson{c.Id, c.CardId, c.Name, c.Order, items[:], c.CreatedAt, c.UpdatedAt, c.CreatedBy, c.UpdatedBy, c.Version}
+}
+
+type ChecklistItem struct {
+	Id          string
+	ChecklistId string
+	Text        string
+	Done        bool
+	Order       int
+	CreatedAt   int
+	UpdatedAt   int
+	CreatedBy   string
+	UpdatedBy   string
+	Version     int
+}
+type ChecklistItemJson struct {
+	Id          string `json:"id"`
+	ChecklistId string `json:"checklistId"`
+	Text        string `json:"text"`
+	Done        bool   `json:"done"`
+	Order       int    `json:"order"`
+	CreatedAt   int    `json:"createdAt"`
+	UpdatedAt   int    `json:"updatedAt"`
+	CreatedBy   string `json:"createdBy"`
+	UpdatedBy   string `json:"updatedBy"`
+	Version     int    `json:"version"`
+}
+
+func (i *ChecklistItem) Json() *ChecklistItemJson {
+	return &ChecklistItemJson{i.Id, i.ChecklistId, i.Text, i.Done, i.Order, i.CreatedAt, i.UpdatedAt, i.CreatedBy, i.UpdatedBy, i.Version}
 }